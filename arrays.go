@@ -0,0 +1,378 @@
+package bacom
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/jaydiff/diff"
+)
+
+// ArrayStrategy describes how the elements of two arrays are matched against each other
+type ArrayStrategy string
+
+// Strategies supported by ArrayRule
+const (
+	// ArrayPositional compares the elements by index, ignoring missing and excess elements (the default)
+	ArrayPositional ArrayStrategy = "positional"
+	// ArrayMatchByKey compares the objects having the same value for the rule's key,
+	// regardless of their position
+	ArrayMatchByKey ArrayStrategy = "key"
+	// ArraySet ignores the order of the elements, reporting the elements absent from the right hand side
+	ArraySet ArrayStrategy = "set"
+	// ArraySchemaOnly merges the shapes of all the elements and only compares the resulting shapes
+	ArraySchemaOnly ArrayStrategy = "schema_only"
+)
+
+// ArrayRule sets the strategy used for the arrays matching Path
+// (using the same suffix semantics as the ignore rules).
+type ArrayRule struct {
+	Path     string
+	Strategy ArrayStrategy
+	// Key is the name of the field identifying the objects with the ArrayMatchByKey strategy
+	Key string
+}
+
+// Validate checks the strategy of the rule
+func (r ArrayRule) Validate() error {
+	switch r.Strategy {
+	default:
+		return errors.Errorf("unknown array strategy %q for %q", r.Strategy, r.Path)
+	case ArrayMatchByKey:
+		if r.Key == "" {
+			return errors.Errorf("missing key for %q", r.Path)
+		}
+	case "", ArrayPositional, ArraySet, ArraySchemaOnly:
+	}
+
+	return nil
+}
+
+// ArrayRules is a list of ArrayRule. When several rules match a path, the last one is used.
+type ArrayRules []ArrayRule
+
+// Match returns the rule matching path
+func (rules ArrayRules) Match(path string) (ArrayRule, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if pathMatches([]string{rules[i].Path}, path) {
+			return rules[i], true
+		}
+	}
+
+	return ArrayRule{}, false
+}
+
+// Prune replaces the arrays of the diff tree matching a rule by the result of the rule's strategy.
+// Elements of the left hand side that can't be matched are kept in the tree as missing elements.
+// The elements of sets are matched when they are equal (see BodyOptions for matching them using the other rules).
+func (rules ArrayRules) Prune(d diff.Differ) diff.Differ {
+	return rules.pruneAt(d, "", nil)
+}
+
+// elementMatcher tells whether two elements of arrays (found at path in the left hand side) match
+type elementMatcher func(path string, lhs, rhs interface{}) bool
+
+// pruneAt implements Prune for the diff tree found at path, matching the elements of sets using identical
+// (or reflect.DeepEqual if nil)
+func (rules ArrayRules) pruneAt(d diff.Differ, path string, identical elementMatcher) diff.Differ {
+	if len(rules) == 0 {
+		return d
+	}
+
+	d, err := walkAt(d, path, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
+		if !diff.IsSlice(d) || d.Diff() != diff.ContentDiffer {
+			return nil, nil
+		}
+		rule, ok := rules.Match(path)
+		if !ok {
+			return nil, nil
+		}
+		lhs, _ := diff.LHS(d)
+		rhs, _ := diff.RHS(d)
+
+		return rule.diff(path, lhs, rhs, identical)
+	})
+	if err != nil {
+		panic(errors.Wrap(err, "impossible error while pruning"))
+	}
+
+	return d
+}
+
+// diff returns the diff between two arrays found at path according to the strategy of the rule, the elements of
+// sets being matched using identical (or reflect.DeepEqual if nil).
+// A nil Differ is returned for the positional strategy.
+func (r ArrayRule) diff(path string, lhs, rhs interface{}, identical elementMatcher) (diff.Differ, error) {
+	lhsVal, rhsVal := reflect.ValueOf(lhs), reflect.ValueOf(rhs)
+	if lhsVal.Kind() != reflect.Slice || rhsVal.Kind() != reflect.Slice {
+		return nil, nil
+	}
+
+	switch r.Strategy {
+	case ArrayMatchByKey:
+		return matchArrays(path, lhs, rhs, lhsVal, rhsVal, r.keyMatcher())
+	case ArraySet:
+		if identical == nil {
+			identical = func(_ string, lhs, rhs interface{}) bool {
+				return reflect.DeepEqual(lhs, rhs)
+			}
+		}
+		return matchArrays(path, lhs, rhs, lhsVal, rhsVal, identical)
+	case ArraySchemaOnly:
+		return schemaArrays(lhs, rhs, lhsVal, rhsVal)
+	}
+
+	return nil, nil
+}
+
+// keyMatcher returns a function matching objects with the same key.
+// Elements without the key are matched if they are equal.
+func (r ArrayRule) keyMatcher() elementMatcher {
+	return func(_ string, lhs, rhs interface{}) bool {
+		lhsKey, lhsOK := objectKey(lhs, r.Key)
+		rhsKey, rhsOK := objectKey(rhs, r.Key)
+		if !lhsOK || !rhsOK {
+			return !lhsOK && !rhsOK && reflect.DeepEqual(lhs, rhs)
+		}
+
+		return reflect.DeepEqual(lhsKey, rhsKey)
+	}
+}
+
+func objectKey(v interface{}, key string) (interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	k, ok := m[key]
+
+	return k, ok && k != nil
+}
+
+// matchArrays diffs each element of lhs (the array found at path) against the first unused element of rhs it matches
+func matchArrays(path string, lhs, rhs interface{}, lhsVal, rhsVal reflect.Value, match elementMatcher) (diff.Differ, error) {
+	d := &arrayDiff{lhs: lhs, rhs: rhs}
+	used := make([]bool, rhsVal.Len())
+
+	for i := 0; i < lhsVal.Len(); i++ {
+		l := lhsVal.Index(i).Interface()
+		var elemDiff diff.Differ = missingElement{value: l}
+		index := "[" + strconv.Itoa(i) + "]"
+
+		for j := 0; j < rhsVal.Len(); j++ {
+			r := rhsVal.Index(j).Interface()
+			if used[j] || !match(path+index, l, r) {
+				continue
+			}
+			used[j] = true
+			var err error
+			elemDiff, err = diff.Diff(l, r)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		d.indices = append(d.indices, index)
+		d.diffs = append(d.diffs, elemDiff)
+	}
+
+	return d, nil
+}
+
+// schemaArrays diffs the merged shapes of the elements of lhs and rhs. The shapes are only compared
+// when both arrays have elements.
+func schemaArrays(lhs, rhs interface{}, lhsVal, rhsVal reflect.Value) (diff.Differ, error) {
+	d := &arrayDiff{lhs: lhs, rhs: rhs}
+	if lhsVal.Len() == 0 || rhsVal.Len() == 0 {
+		return d, nil
+	}
+
+	elemDiff, err := diff.Diff(mergedShape(lhsVal), mergedShape(rhsVal))
+	if err != nil {
+		return nil, err
+	}
+	d.indices = []string{"[]"}
+	d.diffs = []diff.Differ{elemDiff}
+
+	return d, nil
+}
+
+// mergedShape returns the shape of the elements of an array, merged together
+func mergedShape(v reflect.Value) interface{} {
+	var merged interface{}
+
+	for i := 0; i < v.Len(); i++ {
+		merged = mergeShapes(merged, shape(v.Index(i).Interface()))
+	}
+
+	return merged
+}
+
+// shape replaces the scalars of a json value with the zero value of their type.
+// The elements of arrays are merged into a single element.
+func shape(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[k] = shape(value)
+		}
+		return m
+	case []interface{}:
+		if len(v) == 0 {
+			return []interface{}{}
+		}
+		return []interface{}{mergedShape(reflect.ValueOf(v))}
+	}
+
+	return reflect.Zero(reflect.TypeOf(v)).Interface()
+}
+
+// mergeShapes returns the union of two shapes. When the types differ, lhs is kept.
+func mergeShapes(lhs, rhs interface{}) interface{} {
+	if lhs == nil {
+		return rhs
+	}
+	if rhs == nil {
+		return lhs
+	}
+
+	switch l := lhs.(type) {
+	case map[string]interface{}:
+		r, ok := rhs.(map[string]interface{})
+		if !ok {
+			return lhs
+		}
+		for k, value := range r {
+			l[k] = mergeShapes(l[k], value)
+		}
+	case []interface{}:
+		r, ok := rhs.([]interface{})
+		if !ok || len(r) == 0 {
+			return lhs
+		}
+		if len(l) == 0 {
+			return rhs
+		}
+		l[0] = mergeShapes(l[0], r[0])
+	}
+
+	return lhs
+}
+
+// arrayDiff is a diff between two arrays, produced by an ArrayRule.
+// indices holds the path element of each diff (the index in the lhs or "[]").
+type arrayDiff struct {
+	lhs, rhs interface{}
+	indices  []string
+	diffs    []diff.Differ
+}
+
+func (a *arrayDiff) Diff() diff.Type {
+	for _, d := range a.diffs {
+		if d.Diff() != diff.Identical {
+			return diff.ContentDiffer
+		}
+	}
+
+	return diff.Identical
+}
+
+func (a *arrayDiff) Strings() []string {
+	if a.Diff() == diff.Identical {
+		return []string{fmt.Sprintf("  %T %v", a.lhs, a.lhs)}
+	}
+
+	ss := []string{"["}
+	for _, d := range a.diffs {
+		ss = append(ss, d.Strings()...)
+	}
+
+	return append(ss, "]")
+}
+
+func (a *arrayDiff) StringIndent(key, prefix string, conf diff.Output) string {
+	if a.Diff() == diff.Identical {
+		return " " + prefix + key + fmt.Sprint(a.lhs)
+	}
+
+	ss := []string{" " + prefix + key + "["}
+	for _, d := range a.diffs {
+		s := d.StringIndent("", prefix+conf.Indent, conf)
+		if s != "" {
+			ss = append(ss, s)
+		}
+	}
+
+	return strings.Join(append(ss, " "+prefix+"]"), "\n")
+}
+
+// Walk implements diff.Walker
+func (a *arrayDiff) Walk(path string, fn diff.WalkFn) error {
+	for i, d := range a.diffs {
+		d, err := walkElement(a, d, path+a.indices[i], fn)
+		if err != nil {
+			return err
+		}
+		a.diffs[i] = d
+	}
+
+	return nil
+}
+
+func (a *arrayDiff) LHS() interface{} {
+	return a.lhs
+}
+
+func (a *arrayDiff) RHS() interface{} {
+	return a.rhs
+}
+
+// walkElement walks over an element of an arrayDiff the same way diff.Walk does
+func walkElement(parent, d diff.Differ, path string, fn diff.WalkFn) (diff.Differ, error) {
+	newD, err := fn(parent, d, path)
+	if err != nil {
+		return d, err
+	}
+	if newD != nil {
+		d = newD
+	}
+
+	if walker, ok := d.(diff.Walker); ok {
+		return d, walker.Walk(path, fn)
+	}
+
+	return d, nil
+}
+
+// missingElement is an element of the lhs array that has no match in the rhs array
+type missingElement struct {
+	value interface{}
+}
+
+func (m missingElement) Diff() diff.Type {
+	return diff.ContentDiffer
+}
+
+func (m missingElement) Strings() []string {
+	return []string{fmt.Sprintf("- %T %v", m.value, m.value)}
+}
+
+func (m missingElement) StringIndent(key, prefix string, conf diff.Output) string {
+	return "-" + prefix + key + fmt.Sprint(m.value)
+}
+
+func (m missingElement) LHS() interface{} {
+	return m.value
+}
+
+func isMissing(d diff.Differ) bool {
+	_, ok := d.(missingElement)
+
+	return ok || diff.IsMissing(d)
+}
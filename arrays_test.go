@@ -0,0 +1,207 @@
+package bacom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArrayRuleValidate(t *testing.T) {
+	for _, test := range []struct {
+		Rule  ArrayRule
+		Valid bool
+	}{
+		{ArrayRule{Path: ".items"}, true},
+		{ArrayRule{Path: ".items", Strategy: ArrayPositional}, true},
+		{ArrayRule{Path: ".items", Strategy: ArraySet}, true},
+		{ArrayRule{Path: ".items", Strategy: ArraySchemaOnly}, true},
+		{ArrayRule{Path: ".items", Strategy: ArrayMatchByKey, Key: "id"}, true},
+		{ArrayRule{Path: ".items", Strategy: ArrayMatchByKey}, false},
+		{ArrayRule{Path: ".items", Strategy: "sorted"}, false},
+	} {
+		err := test.Rule.Validate()
+		if test.Valid && err != nil {
+			t.Errorf("%+v.Validate(): unexpected error: %s", test.Rule, err)
+		}
+		if !test.Valid && err == nil {
+			t.Errorf("%+v.Validate(): expected an error", test.Rule)
+		}
+	}
+}
+
+func TestBodyOptionsArrays(t *testing.T) {
+	item := func(id float64, name string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "name": name}
+	}
+
+	for _, test := range []struct {
+		Name     string
+		Rule     ArrayRule
+		LHS, RHS interface{}
+		Expected []Difference
+	}{
+		{
+			Name: "positional",
+			Rule: ArrayRule{Path: ".items", Strategy: ArrayPositional},
+			LHS:  map[string]interface{}{"items": []interface{}{item(1, "a"), item(2, "b")}},
+			RHS:  map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 2.0}}},
+			Expected: []Difference{
+				{Kind: MissingKeyDifference, Path: ".items[0].name", Expected: "a"},
+			},
+		},
+		{
+			Name: "key reordered",
+			Rule: ArrayRule{Path: ".items", Strategy: ArrayMatchByKey, Key: "id"},
+			LHS:  map[string]interface{}{"items": []interface{}{item(1, "a"), item(2, "b")}},
+			RHS:  map[string]interface{}{"items": []interface{}{item(3, "c"), item(2, "b"), item(1, "a")}},
+		},
+		{
+			Name: "key removed",
+			Rule: ArrayRule{Path: ".items", Strategy: ArrayMatchByKey, Key: "id"},
+			LHS:  map[string]interface{}{"items": []interface{}{item(1, "a"), item(2, "b")}},
+			RHS:  map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 2.0}}},
+			Expected: []Difference{
+				{Kind: MissingElementDifference, Path: ".items[0]", Expected: item(1, "a")},
+				{Kind: MissingKeyDifference, Path: ".items[1].name", Expected: "b"},
+			},
+		},
+		{
+			Name: "set",
+			Rule: ArrayRule{Path: ".tags", Strategy: ArraySet},
+			LHS:  map[string]interface{}{"tags": []interface{}{"a", "b", "c"}},
+			RHS:  map[string]interface{}{"tags": []interface{}{"c", "a", "d"}},
+			Expected: []Difference{
+				{Kind: MissingElementDifference, Path: ".tags[1]", Expected: "b"},
+			},
+		},
+		{
+			Name: "schema only",
+			Rule: ArrayRule{Path: ".items", Strategy: ArraySchemaOnly},
+			LHS: map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"id": 1.0},
+				map[string]interface{}{"id": 2.0, "name": "b"},
+			}},
+			RHS: map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"id": "3"},
+			}},
+			Expected: []Difference{
+				{Kind: TypeChangeDifference, Path: ".items[].id", Expected: 0.0, Actual: ""},
+				{Kind: MissingKeyDifference, Path: ".items[].name", Expected: ""},
+			},
+		},
+		{
+			Name: "schema only empty",
+			Rule: ArrayRule{Path: ".items", Strategy: ArraySchemaOnly},
+			LHS:  map[string]interface{}{"items": []interface{}{item(1, "a")}},
+			RHS:  map[string]interface{}{"items": []interface{}{}},
+		},
+	} {
+		diffs, err := BodyOptions{
+			Arrays:         []ArrayRule{test.Rule},
+			CompareContent: []ContentRule{{Path: ".name"}},
+		}.Differences(test.LHS, test.RHS)
+		if err != nil {
+			t.Errorf("%s: Differences: unexpected error: %s", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.Expected) {
+			t.Errorf("%s: Differences(...) = %+v, expected %+v", test.Name, diffs, test.Expected)
+		}
+	}
+}
+
+func TestBodyOptionsArraysIgnore(t *testing.T) {
+	lhs := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "a", "meta": "x"},
+		map[string]interface{}{"id": 2.0},
+	}
+	rhs := []interface{}{
+		map[string]interface{}{"id": 1.0, "name": "b"},
+	}
+
+	diffs, err := BodyOptions{
+		Ignore:         []string{"[].meta"},
+		IgnoreMissing:  []string{"[]"},
+		CompareContent: []ContentRule{{Path: ".name"}},
+		Arrays:         []ArrayRule{{Strategy: ArrayMatchByKey, Key: "id"}},
+	}.Differences(lhs, rhs)
+	if err != nil {
+		t.Fatalf("Differences: unexpected error: %s", err)
+	}
+
+	expected := []Difference{
+		{Kind: ContentDifference, Path: "[0].name", Expected: "a", Actual: "b"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Differences(...) = %+v, expected %+v", diffs, expected)
+	}
+}
+
+func TestBodyOptionsArraysSet(t *testing.T) {
+	items := func(elems ...map[string]interface{}) map[string]interface{} {
+		values := make([]interface{}, 0, len(elems))
+		for _, e := range elems {
+			values = append(values, e)
+		}
+		return map[string]interface{}{"items": values}
+	}
+	set := []ArrayRule{{Path: ".items", Strategy: ArraySet}}
+
+	for _, test := range []struct {
+		Name     string
+		Options  BodyOptions
+		LHS, RHS interface{}
+		Expected []Difference
+	}{
+		{
+			Name:    "new keys",
+			Options: BodyOptions{Arrays: set},
+			LHS:     items(map[string]interface{}{"id": 1.0, "name": "a"}, map[string]interface{}{"id": 2.0}),
+			RHS:     items(map[string]interface{}{"id": 2.0, "new": true}, map[string]interface{}{"id": 1.0, "name": "a", "new": true}),
+		},
+		{
+			Name:    "ignored",
+			Options: BodyOptions{Arrays: set, Ignore: []string{".items[].updated"}},
+			LHS:     items(map[string]interface{}{"id": 1.0, "updated": "monday"}),
+			RHS:     items(map[string]interface{}{"id": 1.0, "updated": "tuesday"}),
+		},
+		{
+			Name:    "ignore null",
+			Options: BodyOptions{Arrays: set, IgnoreNull: true},
+			LHS:     items(map[string]interface{}{"id": 1.0, "email": nil}),
+			RHS:     items(map[string]interface{}{"id": 1.0, "email": "a@example.org"}),
+		},
+		{
+			Name:    "content rule",
+			Options: BodyOptions{Arrays: set, CompareContent: []ContentRule{{Path: ".items[].price", Tolerance: 0.1}}},
+			LHS:     items(map[string]interface{}{"id": 1.0, "price": 1.0}),
+			RHS:     items(map[string]interface{}{"id": 1.0, "price": 1.05}),
+		},
+		{
+			Name:    "content rule violated",
+			Options: BodyOptions{Arrays: set, CompareContent: []ContentRule{{Path: ".items[].price", Tolerance: 0.1}}},
+			LHS:     items(map[string]interface{}{"id": 1.0, "price": 1.0}),
+			RHS:     items(map[string]interface{}{"id": 1.0, "price": 2.0}),
+			Expected: []Difference{
+				{Kind: MissingElementDifference, Path: ".items[0]", Expected: map[string]interface{}{"id": 1.0, "price": 1.0}},
+			},
+		},
+		{
+			Name:    "changed value",
+			Options: BodyOptions{Arrays: set},
+			LHS:     items(map[string]interface{}{"id": 1.0, "name": "a"}),
+			RHS:     items(map[string]interface{}{"id": 1.0, "name": "b", "new": true}),
+			Expected: []Difference{
+				{Kind: MissingElementDifference, Path: ".items[0]", Expected: map[string]interface{}{"id": 1.0, "name": "a"}},
+			},
+		},
+	} {
+		diffs, err := test.Options.Differences(test.LHS, test.RHS)
+		if err != nil {
+			t.Errorf("%s: Differences: unexpected error: %s", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.Expected) {
+			t.Errorf("%s: Differences(...) = %+v, expected %+v", test.Name, diffs, test.Expected)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package bacom
 
 import (
 	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 )
@@ -20,3 +23,33 @@ func ReadResponse(req *http.Request, reqFname string) (resp *http.Response, err
 
 	return http.ReadResponse(bufio.NewReader(f), req)
 }
+
+// WriteResponse writes resp to w in the format used for response files.
+// The body is read entirely before writing, so that the trailers sent after it are known.
+// Responses with trailers are written using the chunked transfer encoding, as it is the only
+// way to represent trailers in HTTP/1.1.
+func WriteResponse(w io.Writer, resp *http.Response) error {
+	out := *resp
+
+	if resp.Body != nil {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		err = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		out.Body = ioutil.NopCloser(bytes.NewReader(b))
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	if len(out.Trailer) != 0 {
+		out.TransferEncoding = []string{"chunked"}
+		out.ContentLength = -1
+		out.Header = out.Header.Clone()
+		out.Header.Del("Content-Length")
+	}
+
+	return out.Write(w)
+}
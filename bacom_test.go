@@ -1,9 +1,11 @@
 package bacom
 
 import (
+	"bufio"
 	"bytes"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -97,3 +99,39 @@ func TestReadResponse(t *testing.T) {
 		t.Errorf("ReadResponse(req, %q): body does not match", testReqFname)
 	}
 }
+
+func TestWriteResponseTrailers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Trailer", "X-Checksum")
+		_, _ = w.Write([]byte(`{"foo": "bar"}`))
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("http.Get: unexpected error: %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = WriteResponse(buf, resp)
+	if err != nil {
+		t.Fatalf("WriteResponse: unexpected error: %s", err)
+	}
+
+	read, err := http.ReadResponse(bufio.NewReader(buf), nil)
+	if err != nil {
+		t.Fatalf("http.ReadResponse: unexpected error: %s", err)
+	}
+	body, err := ioutil.ReadAll(read.Body)
+	if err != nil {
+		t.Fatalf("reading body: unexpected error: %s", err)
+	}
+	if string(body) != `{"foo": "bar"}` {
+		t.Errorf("body = %q, expected %q", body, `{"foo": "bar"}`)
+	}
+	if read.Trailer.Get("X-Checksum") != "abc" {
+		t.Errorf("Trailer.Get(%q) = %q, expected %q", "X-Checksum", read.Trailer.Get("X-Checksum"), "abc")
+	}
+}
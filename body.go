@@ -1,25 +1,107 @@
 package bacom
 
 import (
+	"strings"
+
 	"github.com/yazgazan/jaydiff/diff"
 )
 
 // Compare returns a list of differences between two json objects.
 // The ignore and ignoreMissing parameters are a list of JSON paths that should be ignored.
 // If ignoreNull is true, nil values in the lhs won't be tested.
+// The differences are rendered as colorized text (see Differences for the structured version).
 func Compare(ignore, ignoreMissing []string, ignoreNull bool, lhs, rhs interface{}) ([]string, error) {
+	diffs, err := Differences(ignore, ignoreMissing, ignoreNull, lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		results = append(results, strings.Join(d.Lines(true), "\n"))
+	}
+
+	return results, nil
+}
+
+// Differences returns the list of differences between two json objects.
+// The parameters are the same as for Compare.
+func Differences(ignore, ignoreMissing []string, ignoreNull bool, lhs, rhs interface{}) ([]Difference, error) {
+	return BodyOptions{
+		Ignore:        ignore,
+		IgnoreMissing: ignoreMissing,
+		IgnoreNull:    ignoreNull,
+	}.Differences(lhs, rhs)
+}
+
+// PrunedDiff returns the diff tree between two json objects, pruned of the differences
+// that are not considered breaking changes (see Compare).
+func PrunedDiff(ignore, ignoreMissing []string, ignoreNull bool, lhs, rhs interface{}) (diff.Differ, error) {
+	return BodyOptions{
+		Ignore:        ignore,
+		IgnoreMissing: ignoreMissing,
+		IgnoreNull:    ignoreNull,
+	}.PrunedDiff(lhs, rhs)
+}
+
+// BodyOptions holds the rules used when comparing two json objects
+type BodyOptions struct {
+	// Ignore is a list of JSON paths that should be ignored
+	Ignore []string
+	// IgnoreMissing is a list of JSON paths that can be missing from the right hand side
+	IgnoreMissing []string
+	// IgnoreNull disables the type checks where null is involved
+	IgnoreNull bool
+	// CompareContent lists the values for which content differences are reported
+	CompareContent []ContentRule
+	// Arrays sets the strategies used to compare arrays (positional by default)
+	Arrays []ArrayRule
+	// Repeated is a list of JSON paths of values that can be repeated, such as XML elements and form keys (which
+	// are only decoded as arrays when repeated). Single values matching these paths are compared as arrays.
+	Repeated []string
+}
+
+// Differences returns the list of differences between two json objects
+func (o BodyOptions) Differences(lhs, rhs interface{}) ([]Difference, error) {
+	d, err := o.PrunedDiff(lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+
+	return BodyDifferences(d)
+}
+
+// PrunedDiff returns the diff tree between two json objects, pruned of the differences
+// that are not considered breaking changes
+func (o BodyOptions) PrunedDiff(lhs, rhs interface{}) (diff.Differ, error) {
+	if len(o.Repeated) != 0 {
+		lhs, rhs = wrapRepeated(o.Repeated, "", lhs), wrapRepeated(o.Repeated, "", rhs)
+	}
 	d, err := diff.Diff(lhs, rhs)
 	if err != nil {
 		return nil, err
 	}
 
-	d = IgnorePrunner(ignore).Prune(d)
-	d = IgnoreMissingPrunner(ignoreMissing).Prune(d)
-	d = Prune(d, ignoreNull)
+	return o.prune(d, "", false), nil
+}
+
+// prune prunes the diff tree found at path. With keepContent, the content differences on scalars are only ignored
+// when a content rule considers the values equal.
+func (o BodyOptions) prune(d diff.Differ, path string, keepContent bool) diff.Differ {
+	d = ArrayRules(o.Arrays).pruneAt(d, path, o.identical)
+	d = IgnorePrunner(o.Ignore).pruneAt(d, path)
+	d = IgnoreMissingPrunner(o.IgnoreMissing).pruneAt(d, path)
+
+	return pruneAt(d, path, o.IgnoreNull, o.CompareContent, keepContent)
+}
+
+// identical tells whether two elements of a set (found at path in the left hand side) match: their differences on
+// ignored paths, new keys and the values considered equal by the content rules aren't taken into account.
+func (o BodyOptions) identical(path string, lhs, rhs interface{}) bool {
+	d, err := diff.Diff(lhs, rhs)
+	if err != nil {
+		return false
+	}
 
-	return diff.Report(d, diff.Output{
-		Indent:    "\t",
-		ShowTypes: false,
-		Colorized: true,
-	})
+	return o.prune(d, path, true).Diff() == diff.Identical
 }
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// oauth2ExpiryDelta is how long before their expiry OAuth2 access tokens are refreshed
+	oauth2ExpiryDelta = 10 * time.Second
+	// defaultHMACStringToSign is the string signed by hmac providers without string_to_sign
+	defaultHMACStringToSign = "{{method}}\n{{path}}\n{{query}}\n{{timestamp}}\n{{body_sha256}}"
+)
+
+// authProvider sets the credentials of the requests sent to a base or target host, replacing the recorded ones
+type authProvider interface {
+	authenticate(req *http.Request) error
+}
+
+// authConf configures an authProvider. Only the fields of the provider's type are used.
+type authConf struct {
+	Type string `yaml:"type"`
+
+	// bearer
+	Token string `yaml:"token"`
+
+	// basic
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// oauth2 (client credentials grant)
+	TokenURL     string            `yaml:"token_url"`
+	ClientID     string            `yaml:"client_id"`
+	ClientSecret string            `yaml:"client_secret"`
+	Scopes       []string          `yaml:"scopes"`
+	Params       map[string]string `yaml:"params"`
+	// AuthStyle is either header (the client credentials are sent using basic auth, default) or params
+	AuthStyle string `yaml:"auth_style"`
+
+	// hmac
+	Key       string `yaml:"key"`
+	Algorithm string `yaml:"algorithm"`
+	Encoding  string `yaml:"encoding"`
+	// StringToSign and Headers are templates, see hmacAuth
+	StringToSign string            `yaml:"string_to_sign"`
+	Headers      map[string]string `yaml:"headers"`
+}
+
+// readAuthProvider returns the authProvider described by spec, which is either `bearer:<token>`,
+// `basic:<username>:<password>` or the name of a yaml (or json) file holding an authConf.
+// The credentials can reference variables (see requestVars), resolved using vars.
+// OAuth2 tokens are requested using client. A nil provider is returned when spec is empty.
+func readAuthProvider(spec string, vars templateVars, client *http.Client) (authProvider, error) {
+	var conf authConf
+	switch {
+	case spec == "":
+		return nil, nil
+	case strings.HasPrefix(spec, "bearer:"):
+		conf = authConf{Type: "bearer", Token: strings.TrimPrefix(spec, "bearer:")}
+	case strings.HasPrefix(spec, "basic:"):
+		parts := strings.SplitN(strings.TrimPrefix(spec, "basic:"), ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("invalid basic auth, expected basic:<username>:<password>")
+		}
+		conf = authConf{Type: "basic", Username: parts[0], Password: parts[1]}
+	default:
+		var err error
+		conf, err = readAuthConf(spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newAuthProvider(conf.resolve(vars), client)
+}
+
+func readAuthConf(fname string) (conf authConf, err error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return conf, errors.Wrapf(err, "reading auth configuration %q", fname)
+	}
+	defer handleClose(&err, f)
+
+	err = yaml.NewDecoder(f).Decode(&conf)
+
+	return conf, errors.Wrapf(err, "reading auth configuration %q", fname)
+}
+
+// resolve returns conf with the variables of its credentials resolved. The templates of the hmac provider are
+// resolved when signing requests.
+func (conf authConf) resolve(vars templateVars) authConf {
+	for _, s := range []*string{
+		&conf.Token, &conf.Username, &conf.Password,
+		&conf.TokenURL, &conf.ClientID, &conf.ClientSecret, &conf.Key,
+	} {
+		*s = vars.resolve(*s)
+	}
+	params := make(map[string]string, len(conf.Params))
+	for k, v := range conf.Params {
+		params[k] = vars.resolve(v)
+	}
+	conf.Params = params
+
+	return conf
+}
+
+func newAuthProvider(conf authConf, client *http.Client) (authProvider, error) {
+	switch conf.Type {
+	case "bearer":
+		if conf.Token == "" {
+			return nil, errors.New("bearer auth: missing token")
+		}
+		return bearerAuth{token: conf.Token}, nil
+	case "basic":
+		return basicAuth{username: conf.Username, password: conf.Password}, nil
+	case "oauth2":
+		return newOAuth2Auth(conf, client)
+	case "hmac":
+		return newHMACAuth(conf)
+	}
+
+	return nil, errors.Errorf("unknown auth type %q, expected bearer, basic, oauth2 or hmac", conf.Type)
+}
+
+// bearerAuth sets a static bearer token
+type bearerAuth struct {
+	token string
+}
+
+func (a bearerAuth) authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	return nil
+}
+
+// basicAuth sets the basic auth username and password
+type basicAuth struct {
+	username, password string
+}
+
+func (a basicAuth) authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+
+	return nil
+}
+
+// oauth2Auth sets access tokens obtained using the OAuth2 client credentials grant. Tokens are cached, and
+// refreshed shortly before they expire.
+type oauth2Auth struct {
+	conf   authConf
+	client *http.Client
+
+	mu            sync.Mutex
+	authorization string
+	expiry        time.Time
+}
+
+func newOAuth2Auth(conf authConf, client *http.Client) (*oauth2Auth, error) {
+	if conf.TokenURL == "" || conf.ClientID == "" {
+		return nil, errors.New("oauth2 auth: token_url and client_id are required")
+	}
+	if conf.AuthStyle != "" && conf.AuthStyle != "header" && conf.AuthStyle != "params" {
+		return nil, errors.Errorf("oauth2 auth: invalid auth_style %q, expected header or params", conf.AuthStyle)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &oauth2Auth{conf: conf, client: client}, nil
+}
+
+func (a *oauth2Auth) authenticate(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.authorization == "" || (!a.expiry.IsZero() && time.Now().After(a.expiry)) {
+		err := a.fetchToken()
+		if err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", a.authorization)
+
+	return nil
+}
+
+func (a *oauth2Auth) fetchToken() (err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.conf.Scopes) != 0 {
+		form.Set("scope", strings.Join(a.conf.Scopes, " "))
+	}
+	for k, v := range a.conf.Params {
+		form.Set(k, v)
+	}
+	if a.conf.AuthStyle == "params" {
+		form.Set("client_id", a.conf.ClientID)
+		form.Set("client_secret", a.conf.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.conf.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "requesting oauth2 token")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.conf.AuthStyle != "params" {
+		req.SetBasicAuth(url.QueryEscape(a.conf.ClientID), url.QueryEscape(a.conf.ClientSecret))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "requesting oauth2 token")
+	}
+	defer handleClose(&err, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("requesting oauth2 token: %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return errors.Wrap(err, "reading oauth2 token")
+	}
+	if token.AccessToken == "" {
+		return errors.New("reading oauth2 token: missing access_token")
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	a.authorization = tokenType + " " + token.AccessToken
+	a.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		a.expiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - oauth2ExpiryDelta)
+	}
+
+	return nil
+}
+
+// hmacAuth signs requests using HMAC. The string to sign and the headers set on the request are templates,
+// referencing the request using {{method}}, {{host}}, {{path}}, {{query}}, {{timestamp}} (unix time),
+// {{body_sha256}} (hex encoded) and {{header.<Name>}}. The headers can also reference the {{signature}}.
+type hmacAuth struct {
+	key          []byte
+	hash         func() hash.Hash
+	encode       func([]byte) string
+	stringToSign string
+	headers      map[string]string
+	now          func() time.Time
+}
+
+func newHMACAuth(conf authConf) (*hmacAuth, error) {
+	if conf.Key == "" {
+		return nil, errors.New("hmac auth: missing key")
+	}
+	a := &hmacAuth{
+		key:          []byte(conf.Key),
+		stringToSign: conf.StringToSign,
+		headers:      conf.Headers,
+		now:          time.Now,
+	}
+
+	switch strings.ToLower(conf.Algorithm) {
+	case "", "sha256":
+		a.hash = sha256.New
+	case "sha1":
+		a.hash = sha1.New
+	case "sha512":
+		a.hash = sha512.New
+	default:
+		return nil, errors.Errorf("hmac auth: unknown algorithm %q, expected sha1, sha256 or sha512", conf.Algorithm)
+	}
+	switch strings.ToLower(conf.Encoding) {
+	case "", "hex":
+		a.encode = hex.EncodeToString
+	case "base64":
+		a.encode = base64.StdEncoding.EncodeToString
+	default:
+		return nil, errors.Errorf("hmac auth: unknown encoding %q, expected hex or base64", conf.Encoding)
+	}
+	if a.stringToSign == "" {
+		a.stringToSign = defaultHMACStringToSign
+	}
+	if len(a.headers) == 0 {
+		a.headers = map[string]string{
+			"X-Timestamp": "{{timestamp}}",
+			"X-Signature": "{{signature}}",
+		}
+	}
+
+	return a, nil
+}
+
+func (a *hmacAuth) authenticate(req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return errors.Wrap(err, "signing request")
+	}
+	sum := sha256.Sum256(body)
+
+	vars := templateVars{
+		"method":      req.Method,
+		"host":        req.URL.Host,
+		"path":        req.URL.EscapedPath(),
+		"query":       req.URL.RawQuery,
+		"timestamp":   strconv.FormatInt(a.now().Unix(), 10),
+		"body_sha256": hex.EncodeToString(sum[:]),
+	}
+	for k := range req.Header {
+		vars["header."+k] = req.Header.Get(k)
+	}
+
+	mac := hmac.New(a.hash, a.key)
+	_, _ = mac.Write([]byte(vars.resolve(a.stringToSign)))
+	vars["signature"] = a.encode(mac.Sum(nil))
+
+	for k, v := range a.headers {
+		req.Header.Set(k, vars.resolve(v))
+	}
+
+	return nil
+}
+
+// readRequestBody reads the body of req, replacing it with a copy
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+
+	return b, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAuthProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestReadAuthProvider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"bearer.yaml":  "type: bearer\ntoken: '{{API_TOKEN}}'\n",
+		"unknown.yaml": "type: digest\n",
+		"hmac.json":    `{"type": "hmac", "key": "secret", "algorithm": "md5"}`,
+	})
+	vars := templateVars{"API_TOKEN": "t0k3n", "PASSWORD": "p4ss:w0rd"}
+
+	for _, test := range []struct {
+		spec     string
+		expected string
+		err      string
+	}{
+		{spec: "bearer:abc", expected: "Bearer abc"},
+		{spec: "bearer:{{API_TOKEN}}", expected: "Bearer t0k3n"},
+		{spec: "basic:jane:{{PASSWORD}}", expected: "Basic amFuZTpwNHNzOncwcmQ="},
+		{spec: filepath.Join(dir, "bearer.yaml"), expected: "Bearer t0k3n"},
+		{spec: "basic:jane", err: "invalid basic auth"},
+		{spec: "bearer:", err: "missing token"},
+		{spec: filepath.Join(dir, "unknown.yaml"), err: `unknown auth type "digest"`},
+		{spec: filepath.Join(dir, "hmac.json"), err: `unknown algorithm "md5"`},
+		{spec: filepath.Join(dir, "missing.yaml"), err: "no such file"},
+	} {
+		auth, err := readAuthProvider(test.spec, vars, nil)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("readAuthProvider(%q): expected error containing %q, got %v", test.spec, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readAuthProvider(%q): unexpected error: %s", test.spec, err)
+			continue
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer stale")
+		err = auth.authenticate(req)
+		if err != nil {
+			t.Errorf("readAuthProvider(%q).authenticate: unexpected error: %s", test.spec, err)
+		}
+		if got := req.Header.Get("Authorization"); got != test.expected {
+			t.Errorf("readAuthProvider(%q): Authorization = %q, expected %q", test.spec, got, test.expected)
+		}
+	}
+
+	auth, err := readAuthProvider("", vars, nil)
+	if auth != nil || err != nil {
+		t.Errorf(`readAuthProvider("") = %v, %v, expected no provider`, auth, err)
+	}
+}
+
+func TestOAuth2Auth(t *testing.T) {
+	var fetched int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+		err := r.ParseForm()
+		if err != nil {
+			t.Error(err)
+		}
+		id, secret, ok := r.BasicAuth()
+		if r.Form.Get("client_id") != "" {
+			id, secret, ok = r.Form.Get("client_id"), r.Form.Get("client_secret"), true
+		}
+		if !ok || id != "bacom" || secret != "s3cr3t" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		if r.Form.Get("scope") != "read write" || r.Form.Get("audience") != "api" {
+			t.Errorf("token request: form = %v, expected scopes and audience", r.Form)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "token-` + string(rune('0'+fetched)) + `", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	defer srv.Close()
+
+	for _, style := range []string{"", "params"} {
+		fetched = 0
+		auth, err := newOAuth2Auth(authConf{
+			TokenURL:     srv.URL,
+			ClientID:     "bacom",
+			ClientSecret: "s3cr3t",
+			Scopes:       []string{"read", "write"},
+			Params:       map[string]string{"audience": "api"},
+			AuthStyle:    style,
+		}, nil)
+		if err != nil {
+			t.Fatalf("newOAuth2Auth(%q): unexpected error: %s", style, err)
+		}
+
+		for i, expected := range []string{"Bearer token-1", "Bearer token-1"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			err = auth.authenticate(req)
+			if err != nil {
+				t.Fatalf("%q: authenticate #%d: unexpected error: %s", style, i, err)
+			}
+			if got := req.Header.Get("Authorization"); got != expected {
+				t.Errorf("%q: authenticate #%d: Authorization = %q, expected %q", style, i, got, expected)
+			}
+		}
+		if auth.expiry.Before(time.Now().Add(time.Hour - oauth2ExpiryDelta - time.Minute)) {
+			t.Errorf("%q: token expiry = %s, expected in about an hour", style, auth.expiry)
+		}
+
+		// expired tokens are refreshed
+		auth.expiry = time.Now().Add(-time.Second)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		err = auth.authenticate(req)
+		if err != nil || req.Header.Get("Authorization") != "Bearer token-2" || fetched != 2 {
+			t.Errorf("%q: authenticate(expired) = %v, Authorization %q after %d token requests, expected a new token",
+				style, err, req.Header.Get("Authorization"), fetched)
+		}
+	}
+
+	auth, err := newOAuth2Auth(authConf{TokenURL: srv.URL, ClientID: "bacom", ClientSecret: "wrong"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = auth.authenticate(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("authenticate(invalid client): expected an error, got %v", err)
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	auth, err := newHMACAuth(authConf{Key: "s3cr3t"})
+	if err != nil {
+		t.Fatalf("newHMACAuth: unexpected error: %s", err)
+	}
+	auth.now = func() time.Time { return time.Unix(1500000000, 0) }
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/api/users?page=2", strings.NewReader(`{"name":"Jane"}`))
+	req.Header.Set("X-Signature", "stale")
+	err = auth.authenticate(req)
+	if err != nil {
+		t.Fatalf("authenticate: unexpected error: %s", err)
+	}
+
+	bodySum := sha256.Sum256([]byte(`{"name":"Jane"}`))
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	_, _ = mac.Write([]byte("POST\n/api/users\npage=2\n1500000000\n" + hex.EncodeToString(bodySum[:])))
+	if expected := hex.EncodeToString(mac.Sum(nil)); req.Header.Get("X-Signature") != expected {
+		t.Errorf("X-Signature = %q, expected %q", req.Header.Get("X-Signature"), expected)
+	}
+	if req.Header.Get("X-Timestamp") != "1500000000" {
+		t.Errorf("X-Timestamp = %q, expected %q", req.Header.Get("X-Timestamp"), "1500000000")
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil || string(b) != `{"name":"Jane"}` {
+		t.Errorf("body = %q, %v, expected the original body", b, err)
+	}
+
+	auth, err = newHMACAuth(authConf{
+		Key:          "s3cr3t",
+		Algorithm:    "sha512",
+		Encoding:     "base64",
+		StringToSign: "{{method}} {{header.X-Request-Id}}",
+		Headers:      map[string]string{"Authorization": "HMAC keyId=bacom,signature={{signature}}"},
+	})
+	if err != nil {
+		t.Fatalf("newHMACAuth: unexpected error: %s", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "42")
+	err = auth.authenticate(req)
+	if err != nil {
+		t.Fatalf("authenticate: unexpected error: %s", err)
+	}
+	mac = hmac.New(sha512.New, []byte("s3cr3t"))
+	_, _ = mac.Write([]byte("GET 42"))
+	expected := "HMAC keyId=bacom,signature=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Authorization = %q, expected %q", got, expected)
+	}
+}
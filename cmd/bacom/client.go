@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/imdario/mergo"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultRetryBackoff is the wait before the first retry of a request, when retry_backoff isn't set
+const defaultRetryBackoff = 500 * time.Millisecond
+
+// duration is a time.Duration read from flags and configuration files using the time.ParseDuration format
+type duration time.Duration
+
+func (d duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+
+	return nil
+}
+
+func (d *duration) UnmarshalText(b []byte) error {
+	return d.Set(string(b))
+}
+
+func (d *duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	err := unmarshal(&s)
+	if err != nil {
+		return err
+	}
+
+	return d.Set(s)
+}
+
+// clientConf configures the HTTP client used for the requests and gRPC calls sent to a base or target host
+type clientConf struct {
+	// Timeout bounds each attempt of a request, including reading the response body
+	Timeout duration
+	// Retries is the number of times requests failing with a connection error or a 5xx status are retried
+	Retries      int
+	RetryBackoff duration `yaml:"retry_backoff"`
+
+	CACert             string `yaml:"ca_cert"`
+	ClientCert         string `yaml:"client_cert"`
+	ClientKey          string `yaml:"client_key"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+
+	// Proxy is the URL of the HTTP proxy used for requests (but not gRPC calls), defaulting to the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	Proxy        string
+	DisableHTTP2 bool `yaml:"disable_http2"`
+}
+
+// clientsConf is the `clients` section of the configuration file
+type clientsConf struct {
+	Base   clientConf
+	Target clientConf
+}
+
+// SetupFlags registers the flags of the client, using the base- or target- prefix
+func (c *clientConf) SetupFlags(flags *flag.FlagSet, prefix string) {
+	flags.Var(&c.Timeout, prefix+"-timeout", "timeout of the requests to the "+prefix+" host, including reading the response (e.g. 10s)")
+	flags.IntVar(&c.Retries, prefix+"-retries", 0, "number of retries of the requests to the "+prefix+" host failing with a connection error or a 5xx status")
+	flags.Var(&c.RetryBackoff, prefix+"-retry-backoff", "wait before the first retry of a request to the "+prefix+" host, doubled for each retry (default 500ms)")
+	flags.StringVar(&c.CACert, prefix+"-ca-cert", "", "PEM file of the certificate authorities used to verify the "+prefix+" host")
+	flags.StringVar(&c.ClientCert, prefix+"-client-cert", "", "PEM file of the client certificate sent to the "+prefix+" host (requires -"+prefix+"-client-key)")
+	flags.StringVar(&c.ClientKey, prefix+"-client-key", "", "PEM file of the key of the client certificate sent to the "+prefix+" host")
+	flags.BoolVar(&c.InsecureSkipVerify, prefix+"-insecure-skip-verify", false, "don't verify the certificate of the "+prefix+" host")
+	flags.StringVar(&c.Proxy, prefix+"-proxy", "", "HTTP proxy used for the requests to the "+prefix+" host")
+	flags.BoolVar(&c.DisableHTTP2, prefix+"-disable-http2", false, "use HTTP/1.1 for the requests to the "+prefix+" host")
+}
+
+// readClientsConf reads the clients section of the configuration file fname. As for the rest of the configuration,
+// a missing file is not an error. The json format accepts either an array of path configurations, or an object
+// holding the `Conf` and `Clients` sections.
+func readClientsConf(fname string) (conf clientsConf, err error) {
+	var file struct {
+		Clients clientsConf
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return conf, nil
+	}
+	defer handleClose(&err, f)
+
+	switch getPathConfFormat(fname) {
+	case jsonPathConf:
+		var b []byte
+		b, err = ioutil.ReadAll(f)
+		if err != nil || bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+			return conf, err
+		}
+		err = json.Unmarshal(b, &file)
+	case yamlPathConf:
+		err = yaml.NewDecoder(f).Decode(&file)
+	case tomlPathConf:
+		_, err = toml.DecodeReader(f, &file)
+	}
+
+	return file.Clients, err
+}
+
+// setupClients builds the clients of the base and target. The flags take precedence over the configuration file.
+func (c *testConf) setupClients() error {
+	if c.PathsConfFile != "" {
+		file, err := readClientsConf(c.PathsConfFile)
+		if err != nil {
+			return errors.Wrapf(err, "parsing configuration file %q", c.PathsConfFile)
+		}
+		err = mergo.Merge(&c.Base.Client, file.Base)
+		if err == nil {
+			err = mergo.Merge(&c.Target.Client, file.Target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	c.Base.client, c.Base.grpcClient, err = c.Base.Client.build()
+	if err != nil {
+		return errors.Wrap(err, "base client")
+	}
+	c.Target.client, c.Target.grpcClient, err = c.Target.Client.build()
+
+	return errors.Wrap(err, "target client")
+}
+
+// build returns the clients used for requests and gRPC calls
+func (c clientConf) build() (client, grpcClient *http.Client, err error) {
+	tlsConf, err := c.tlsConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "parsing proxy URL %q", c.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if c.DisableHTTP2 {
+		// a non-nil empty map disables the HTTP/2 upgrade of TLS connections
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return c.client(transport), c.client(newGRPCTransport(tlsConf)), nil
+}
+
+func (c clientConf) client(transport http.RoundTripper) *http.Client {
+	backoff := time.Duration(c.RetryBackoff)
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	return &http.Client{Transport: &retryTransport{
+		next:    transport,
+		timeout: time.Duration(c.Timeout),
+		retries: c.Retries,
+		backoff: backoff,
+	}}
+}
+
+// tlsConfig returns the TLS configuration of the client, or nil if the defaults are used
+func (c clientConf) tlsConfig() (*tls.Config, error) {
+	if c.CACert == "" && c.ClientCert == "" && c.ClientKey == "" && !c.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	conf := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CACert != "" {
+		b, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading CA certificates")
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(b) {
+			return nil, errors.Errorf("reading CA certificates: no certificate found in %q", c.CACert)
+		}
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading client certificate")
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// retryTransport bounds each attempt of a request by timeout (if set), and retries the requests failing with a
+// connection error or a 5xx status up to retries times, waiting backoff before the first retry and doubling it for
+// each following one
+type retryTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body is sent again by each retry, using GetBody when set (see setGetBody) or a buffered copy otherwise
+	getBody, buffered := req.GetBody, false
+	if t.retries > 0 && getBody == nil && req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		errClose := req.Body.Close()
+		if err == nil {
+			err = errClose
+		}
+		if err != nil {
+			return nil, err
+		}
+		getBody, buffered = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}, true
+	}
+
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		var getAttemptBody func() (io.ReadCloser, error)
+		if attempt > 0 || buffered {
+			getAttemptBody = getBody
+		}
+		resp, err := t.attempt(req, getAttemptBody)
+		if attempt >= t.retries || (err == nil && resp.StatusCode < 500) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends req, with the body returned by getBody if set
+func (t *retryTransport) attempt(req *http.Request, getBody func() (io.ReadCloser, error)) (*http.Response, error) {
+	var body io.ReadCloser
+	if getBody != nil && req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = getBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	r := req.WithContext(ctx)
+	if body != nil {
+		r.Body = body
+	}
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// cancelBody releases the context of an attempt once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil || string(b) != `{"name":"Jane"}` {
+			t.Errorf("attempt %d: body = %q, %v, expected the request body", n, b, err)
+		}
+		switch {
+		case r.URL.Path == "/hang" && n == 1:
+			time.Sleep(200 * time.Millisecond)
+		case n < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	for _, test := range []struct {
+		name     string
+		path     string
+		conf     clientConf
+		status   int
+		attempts int32
+		err      bool
+	}{
+		{name: "no retries", conf: clientConf{}, status: http.StatusServiceUnavailable, attempts: 1},
+		{name: "not enough retries", conf: clientConf{Retries: 1}, status: http.StatusServiceUnavailable, attempts: 2},
+		{name: "retries", conf: clientConf{Retries: 2}, status: http.StatusOK, attempts: 3},
+		{name: "timeout", path: "/hang", conf: clientConf{Timeout: duration(50 * time.Millisecond)}, err: true, attempts: 1},
+		{
+			name:     "timeout retried",
+			path:     "/hang",
+			conf:     clientConf{Timeout: duration(50 * time.Millisecond), Retries: 1},
+			status:   http.StatusServiceUnavailable,
+			attempts: 2,
+		},
+	} {
+		atomic.StoreInt32(&attempts, 0)
+		test.conf.RetryBackoff = duration(time.Millisecond)
+		client, _, err := test.conf.build()
+		if err != nil {
+			t.Fatalf("%s: build: unexpected error: %s", test.name, err)
+		}
+
+		resp, err := client.Post(srv.URL+test.path, "application/json", strings.NewReader(`{"name":"Jane"}`))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", test.name, resp.Status)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else {
+			_ = resp.Body.Close()
+			if resp.StatusCode != test.status {
+				t.Errorf("%s: status = %d, expected %d", test.name, resp.StatusCode, test.status)
+			}
+		}
+		if n := atomic.LoadInt32(&attempts); n != test.attempts {
+			t.Errorf("%s: %d attempts, expected %d", test.name, n, test.attempts)
+		}
+	}
+}
+
+func TestRetryTransportRedirect(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil || string(b) != `{"name":"Jane"}` {
+			t.Errorf("%s: body = %q, %v, expected the request body", r.URL.Path, b, err)
+		}
+		if r.URL.Path == "/users" {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			http.Redirect(w, r, "/v2/users", http.StatusTemporaryRedirect)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	conf := clientConf{Retries: 1, RetryBackoff: duration(time.Millisecond)}
+	client, _, err := conf.build()
+	if err != nil {
+		t.Fatalf("build: unexpected error: %s", err)
+	}
+
+	// requests read from request files have no GetBody
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(
+		"POST /users HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 15\r\n\r\n" +
+			`{"name":"Jane"}`,
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := getTargetResponseFromHost(req, targetConf{Host: strings.TrimPrefix(srv.URL, "http://"), client: client})
+	if err != nil {
+		t.Fatalf("getTargetResponseFromHost: unexpected error: %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/v2/users" {
+		t.Errorf("response = %s for %s, expected 200 OK for /v2/users", resp.Status, resp.Request.URL.Path)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("%d attempts, expected 2", n)
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "TestClientTLS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caCert := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		conf  clientConf
+		proto string
+	}{
+		{name: "default", conf: clientConf{}},
+		{name: "insecure", conf: clientConf{InsecureSkipVerify: true}, proto: "HTTP/2.0"},
+		{name: "CA", conf: clientConf{CACert: caCert}, proto: "HTTP/2.0"},
+		{name: "HTTP/1.1", conf: clientConf{CACert: caCert, DisableHTTP2: true}, proto: "HTTP/1.1"},
+	} {
+		client, _, err := test.conf.build()
+		if err != nil {
+			t.Fatalf("%s: build: unexpected error: %s", test.name, err)
+		}
+
+		resp, err := client.Get(srv.URL)
+		if test.proto == "" {
+			if err == nil {
+				t.Errorf("%s: expected a certificate error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil || string(b) != test.proto {
+			t.Errorf("%s: protocol = %q, %v, expected %q", test.name, b, err, test.proto)
+		}
+	}
+
+	_, _, err = clientConf{CACert: filepath.Join(dir, "missing.pem")}.build()
+	if err == nil {
+		t.Error("build(missing CA): expected an error, got nil")
+	}
+}
+
+func TestReadClientsConf(t *testing.T) {
+	expected := clientsConf{
+		Base: clientConf{InsecureSkipVerify: true},
+		Target: clientConf{
+			Timeout:      duration(10 * time.Second),
+			Retries:      2,
+			RetryBackoff: duration(time.Second),
+			Proxy:        "http://proxy:3128",
+		},
+	}
+
+	for ext, content := range map[string]string{
+		".json": `{
+			"Conf": [{"Path": "**", "Headers": {"Ignore": ["Date"]}}],
+			"Clients": {
+				"Base": {"InsecureSkipVerify": true},
+				"Target": {"Timeout": "10s", "Retries": 2, "RetryBackoff": "1s", "Proxy": "http://proxy:3128"}
+			}
+		}`,
+		".yaml": `conf:
+  - path: "**"
+    headers:
+      ignore: [Date]
+clients:
+  base:
+    insecure_skip_verify: true
+  target:
+    timeout: 10s
+    retries: 2
+    retry_backoff: 1s
+    proxy: http://proxy:3128
+`,
+		".toml": `[[conf]]
+    path = "**"
+    [conf.headers]
+        ignore = ["Date"]
+[clients.base]
+    insecureSkipVerify = true
+[clients.target]
+    timeout = "10s"
+    retries = 2
+    retryBackoff = "1s"
+    proxy = "http://proxy:3128"
+`,
+	} {
+		f, err := ioutil.TempFile("", "bacom-conf-*"+ext)
+		if err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		if err != nil {
+			t.Fatalf("writing temporary file: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("closing temporary file: %s", err)
+		}
+
+		conf, err := readClientsConf(f.Name())
+		if err != nil {
+			t.Errorf("readClientsConf(%s): unexpected error: %s", ext, err)
+		} else if !reflect.DeepEqual(conf, expected) {
+			t.Errorf("readClientsConf(%s) = %+v, expected %+v", ext, conf, expected)
+		}
+
+		paths, err := readPathConf(f.Name(), nil)
+		if err != nil {
+			t.Errorf("readPathConf(%s): unexpected error: %s", ext, err)
+		} else if len(paths) != 1 || !reflect.DeepEqual(paths[0].Headers.Ignore, []string{"Date"}) {
+			t.Errorf("readPathConf(%s) = %+v, expected a single path configuration", ext, paths)
+		}
+	}
+}
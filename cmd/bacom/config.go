@@ -10,10 +10,13 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom/grpc"
 )
 
 const (
@@ -24,11 +27,18 @@ const (
 	mvCmdName        = "mv"
 	cpCmdName        = "cp"
 	versionCmdName   = "version"
+	schemaCmdName    = "schema"
+	exportCmdName    = "export"
+	enumsCmdName     = "enums"
 	proxyDefaultAddr = "localhost:5480"
-
-	curlSubCmdName  = "curl"
-	harSubCmdName   = "har"
-	proxySubCmdName = "proxy"
+	grpcDefaultAddr  = "localhost:5481"
+
+	curlSubCmdName     = "curl"
+	harSubCmdName      = "har"
+	proxySubCmdName    = "proxy"
+	grpcSubCmdName     = "grpc"
+	postmanSubCmdName  = "postman"
+	insomniaSubCmdName = "insomnia"
 )
 
 var (
@@ -113,9 +123,17 @@ func (c *constraints) MarshalJSON() ([]byte, error) {
 }
 
 type targetConf struct {
-	Host       string
-	UseHTTPS   bool
-	PreProcess string
+	Host         string
+	UseHTTPS     bool
+	PreProcess   string
+	RequestHook  string
+	ResponseHook string
+	AuthSpec     string
+	Client       clientConf
+
+	Auth       authProvider
+	client     *http.Client
+	grpcClient *http.Client
 }
 
 func printGlobalUsage() {
@@ -130,6 +148,9 @@ COMMANDS:
     list    lists tests information
     mv      move request/response pairs around
     cp      copy request/response pairs
+    schema  generate a JSON schema from the stored responses
+    export  export requests and responses to other formats
+    enums   print the enum values found in the stored responses
     version print version information
 
 Note:
@@ -153,7 +174,8 @@ func getCommand() (cmd string, args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", cmd)
 		os.Exit(2)
-	case testCmdName, importCmdName, listCmdName, mvCmdName, cpCmdName, versionCmdName:
+	case testCmdName, importCmdName, listCmdName, mvCmdName, cpCmdName, versionCmdName,
+		schemaCmdName, exportCmdName, enumsCmdName:
 		return strings.ToLower(cmd), args
 	}
 
@@ -177,15 +199,31 @@ type testConf struct {
 	Quiet         bool
 	DumpResponses bool
 	PathsConfFile string
+	Parallel      int
+	ReportFormat  reportFormat
+	ReportFile    string
+	OpenAPIFile   string
+	Validators    varsFlag
+	Decompress    bool
+
+	CommandTimeout duration
+
+	Base    targetConf
+	Target  targetConf
+	Enums   endpointEnums
+	Paths   []pathConf
+	OpenAPI *openAPISpec
+	GraphQL graphQLConf
+	Vars    requestVars
 
-	Base   targetConf
-	Target targetConf
-	Paths  []pathConf
+	GRPCDescriptorsFile string
+	GRPCDescriptors     *grpc.Descriptors
 }
 
 func parseTestFlags(args []string) (c testConf, err error) {
 	c = testConf{
-		Constraints: defaultConstraints,
+		Constraints:    defaultConstraints,
+		CommandTimeout: duration(defaultCommandTimeout),
 	}
 
 	flags := flag.NewFlagSet(getBinaryName()+" "+testCmdName, flag.ExitOnError)
@@ -194,32 +232,98 @@ func parseTestFlags(args []string) (c testConf, err error) {
 	flags.Var(&c.Constraints, "version", "test version")
 	flags.Var(&c.TestFiles, "tests", "list of request files to run (can be repeated)")
 	flags.StringVar(&c.Save, "save", "", "save requests to target to the specified version")
+	flags.BoolVar(&c.Decompress, "decompress", false, "save decompressed response bodies (with -save)")
 	flags.BoolVar(&c.Verbose, "v", false, "print reasons")
 	flags.BoolVar(&c.Quiet, "q", false, "Reduce standard output")
 	flags.BoolVar(&c.DumpResponses, "dump", false, "dump responses to standard output for failing tests")
 	flags.StringVar(&c.PathsConfFile, "conf", "bacom.json", "configuration file")
+	flags.IntVar(&c.Parallel, "parallel", 1, "number of requests to run concurrently")
+	flags.Var(&c.ReportFormat, "report-format", "format of the test report (json, junit or tap)")
+	flags.StringVar(&c.ReportFile, "report-file", "", "file to write the test report to (requires -report-format)")
+	flags.StringVar(&c.OpenAPIFile, "openapi", "", "OpenAPI 3 document (json or yaml) to validate the target responses against")
+	flags.BoolVar(&c.GraphQL.Enabled, "graphql", false, "report the errors of GraphQL responses as failures")
+	flags.StringVar(&c.GraphQL.SchemaFile, "graphql-schema", "", "GraphQL schema (SDL) of the target, used to report breaking changes (implies -graphql)")
+	flags.StringVar(&c.GraphQL.BaseSchemaFile, "graphql-base-schema", "", "GraphQL schema (SDL) of the base, used to report type and nullability changes (requires -graphql-schema)")
+	flags.StringVar(&c.GRPCDescriptorsFile, "grpc-descriptors", "", "descriptor set (protoc --descriptor_set_out) of the target, used to report breaking changes of gRPC calls")
+	flags.Var(&c.Validators, "validator", "external validator referenced in the configuration (name=command, can be repeated)")
+	flags.Var(&c.CommandTimeout, "command-timeout", "timeout of the pre-process commands, hooks and validators (0 for no timeout)")
 
 	flags.StringVar(&c.Base.Host, "base-host", "", "host for the base to compare to (leave empty to use saved tests versions)")
 	flags.BoolVar(&c.Base.UseHTTPS, "base-use-https", false, "use https for requests to the base host")
 	flags.StringVar(&c.Base.PreProcess, "base-preprocess", "", "command used to pre-process requests sent to the base")
+	flags.StringVar(&c.Base.RequestHook, "base-request-hook", "", "command receiving the requests sent to the base as JSON, and writing them back modified")
+	flags.StringVar(&c.Base.ResponseHook, "base-response-hook", "", "command receiving the base responses as JSON before comparison, and writing them back modified")
+	flags.StringVar(&c.Base.AuthSpec, "base-auth", "", "credentials for the requests to the base host (bearer:<token>, basic:<user>:<password> or a yaml/json file)")
+	c.Base.Client.SetupFlags(flags, "base")
 	flags.StringVar(&c.Target.Host, "target-host", "localhost", "host for the target to compare (can include port)")
 	flags.BoolVar(&c.Target.UseHTTPS, "target-use-https", false, "use httpsfor the requests to the target host")
 	flags.StringVar(&c.Target.PreProcess, "target-preprocess", "", "command used to pre-process requests sent to the target")
+	flags.StringVar(&c.Target.RequestHook, "target-request-hook", "", "command receiving the requests sent to the target as JSON, and writing them back modified")
+	flags.StringVar(&c.Target.ResponseHook, "target-response-hook", "", "command receiving the target responses as JSON before comparison, and writing them back modified")
+	flags.StringVar(&c.Target.AuthSpec, "target-auth", "", "credentials for the requests to the target host (bearer:<token>, basic:<user>:<password> or a yaml/json file)")
+	c.Target.Client.SetupFlags(flags, "target")
+	c.Vars.SetupFlags(flags)
 	err = flags.Parse(args)
 	if err != nil {
 		return c, err
 	}
+	c.Vars.Root = c.Dir
+	err = c.Vars.load()
+	if err != nil {
+		return c, err
+	}
+	err = c.setupClients()
+	if err != nil {
+		return c, err
+	}
+	c.Base.Auth, err = readAuthProvider(c.Base.AuthSpec, c.Vars.global(), c.Base.client)
+	if err != nil {
+		return c, errors.Wrap(err, "-base-auth")
+	}
+	c.Target.Auth, err = readAuthProvider(c.Target.AuthSpec, c.Vars.global(), c.Target.client)
+	if err != nil {
+		return c, errors.Wrap(err, "-target-auth")
+	}
 
 	if c.Verbose && c.Quiet {
 		return c, errors.New("conflicting -v and -q")
 	}
+	if c.Parallel < 1 {
+		return c, errors.Errorf("invalid -parallel value %d, expected at least 1", c.Parallel)
+	}
+	if (c.ReportFormat == noReport) != (c.ReportFile == "") {
+		return c, errors.New("-report-format and -report-file must be used together")
+	}
+	if c.OpenAPIFile != "" {
+		c.OpenAPI, err = readOpenAPISpec(c.OpenAPIFile)
+		if err != nil {
+			return c, err
+		}
+	}
+	err = c.GraphQL.readSchemas()
+	if err != nil {
+		return c, err
+	}
+	if c.GRPCDescriptorsFile != "" {
+		c.GRPCDescriptors, err = readDescriptorSet(c.GRPCDescriptorsFile)
+		if err != nil {
+			return c, err
+		}
+	}
+	err = registerCommandValidators(c.Validators, time.Duration(c.CommandTimeout))
+	if err != nil {
+		return c, err
+	}
 	if c.PathsConfFile == "" {
 		return c, nil
 	}
 
 	c.Paths, err = readPathConf(c.PathsConfFile, defaultPathsConfig)
+	if err != nil {
+		return c, errors.Wrapf(err, "parsing configuration file %q", c.PathsConfFile)
+	}
 
-	return c, errors.Wrapf(err, "parsing configuration file %q", c.PathsConfFile)
+	return c, checkValidators(c.Paths)
 }
 
 type stringsFlag []string
@@ -242,6 +346,37 @@ func (ss stringsFlag) String() string {
 	return strings.Join(ss, ",")
 }
 
+type varsFlag map[string]string
+
+func (vv *varsFlag) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return errors.Errorf("invalid variable %q, expected name=value", s)
+	}
+	if *vv == nil {
+		*vv = make(map[string]string)
+	}
+
+	(*vv)[parts[0]] = parts[1]
+
+	return nil
+}
+
+func (vv varsFlag) String() string {
+	names := make([]string, 0, len(vv))
+	for name := range vv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+vv[name])
+	}
+
+	return strings.Join(parts, ",")
+}
+
 type regexesFlag []*regexp.Regexp
 
 func (rr *regexesFlag) Set(s string) error {
@@ -291,13 +426,84 @@ func parseImportHARFlags(args []string) (c importHARConf, err error) {
 	return c, nil
 }
 
-type importProxyConf struct {
-	Listen  string
-	Target  string
+type importPostmanConf struct {
 	Dir     string
+	Files   []string
+	Verbose bool
+	EnvFile string
+	Vars    varsFlag
+
 	Filters reqFilters
+}
+
+func parseImportPostmanFlags(args []string) (c importPostmanConf, err error) {
+	c.Filters.IgnoreMethods = stringsFlag{http.MethodOptions, http.MethodHead}
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+importCmdName+" "+postmanSubCmdName, flag.ExitOnError)
+
+	flags.StringVar(&c.Dir, "out", ".", "output directory")
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.StringVar(&c.EnvFile, "env", "", "postman environment file used to resolve variables")
+	flags.Var(&c.Vars, "var", "variable used when resolving requests (name=value, can be repeated)")
+	c.Filters.SetupFlags(flags)
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+
+	c.Files = flags.Args()
+
+	if len(c.Files) == 0 {
+		return c, errors.New("missing input file(s)")
+	}
+
+	return c, nil
+}
+
+type importInsomniaConf struct {
+	Dir     string
+	Files   []string
 	Verbose bool
-	Graph   bool
+	Env     string
+	Vars    varsFlag
+
+	Filters reqFilters
+}
+
+func parseImportInsomniaFlags(args []string) (c importInsomniaConf, err error) {
+	c.Filters.IgnoreMethods = stringsFlag{http.MethodOptions, http.MethodHead}
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+importCmdName+" "+insomniaSubCmdName, flag.ExitOnError)
+
+	flags.StringVar(&c.Dir, "out", ".", "output directory")
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.StringVar(&c.Env, "env", "", "name of the insomnia sub-environment used to resolve variables")
+	flags.Var(&c.Vars, "var", "variable used when resolving requests (name=value, can be repeated)")
+	c.Filters.SetupFlags(flags)
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+
+	c.Files = flags.Args()
+
+	if len(c.Files) == 0 {
+		return c, errors.New("missing input file(s)")
+	}
+
+	return c, nil
+}
+
+type importProxyConf struct {
+	Listen     string
+	Target     string
+	Dir        string
+	Filters    reqFilters
+	Verbose    bool
+	Graph      bool
+	Decompress bool
 }
 
 func parseImportProxyFlags(args []string) (c importProxyConf, err error) {
@@ -312,6 +518,7 @@ func parseImportProxyFlags(args []string) (c importProxyConf, err error) {
 	c.Filters.SetupFlags(flags)
 	flags.BoolVar(&c.Verbose, "v", false, "verbose")
 	flags.BoolVar(&c.Graph, "graph", false, "enable GraphQL support")
+	flags.BoolVar(&c.Decompress, "decompress", false, "save decompressed response bodies")
 
 	err = flags.Parse(args)
 	if err != nil {
@@ -324,6 +531,45 @@ func parseImportProxyFlags(args []string) (c importProxyConf, err error) {
 	return c, nil
 }
 
+type importGRPCConf struct {
+	Listen          string
+	Target          string
+	Dir             string
+	DescriptorsFile string
+	Filters         reqFilters
+	Verbose         bool
+
+	Descriptors *grpc.Descriptors
+}
+
+func parseImportGRPCFlags(args []string) (c importGRPCConf, err error) {
+	c.Listen = grpcDefaultAddr
+	c.Dir = defaultDir
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+importCmdName+" "+grpcSubCmdName, flag.ExitOnError)
+
+	flags.StringVar(&c.Listen, "listen", c.Listen, "address to listen on")
+	flags.StringVar(&c.Target, "target", c.Target, "target gRPC server (i.e http://localhost:50051, https for TLS)")
+	flags.StringVar(&c.Dir, "out", c.Dir, "output directory")
+	flags.StringVar(&c.DescriptorsFile, "descriptors", "", "descriptor set of the services (protoc --include_imports --descriptor_set_out)")
+	c.Filters.SetupFlags(flags)
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+	if c.Target == "" {
+		return c, errors.New("missing -target")
+	}
+	if c.DescriptorsFile == "" {
+		return c, errors.New("missing -descriptors")
+	}
+	c.Descriptors, err = readDescriptorSet(c.DescriptorsFile)
+
+	return c, err
+}
+
 type headers map[string][]string
 
 func (h *headers) String() string {
@@ -414,9 +660,10 @@ type curlConf struct {
 	Data    dataFlag
 
 	// bacom options
-	Name    string
-	Dir     string
-	Verbose bool
+	Name       string
+	Dir        string
+	Verbose    bool
+	Decompress bool
 }
 
 func parseCurlFlags(args []string) (c curlConf, err error) {
@@ -432,6 +679,7 @@ func parseCurlFlags(args []string) (c curlConf, err error) {
 	)
 	flags.StringVar(&c.Dir, "dir", "", "folder to save the request/response files in")
 	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.BoolVar(&c.Decompress, "decompress", false, "save the decompressed response body")
 
 	flags.StringVar(&c.Method, "X", http.MethodGet, "Specify request command to use")
 	flags.StringVar(&c.URL, "url", "", "URL to work with")
@@ -443,8 +691,7 @@ func parseCurlFlags(args []string) (c curlConf, err error) {
 	flags.Var(&c.Data, "data-binary", "HTTP POST binary data")
 	flags.Var((*dataRawFlag)(&c.Data), "data-raw", "HTTP POST data, '@' allowed")
 
-	// Flags defined for compatibility purposes
-	flags.Bool("compressed", false, "placeholder for curl's --compressed option")
+	flags.BoolVar(&c.Decompress, "compressed", false, "same as -decompress")
 
 	err = flags.Parse(args)
 	if err != nil {
@@ -522,3 +769,118 @@ func parseCpFlags(args []string) (c cpConf, err error) {
 
 	return c, nil
 }
+
+type schemaConf struct {
+	Dir           string
+	Constraints   constraints
+	Out           string
+	Verbose       bool
+	PathsConfFile string
+
+	Paths []pathConf
+}
+
+func parseSchemaFlags(args []string) (c schemaConf, err error) {
+	c = schemaConf{
+		Constraints: defaultConstraints,
+	}
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+schemaCmdName, flag.ExitOnError)
+
+	flags.StringVar(&c.Dir, "dir", defaultDir, "directory containing the tests")
+	flags.Var(&c.Constraints, "version", "versions to infer the schema from")
+	flags.StringVar(&c.Out, "out", "", "output file (defaults to standard output)")
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.StringVar(&c.PathsConfFile, "conf", "bacom.json", "configuration file")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+	if c.PathsConfFile == "" {
+		return c, nil
+	}
+
+	c.Paths, err = readPathConf(c.PathsConfFile, defaultPathsConfig)
+
+	return c, errors.Wrapf(err, "parsing configuration file %q", c.PathsConfFile)
+}
+
+type exportConf struct {
+	Dir         string
+	Constraints constraints
+	Out         string
+	Verbose     bool
+	UseHTTPS    bool
+	PreProcess  string
+	RequestHook string
+
+	CommandTimeout duration
+
+	Filters reqFilters
+	Vars    requestVars
+}
+
+func parseExportFlags(subCmd string, args []string) (c exportConf, err error) {
+	c = exportConf{
+		Constraints:    defaultConstraints,
+		CommandTimeout: duration(defaultCommandTimeout),
+	}
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+exportCmdName+" "+subCmd, flag.ExitOnError)
+
+	flags.StringVar(&c.Dir, "dir", defaultDir, "directory containing the tests")
+	flags.Var(&c.Constraints, "version", "versions to export")
+	flags.StringVar(&c.Out, "out", "", "output file (defaults to standard output)")
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.BoolVar(&c.UseHTTPS, "use-https", false, "use https in the exported urls")
+	flags.StringVar(&c.PreProcess, "preprocess", "", "command used to pre-process requests before exporting them")
+	flags.StringVar(&c.RequestHook, "request-hook", "", "command receiving the requests as JSON before exporting them, and writing them back modified")
+	flags.Var(&c.CommandTimeout, "command-timeout", "timeout of the pre-process command and hook (0 for no timeout)")
+	c.Filters.SetupFlags(flags)
+	c.Vars.SetupFlags(flags)
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+	c.Vars.Root = c.Dir
+
+	return c, c.Vars.load()
+}
+
+type enumsConf struct {
+	Dir           string
+	Constraints   constraints
+	Verbose       bool
+	PathsConfFile string
+	EnumPaths     stringsFlag
+
+	Paths []pathConf
+}
+
+func parseEnumsFlags(args []string) (c enumsConf, err error) {
+	c = enumsConf{
+		Constraints: defaultConstraints,
+	}
+
+	flags := flag.NewFlagSet(getBinaryName()+" "+enumsCmdName, flag.ExitOnError)
+
+	flags.StringVar(&c.Dir, "dir", defaultDir, "directory containing the tests")
+	flags.Var(&c.Constraints, "version", "versions to collect the enum values from")
+	flags.BoolVar(&c.Verbose, "v", false, "verbose")
+	flags.StringVar(&c.PathsConfFile, "conf", "bacom.json", "configuration file")
+	flags.Var(&c.EnumPaths, "paths", "JSON paths to collect values for, in addition to the configuration's json.enums (can be repeated)")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return c, err
+	}
+	if c.PathsConfFile == "" {
+		return c, nil
+	}
+
+	c.Paths, err = readPathConf(c.PathsConfFile, defaultPathsConfig)
+
+	return c, errors.Wrapf(err, "parsing configuration file %q", c.PathsConfFile)
+}
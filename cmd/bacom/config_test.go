@@ -70,3 +70,28 @@ func TestRegexesFlag(t *testing.T) {
 		t.Errorf("regexesFlag.Set(%q).String() = %q, expected %q", s, flags.String(), expectedString)
 	}
 }
+
+func TestVarsFlag(t *testing.T) {
+	var flags varsFlag
+
+	for _, s := range []string{"host=localhost:8080", "token=abc=def"} {
+		err := flags.Set(s)
+		if err != nil {
+			t.Errorf("varsFlag.Set(%q): unexpected error %s", s, err)
+		}
+	}
+	expected := varsFlag{"host": "localhost:8080", "token": "abc=def"}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("varsFlag = %q, expected %q", flags, expected)
+	}
+	expectedString := "host=localhost:8080,token=abc=def"
+	if flags.String() != expectedString {
+		t.Errorf("varsFlag.String() = %q, expected %q", flags.String(), expectedString)
+	}
+
+	s := "invalid"
+	err := flags.Set(s)
+	if err == nil {
+		t.Errorf("varsFlag.Set(%q): expected error, got nil", s)
+	}
+}
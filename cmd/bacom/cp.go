@@ -71,20 +71,7 @@ func cpFileToDir(src, dir string) error {
 		return err
 	}
 
-	srcResp, err := bacom.GetResponseFilename(src)
-	if err != nil {
-		return err
-	}
-	dstResp, err := bacom.GetResponseFilename(reqFname)
-	if err != nil {
-		return err
-	}
-	respExists, err := fileExists(srcResp)
-	if err != nil || !respExists {
-		return err
-	}
-
-	return cpFile(srcResp, dstResp)
+	return withCompanions(src, reqFname, cpFile)
 }
 
 func cpFileToFile(src, dst string) error {
@@ -99,20 +86,7 @@ func cpFileToFile(src, dst string) error {
 		return err
 	}
 
-	srcResp, err := bacom.GetResponseFilename(src)
-	if err != nil {
-		return err
-	}
-	dstResp, err := bacom.GetResponseFilename(reqFname)
-	if err != nil {
-		return err
-	}
-	respExists, err := fileExists(srcResp)
-	if err != nil || !respExists {
-		return err
-	}
-
-	return cpFile(srcResp, dstResp)
+	return withCompanions(src, reqFname, cpFile)
 }
 
 func cpFile(srcFname, dstFname string) (err error) {
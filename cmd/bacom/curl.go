@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/yazgazan/bacom"
 )
@@ -68,7 +70,11 @@ func importCurlCmd(args []string) {
 	f, err = os.Create(respFile)
 	logAndExitOnError(err)
 	defer closeOrExit(f)
-	err = resp.Write(f)
+	if c.Decompress {
+		err = bacom.DecompressBody(resp)
+		logAndExitOnError(err)
+	}
+	err = bacom.WriteResponse(f, resp)
 	logAndExitOnError(err)
 
 	if c.Verbose {
@@ -86,3 +92,80 @@ func newRequest(method, url string, h http.Header, body io.Reader) (*http.Reques
 
 	return req, nil
 }
+
+func exportCurlCmd(args []string) {
+	c, err := parseExportFlags(curlSubCmdName, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	pairs, err := collectExportPairs(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = writeExport(c.Out, func(w io.Writer) error {
+		for i, pair := range pairs {
+			if i != 0 {
+				_, err := io.WriteString(w, "\n")
+				if err != nil {
+					return err
+				}
+			}
+			_, err := io.WriteString(w, curlCommand(pair))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// curlCommand renders a request as a runnable curl command, preceded by a comment naming the test
+func curlCommand(pair exportPair) string {
+	name, _ := bacom.NameFromReqFileName(pair.fname)
+	lines := []string{}
+
+	method := "-X " + pair.req.Method
+	if pair.req.Method == http.MethodHead {
+		method = "--head"
+	}
+	lines = append(lines, "curl "+method+" "+shellQuote(pair.req.URL.String()))
+
+	for _, h := range exportHeaders(pair.req.Header) {
+		lines = append(lines, "-H "+shellQuote(h.Key+": "+string(h.Value)))
+	}
+
+	cmd := ""
+	if len(pair.reqBody) != 0 {
+		if utf8.Valid(pair.reqBody) && !bytes.ContainsRune(pair.reqBody, 0) {
+			lines = append(lines, "--data-binary "+shellQuote(string(pair.reqBody)))
+		} else {
+			// binary bodies are piped through printf using octal escapes
+			cmd = "printf " + shellQuote(octalEscape(pair.reqBody)) + " | "
+			lines = append(lines, "--data-binary @-")
+		}
+	}
+
+	return fmt.Sprintf("# %s/%s\n%s%s\n", pair.version, name, cmd, strings.Join(lines, " \\\n  "))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func octalEscape(b []byte) string {
+	var buf strings.Builder
+
+	for _, c := range b {
+		fmt.Fprintf(&buf, `\%03o`, c)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+func enumsCmd(args []string) {
+	c, err := parseEnumsFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	versions, err := bacom.FindVersions(c.Dir, c.Verbose, c.Constraints)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	enums, err := collectEnums(c.Verbose, c.Paths, c.EnumPaths, versions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	printEnums(enums)
+}
+
+// endpointEnums holds the enum collectors, keyed by enumEndpoint
+type endpointEnums map[string]*bacom.EnumCollector
+
+// enumEndpoint returns the key of the enum collector of a request: its method and the path pattern of the last
+// configuration listing json.enums for it, so that the paths matching a pattern (e.g. /api/orders/*) share their known
+// values. Requests without json.enums in the configuration are keyed by their path.
+func enumEndpoint(verbose bool, conf []pathConf, version, method, path string) string {
+	endpoint := path
+	for _, c := range matchPathConf(verbose, conf, version, method, path) {
+		if len(c.JSON.Enums) != 0 {
+			endpoint = c.Path
+		}
+	}
+
+	return method + " " + endpoint
+}
+
+// collectEnums gathers the enum values found in the stored responses. The enum paths are the
+// json.enums of the configuration, in addition to extraPaths.
+func collectEnums(verbose bool, conf []pathConf, extraPaths []string, versions []string) (endpointEnums, error) {
+	enums := endpointEnums{}
+
+	for _, dirname := range versions {
+		reqFiles, err := bacom.GetRequestsFiles(dirname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looking for requests files in %q", dirname)
+		}
+
+		for _, fname := range reqFiles {
+			err = addEnumSample(verbose, conf, extraPaths, enums, filepath.Base(dirname), fname)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return enums, nil
+}
+
+func addEnumSample(verbose bool, conf []pathConf, extraPaths []string, enums endpointEnums, version, fname string) (err error) {
+	req, err := parseRequest(nil, requestHooks{}, fname)
+	if err != nil {
+		return err
+	}
+
+	pConf := getPathConf(verbose, conf, version, req.Method, req.URL.Path)
+	// the enums are copied, as the configuration is shared with the other jobs
+	paths := append(append([]string(nil), pConf.JSON.Enums...), extraPaths...)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	resp, err := bacom.ReadResponse(req, fname)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "reading response for %q", fname)
+	}
+	defer handleClose(&err, resp.Body)
+
+	body, err := readBody(resp, pConf.Body)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "skipping %q: %s\n", fname, err)
+		}
+		return nil
+	}
+
+	endpoint := enumEndpoint(verbose, conf, version, req.Method, req.URL.Path)
+	c, ok := enums[endpoint]
+	if !ok {
+		c = bacom.NewEnumCollector(paths)
+		enums[endpoint] = c
+	}
+	c.Add(unwrapBody(body))
+
+	return nil
+}
+
+// hasEnums returns true if json.enums is used in the configuration
+func hasEnums(conf []pathConf) bool {
+	for _, c := range conf {
+		if len(c.JSON.Enums) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func printEnums(enums endpointEnums) {
+	endpoints := make([]string, 0, len(enums))
+	for endpoint := range enums {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	for _, endpoint := range endpoints {
+		values := enums[endpoint].Enums()
+		if len(values) == 0 {
+			continue
+		}
+		paths := make([]string, 0, len(values))
+		for path := range values {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		fmt.Printf("%s:\n", endpoint)
+		for _, path := range paths {
+			fmt.Printf("\t%s: %s\n", path, strings.Join(values[path], ", "))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCollectEnums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestCollectEnums")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "v1.0.0")
+	err = os.Mkdir(versionDir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := func(status string) string {
+		body := `{"status": "` + status + `"}`
+		return "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: " +
+			strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	}
+	writeTestFiles(t, versionDir, map[string]string{
+		"order1_req.txt":  "GET /api/orders/1 HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"order1_resp.txt": order("paid"),
+		"order2_req.txt":  "GET /api/orders/2 HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"order2_resp.txt": order("shipped"),
+		"user_req.txt":    "GET /api/users/1 HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"user_resp.txt":   order("active"),
+	})
+	conf := []pathConf{{Path: "/api/orders/*", JSON: jsonConf{Enums: []string{".status"}}}}
+
+	enums, err := collectEnums(false, conf, nil, []string{versionDir})
+	if err != nil {
+		t.Fatalf("collectEnums: unexpected error: %s", err)
+	}
+	expected := map[string][]string{".status": {"paid", "shipped"}}
+	if len(enums) != 1 || enums["GET /api/orders/*"] == nil {
+		t.Fatalf("collectEnums = %v, expected a single collector for GET /api/orders/*", enums)
+	}
+	if got := enums["GET /api/orders/*"].Enums(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("collectEnums: values = %v, expected %v", got, expected)
+	}
+
+	// the values recorded for /api/orders/1 are known for the other orders
+	endpoint := enumEndpoint(false, conf, "v1.0.0", "GET", "/api/orders/3")
+	if endpoint != "GET /api/orders/*" {
+		t.Fatalf("enumEndpoint(/api/orders/3) = %q, expected %q", endpoint, "GET /api/orders/*")
+	}
+	if diffs := enums[endpoint].Unseen(map[string]interface{}{"status": "paid"}); len(diffs) != 0 {
+		t.Errorf("Unseen(paid) = %+v, expected no differences", diffs)
+	}
+	if diffs := enums[endpoint].Unseen(map[string]interface{}{"status": "refunded"}); len(diffs) != 1 {
+		t.Errorf("Unseen(refunded) = %+v, expected an unknown_enum difference", diffs)
+	}
+
+	if endpoint := enumEndpoint(false, conf, "v1.0.0", "GET", "/api/users/1"); endpoint != "GET /api/users/1" {
+		t.Errorf("enumEndpoint(/api/users/1) = %q, expected %q", endpoint, "GET /api/users/1")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+func exportCmd(args []string) {
+	var cmd string
+
+	cmd, args = getExportSubCommand(args)
+	switch cmd {
+	default:
+		fmt.Fprintf(os.Stderr, "command %q not implemented yet\n", cmd)
+		os.Exit(1)
+	case harSubCmdName:
+		exportHarCmd(args)
+	case curlSubCmdName:
+		exportCurlCmd(args)
+	case postmanSubCmdName:
+		exportPostmanCmd(args)
+	}
+}
+
+func getExportSubCommand(args []string) (cmd string, cmdArgs []string) {
+	if len(args) == 0 {
+		printExportUsage()
+		os.Exit(2)
+	}
+	cmd = args[0]
+	cmdArgs = args[1:]
+
+	switch strings.ToLower(cmd) {
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown export sub-command %q\n", cmd)
+		os.Exit(2)
+	case harSubCmdName, curlSubCmdName, postmanSubCmdName:
+		return strings.ToLower(cmd), cmdArgs
+	}
+
+	return "", nil
+}
+
+func printExportUsage() {
+	bin := getBinaryName()
+	fmt.Fprintf(
+		os.Stderr,
+		`Usage: %s export [SUB-COMMAND] [OPTIONS]
+
+SUB-COMMANDS:
+    har      export requests and responses to a HAR 1.2 file
+    curl     export requests as curl commands
+    postman  export requests and responses to a postman v2.1 collection
+
+Note:
+    "%s export SUB-COMMAND -h" to get an overview of each sub-command's flags
+
+`,
+		bin, bin,
+	)
+}
+
+// exportPair is a request/response pair read from a version directory.
+// resp is nil if the response file is missing.
+type exportPair struct {
+	version  string
+	fname    string
+	req      *http.Request
+	reqBody  []byte
+	resp     *http.Response
+	respBody []byte
+	modTime  time.Time
+}
+
+func collectExportPairs(c exportConf) ([]exportPair, error) {
+	var pairs []exportPair
+
+	versions, err := bacom.FindVersions(c.Dir, c.Verbose, c.Constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dirname := range versions {
+		reqFiles, err := bacom.GetRequestsFiles(dirname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looking for requests files in %q", dirname)
+		}
+
+		for _, fname := range reqFiles {
+			pair, err := readExportPair(c, filepath.Base(dirname), fname)
+			if err != nil {
+				return nil, err
+			}
+			if c.Filters.Match(pair.req) != nil {
+				if c.Verbose {
+					fmt.Fprintf(os.Stderr, "excluding %q\n", fname)
+				}
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs, nil
+}
+
+func readExportPair(c exportConf, version, fname string) (pair exportPair, err error) {
+	pair = exportPair{
+		version: version,
+		fname:   fname,
+	}
+
+	pair.req, err = parseRequest(&c.Vars, requestHooks{
+		PreProcess: c.PreProcess,
+		Hook:       c.RequestHook,
+		Timeout:    time.Duration(c.CommandTimeout),
+		Version:    version,
+	}, fname)
+	if err != nil {
+		return pair, err
+	}
+	pair.reqBody, err = ioutil.ReadAll(pair.req.Body)
+	if err != nil {
+		return pair, errors.Wrapf(err, "reading request body from %q", fname)
+	}
+	pair.req.Body = ioutil.NopCloser(bytes.NewReader(pair.reqBody))
+	pair.req.URL.Host = pair.req.Host
+	pair.req.URL.Scheme = "http"
+	if c.UseHTTPS {
+		pair.req.URL.Scheme = "https"
+	}
+	if info, err := os.Stat(fname); err == nil {
+		pair.modTime = info.ModTime()
+	}
+
+	resp, err := bacom.ReadResponse(pair.req, fname)
+	if os.IsNotExist(err) {
+		if c.Verbose {
+			fmt.Fprintf(os.Stderr, "%q: missing response\n", fname)
+		}
+		return pair, nil
+	}
+	if err != nil {
+		return pair, errors.Wrapf(err, "reading response for %q", fname)
+	}
+	defer handleClose(&err, resp.Body)
+
+	// the exported bodies are decoded
+	err = bacom.DecompressBody(resp)
+	if err != nil {
+		return pair, errors.Wrapf(err, "decompressing response for %q", fname)
+	}
+	pair.respBody, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return pair, errors.Wrapf(err, "reading response body for %q", fname)
+	}
+	pair.resp = resp
+	if respFname, err := bacom.GetResponseFilename(fname); err == nil {
+		if info, err := os.Stat(respFname); err == nil {
+			pair.modTime = info.ModTime()
+		}
+	}
+
+	return pair, nil
+}
+
+// writeExport writes to fname, or to the standard output if fname is empty
+func writeExport(fname string, write func(w io.Writer) error) (err error) {
+	if fname == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer handleClose(&err, f)
+
+	return write(f)
+}
+
+// exportHeaders returns the request headers worth exporting, sorted by name.
+// Content-Length is left to the client as the body may be edited.
+func exportHeaders(header http.Header) []postmanKV {
+	var kvs []postmanKV
+
+	for _, name := range sortedHeaderNames(header) {
+		if name == "Content-Length" {
+			continue
+		}
+		for _, v := range header[name] {
+			kvs = append(kvs, postmanKV{Key: name, Value: postmanValue(v)})
+		}
+	}
+
+	return kvs
+}
+
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
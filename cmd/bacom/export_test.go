@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func newTestExportPair(t *testing.T, method, u string, body []byte) exportPair {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest: unexpected error: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", "42")
+
+	return exportPair{
+		version: "v1.0.0",
+		fname:   "bacom-tests/v1.0.0/post-api_req.txt",
+		req:     req,
+		reqBody: body,
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	for _, test := range []struct {
+		Method   string
+		Body     []byte
+		Expected string
+	}{
+		{
+			Method: http.MethodPost,
+			Body:   []byte(`{"name": "it's"}`),
+			Expected: "# v1.0.0/post-api\n" +
+				"curl -X POST 'http://localhost:8080/api?q=1' \\\n" +
+				"  -H 'Content-Type: application/json' \\\n" +
+				"  --data-binary '{\"name\": \"it'\\''s\"}'\n",
+		},
+		{
+			Method: http.MethodHead,
+			Expected: "# v1.0.0/post-api\n" +
+				"curl --head 'http://localhost:8080/api?q=1' \\\n" +
+				"  -H 'Content-Type: application/json'\n",
+		},
+		{
+			Method: http.MethodPut,
+			Body:   []byte{0xff, 0x00},
+			Expected: "# v1.0.0/post-api\n" +
+				"printf '\\377\\000' | curl -X PUT 'http://localhost:8080/api?q=1' \\\n" +
+				"  -H 'Content-Type: application/json' \\\n" +
+				"  --data-binary @-\n",
+		},
+	} {
+		pair := newTestExportPair(t, test.Method, "http://localhost:8080/api?q=1", test.Body)
+		got := curlCommand(pair)
+		if got != test.Expected {
+			t.Errorf("curlCommand(%s) = %q, expected %q", test.Method, got, test.Expected)
+		}
+	}
+}
+
+func TestNewPostmanCollection(t *testing.T) {
+	pairs := []exportPair{
+		newTestExportPair(t, http.MethodPost, "http://localhost:8080/api", []byte(`{}`)),
+		newTestExportPair(t, http.MethodGet, "http://localhost:8080/api", nil),
+	}
+	pairs[1].version = "v1.1.0"
+
+	collection := newPostmanCollection("tests", pairs)
+	if collection.Info.Schema != postmanSchema {
+		t.Errorf("collection.Info.Schema = %q, expected %q", collection.Info.Schema, postmanSchema)
+	}
+	if len(collection.Item) != 2 {
+		t.Fatalf("len(collection.Item) = %d, expected 2", len(collection.Item))
+	}
+
+	item := collection.Item[0].Item[0]
+	if item.Name != "post-api" {
+		t.Errorf("item.Name = %q, expected %q", item.Name, "post-api")
+	}
+	if item.Request.Body == nil || item.Request.Body.Raw != "{}" {
+		t.Errorf("item.Request.Body = %+v, expected a raw body", item.Request.Body)
+	} else if item.Request.Body.Options == nil || item.Request.Body.Options.Raw.Language != "json" {
+		t.Errorf("item.Request.Body.Options = %+v, expected json language", item.Request.Body.Options)
+	}
+	for _, h := range item.Request.Header {
+		if h.Key == "Content-Length" {
+			t.Error("item.Request.Header: unexpected Content-Length header")
+		}
+	}
+	if collection.Item[1].Item[0].Request.Body != nil {
+		t.Errorf("collection.Item[1].Item[0].Request.Body = %+v, expected nil", collection.Item[1].Item[0].Request.Body)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/graphql"
+)
+
+// graphQLConf enables the GraphQL checks of the test command
+type graphQLConf struct {
+	Enabled        bool
+	SchemaFile     string
+	BaseSchemaFile string
+
+	Schema *graphql.Schema
+	Base   *graphql.Schema
+}
+
+func (c *graphQLConf) readSchemas() (err error) {
+	if c.BaseSchemaFile != "" && c.SchemaFile == "" {
+		return errors.New("-graphql-base-schema requires -graphql-schema")
+	}
+	if c.SchemaFile == "" {
+		return nil
+	}
+	c.Enabled = true
+
+	c.Schema, err = readGraphQLSchema(c.SchemaFile)
+	if err != nil || c.BaseSchemaFile == "" {
+		return err
+	}
+	c.Base, err = readGraphQLSchema(c.BaseSchemaFile)
+
+	return err
+}
+
+func readGraphQLSchema(fname string) (*graphql.Schema, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading GraphQL schema %q", fname)
+	}
+	schema, err := graphql.ParseSchema(string(b))
+
+	return schema, errors.Wrapf(err, "reading GraphQL schema %q", fname)
+}
+
+// graphQLRequest is the query sent by a GraphQL request
+type graphQLRequest struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+}
+
+// readGraphQLRequest reads the GraphQL query from the request stored in fname (using the query string for GET
+// requests). ok is false if the request isn't a GraphQL request.
+func readGraphQLRequest(fname string) (gqlReq graphQLRequest, ok bool, err error) {
+	req, err := parseRequest(nil, requestHooks{}, fname)
+	if err != nil {
+		return gqlReq, false, err
+	}
+	defer handleClose(&err, req.Body)
+
+	if req.Method == http.MethodGet {
+		q := req.URL.Query()
+		gqlReq.Query, gqlReq.OperationName = q.Get("query"), q.Get("operationName")
+
+		return gqlReq, gqlReq.Query != "", nil
+	}
+
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return gqlReq, false, errors.Wrapf(err, "reading request body %q", fname)
+	}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "application/graphql" {
+		gqlReq.Query = string(b)
+		return gqlReq, true, nil
+	}
+	if json.Unmarshal(b, &gqlReq) != nil {
+		// not a GraphQL request (batched queries aren't supported)
+		return graphQLRequest{}, false, nil
+	}
+
+	return gqlReq, gqlReq.Query != "", nil
+}
+
+// graphQLDifferences reports the errors of the target response that are not in the base response (even if the
+// status is 200) and, if a schema was provided, the schema changes breaking the query.
+func graphQLDifferences(conf graphQLConf, fname string, baseBody, targetBody interface{}) ([]bacom.Difference, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	gqlReq, ok, err := readGraphQLRequest(fname)
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	diffs := graphQLErrors(unwrapBody(baseBody), unwrapBody(targetBody))
+	if conf.Schema == nil {
+		return diffs, nil
+	}
+
+	doc, err := graphql.ParseQuery(gqlReq.Query)
+	if err != nil {
+		return diffs, errors.Wrapf(err, "reading GraphQL query from %q", fname)
+	}
+	op, err := doc.Operation(gqlReq.OperationName)
+	if err != nil {
+		return diffs, errors.Wrapf(err, "reading GraphQL query from %q", fname)
+	}
+	for _, b := range graphql.Breaks(doc, op, conf.Schema, conf.Base) {
+		diffs = append(diffs, bacom.Difference{
+			Kind:    bacom.SchemaBreakDifference,
+			Path:    ".data" + b.Path,
+			Message: b.Message,
+		})
+	}
+
+	return diffs, nil
+}
+
+// graphQLErrors returns the errors[] of the target body, ignoring the errors already present in the base body
+func graphQLErrors(base, target interface{}) []bacom.Difference {
+	var diffs []bacom.Difference
+
+	expected := map[string]bool{}
+	for _, e := range responseErrors(base) {
+		expected[errorMessage(e)] = true
+	}
+	for _, e := range responseErrors(target) {
+		msg := errorMessage(e)
+		if expected[msg] {
+			continue
+		}
+		diffs = append(diffs, bacom.Difference{
+			Kind:    bacom.GraphQLErrorDifference,
+			Path:    errorPath(e),
+			Message: msg,
+		})
+	}
+
+	return diffs
+}
+
+func responseErrors(body interface{}) []interface{} {
+	m, ok := body.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	errs, _ := m["errors"].([]interface{})
+
+	return errs
+}
+
+func errorMessage(e interface{}) string {
+	if m, ok := e.(map[string]interface{}); ok {
+		if msg, ok := m["message"].(string); ok {
+			return msg
+		}
+	}
+	b, _ := json.Marshal(e)
+
+	return string(b)
+}
+
+// errorPath converts the path of an error (i.e `["user", 0, "email"]`) to the path of the field in the
+// response (`.data.user[0].email`)
+func errorPath(e interface{}) string {
+	m, _ := e.(map[string]interface{})
+	segments, _ := m["path"].([]interface{})
+	if len(segments) == 0 {
+		return ""
+	}
+
+	path := ".data"
+	for _, segment := range segments {
+		switch segment := segment.(type) {
+		case float64:
+			path += fmt.Sprintf("[%d]", int(segment))
+		default:
+			path += fmt.Sprintf(".%v", segment)
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/yazgazan/bacom"
+)
+
+func TestGraphQLErrors(t *testing.T) {
+	base := map[string]interface{}{
+		"errors": []interface{}{
+			map[string]interface{}{"message": "rate limited"},
+		},
+	}
+	target := map[string]interface{}{
+		"data": map[string]interface{}{"user": nil},
+		"errors": []interface{}{
+			map[string]interface{}{"message": "rate limited"},
+			map[string]interface{}{"message": "not found", "path": []interface{}{"users", 1.0, "email"}},
+			"unexpected",
+		},
+	}
+
+	expected := []bacom.Difference{
+		{Kind: bacom.GraphQLErrorDifference, Path: ".data.users[1].email", Message: "not found"},
+		{Kind: bacom.GraphQLErrorDifference, Message: `"unexpected"`},
+	}
+	diffs := graphQLErrors(base, target)
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("graphQLErrors(...) = %+v, expected %+v", diffs, expected)
+	}
+
+	if diffs = graphQLErrors(nil, base); len(diffs) != 1 {
+		t.Errorf("graphQLErrors(nil, ...) = %+v, expected 1 difference", diffs)
+	}
+}
+
+func TestReadGraphQLRequest(t *testing.T) {
+	for _, test := range []struct {
+		Request  string
+		OK       bool
+		Expected graphQLRequest
+	}{
+		{
+			Request: "POST /graphql HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: 45\r\n\r\n" +
+				`{"query":"query Q { a }","operationName":"Q"}`,
+			OK:       true,
+			Expected: graphQLRequest{Query: "query Q { a }", OperationName: "Q"},
+		},
+		{
+			Request:  "GET /graphql?query=%7B+a+%7D HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			OK:       true,
+			Expected: graphQLRequest{Query: "{ a }"},
+		},
+		{
+			Request:  "POST /graphql HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/graphql\r\nContent-Length: 5\r\n\r\n{ a }",
+			OK:       true,
+			Expected: graphQLRequest{Query: "{ a }"},
+		},
+		{
+			Request: "POST /api HTTP/1.1\r\nHost: localhost\r\nContent-Length: 9\r\n\r\n[1, 2, 3]",
+		},
+	} {
+		f, err := ioutil.TempFile("", "bacom-req-*.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(test.Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, ok, err := readGraphQLRequest(f.Name())
+		if err != nil {
+			t.Errorf("readGraphQLRequest(%q): unexpected error: %s", test.Request, err)
+			continue
+		}
+		if ok != test.OK || got != test.Expected {
+			t.Errorf("readGraphQLRequest(%q) = %+v, %v, expected %+v, %v", test.Request, got, ok, test.Expected, test.OK)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/grpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// grpcClient sends gRPC calls using the default TLS configuration, see grpcTransport
+var grpcClient = &http.Client{Transport: newGRPCTransport(nil)}
+
+// grpcTransport sends gRPC calls over HTTP/2, using TLS for https targets and cleartext HTTP/2 (h2c) otherwise
+type grpcTransport struct {
+	tls *http2.Transport
+	h2c *http2.Transport
+}
+
+func newGRPCTransport(tlsConf *tls.Config) grpcTransport {
+	return grpcTransport{
+		tls: &http2.Transport{TLSClientConfig: tlsConf},
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+}
+
+func (t grpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(req)
+	}
+
+	return t.tls.RoundTrip(req)
+}
+
+func readDescriptorSet(fname string) (*grpc.Descriptors, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading descriptor set %q", fname)
+	}
+	d, err := grpc.ParseDescriptorSet(b)
+
+	return d, errors.Wrapf(err, "reading descriptor set %q", fname)
+}
+
+func isGRPCCall(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	return mediaType == grpc.CallContentType
+}
+
+// readGRPCCall reads the call stored in the body of req, returning the descriptor of its method
+func readGRPCCall(req *http.Request) (call grpc.Call, m *grpc.Method, err error) {
+	err = json.NewDecoder(req.Body).Decode(&call)
+	if err != nil {
+		return call, nil, errors.Wrap(err, "decoding gRPC call")
+	}
+	if call.Descriptors == nil {
+		return call, nil, errors.New("decoding gRPC call: missing descriptors")
+	}
+	m, err = call.Descriptors.Method(req.URL.Path)
+	if err == nil && !m.Unary() {
+		err = errors.Errorf("%s: only unary calls are supported", req.URL.Path)
+	}
+
+	return call, m, err
+}
+
+// getGRPCResponse replays the call stored in req: the request message is encoded using the stored descriptors, and
+// the response message decoded to JSON (see transcodeResponse). The call is sent using client, or grpcClient if nil.
+func getGRPCResponse(req *http.Request, client *http.Client) (*http.Response, error) {
+	call, m, err := readGRPCCall(req)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := call.Descriptors.Encode(m.Input, call.Message)
+	if err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	err = grpc.WriteMessage(body, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcReq, err := http.NewRequest(http.MethodPost, req.URL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, vv := range req.Header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Content-Type", "Content-Length", "Accept-Encoding", "User-Agent":
+			continue
+		}
+		grpcReq.Header[k] = vv
+	}
+	grpcReq.Header.Set("Content-Type", grpc.ContentType)
+	grpcReq.Header.Set("Te", "trailers")
+
+	if client == nil {
+		client = grpcClient
+	}
+	resp, err := client.Do(grpcReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return transcodeResponse(resp, call.Descriptors, m)
+}
+
+// transcodeResponse reads a gRPC response and returns a response holding the JSON-transcoded message (or an empty
+// body if the call failed). Grpc-Status and Grpc-Message are always returned as trailers, including for
+// "trailers-only" responses.
+func transcodeResponse(resp *http.Response, d *grpc.Descriptors, m *grpc.Method) (*http.Response, error) {
+	messages, err := grpc.ReadMessages(resp.Body, resp.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if len(messages) != 0 {
+		msg, err := d.Decode(m.Output, messages[0])
+		if err != nil {
+			return nil, err
+		}
+		body, err = json.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, '\n')
+	}
+
+	out := &http.Response{
+		Status:        resp.Status,
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Trailer:       resp.Trailer.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+	if out.Trailer == nil {
+		out.Trailer = http.Header{}
+	}
+	for _, k := range []string{"Grpc-Status", "Grpc-Message"} {
+		if v, ok := out.Header[k]; ok {
+			out.Trailer[k] = v
+			out.Header.Del(k)
+		}
+	}
+	out.Header.Del("Grpc-Encoding")
+	out.Header.Del("Trailer")
+	out.Header.Set("Content-Type", grpc.JSONContentType)
+	out.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return out, nil
+}
+
+// grpcDifferences reports the changes of descriptors breaking the call stored in fname (if it is a gRPC call)
+func grpcDifferences(descriptors *grpc.Descriptors, fname string) (diffs []bacom.Difference, err error) {
+	if descriptors == nil {
+		return nil, nil
+	}
+	req, err := parseRequest(nil, requestHooks{}, fname)
+	if err != nil || !isGRPCCall(req) {
+		return nil, err
+	}
+	defer handleClose(&err, req.Body)
+
+	call, _, err := readGRPCCall(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %q", fname)
+	}
+	for _, msg := range grpc.Breaks(call.Descriptors, descriptors) {
+		diffs = append(diffs, bacom.Difference{
+			Kind:    bacom.SchemaBreakDifference,
+			Message: msg,
+		})
+	}
+
+	return diffs, nil
+}
+
+func importGRPCCmd(args []string) {
+	c, err := parseImportGRPCFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	err = os.MkdirAll(c.Dir, 0750)
+	if err != nil && !os.IsExist(err) {
+		log.Fatal(err)
+	}
+
+	targetURL, err := url.Parse(c.Target)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// h2c serves both HTTP/1.1 and cleartext HTTP/2
+	handler := grpcProxyHandler(targetURL, c.Dir, c.Descriptors, c.Verbose, c.Filters)
+	srv := &http.Server{
+		Addr:    c.Listen,
+		Handler: h2c.NewHandler(handler, &http2.Server{}),
+	}
+
+	log.Printf("listening on %s", c.Listen)
+	err = srv.ListenAndServe()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// grpcProxyHandler forwards gRPC calls to target and saves the unary calls as request/response pairs
+func grpcProxyHandler(target *url.URL, outDir string, d *grpc.Descriptors, verbose bool, filters reqFilters) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		reqBody, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusInternalServerError)
+			log.Printf("failed to read request body: %v", err)
+			return
+		}
+
+		u := *target // copies the URL
+		u.Path = path.Join(u.Path, r.URL.Path)
+		req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(reqBody))
+		if err != nil {
+			http.Error(w, "failed to create proxied request", http.StatusInternalServerError)
+			log.Printf("failed to create proxied request: %v", err)
+			return
+		}
+		for k, vv := range r.Header {
+			if k == "Host" || k == "Connection" {
+				continue
+			}
+			req.Header[k] = vv
+		}
+
+		resp, err := grpcClient.Do(req)
+		if err != nil {
+			http.Error(w, "failed to proxy request", http.StatusBadGateway)
+			log.Printf("failed to proxy request: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		respBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			http.Error(w, "failed to read response body", http.StatusInternalServerError)
+			log.Printf("failed to read response body: %v", err)
+			return
+		}
+
+		for k, vv := range resp.Header {
+			w.Header()[k] = vv
+		}
+		for k := range resp.Trailer {
+			w.Header().Add("Trailer", k)
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, err = w.Write(respBody)
+		if err != nil {
+			log.Printf("failed to write response: %v", err)
+			return
+		}
+		for k, vv := range resp.Trailer {
+			w.Header()[k] = vv
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+		if err := filters.Match(req); err != nil {
+			if verbose {
+				log.Printf("skipping %s: %v", u.String(), err)
+			}
+			return
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		err = saveGRPCCall(outDir, verbose, req, reqBody, resp, d)
+		if err != nil {
+			log.Printf("failed to save call %q: %v", r.URL.Path, err)
+			return
+		}
+
+		log.Printf("saved call %q", r.URL.Path)
+	}
+}
+
+// saveGRPCCall stores a unary call as a request/response pair: the request holds the JSON-transcoded message
+// and the descriptors of the method (see grpc.Call), the response the JSON-transcoded response message.
+func saveGRPCCall(outDir string, verbose bool, req *http.Request, reqBody []byte, resp *http.Response, d *grpc.Descriptors) error {
+	m, err := d.Method(req.URL.Path)
+	if err != nil {
+		return err
+	}
+	if !m.Unary() {
+		return errors.New("only unary calls are supported")
+	}
+	sub, err := d.Subset(req.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	messages, err := grpc.ReadMessages(bytes.NewReader(reqBody), req.Header.Get("Grpc-Encoding"))
+	if err != nil {
+		return err
+	}
+	if len(messages) != 1 {
+		return errors.Errorf("expected a single request message, got %d", len(messages))
+	}
+	msg, err := d.Decode(m.Input, messages[0])
+	if err != nil {
+		return err
+	}
+	callBody, err := json.MarshalIndent(grpc.Call{Message: msg, Descriptors: sub}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	call, err := http.NewRequest(http.MethodPost, req.URL.String(), bytes.NewReader(callBody))
+	if err != nil {
+		return err
+	}
+	for k, vv := range req.Header {
+		switch k {
+		case "Content-Type", "Content-Length", "Te", "Grpc-Encoding", "Grpc-Accept-Encoding", "User-Agent":
+			continue
+		}
+		call.Header[k] = vv
+	}
+	call.Header.Set("Content-Type", grpc.CallContentType)
+
+	transcoded, err := transcodeResponse(resp, sub, m)
+	if err != nil {
+		return err
+	}
+
+	name := "grpc-" + normalize(strings.Replace(req.URL.Path, ".", "-", -1))
+	reqFname, err := importReq(verbose, outDir, name, call)
+	if err != nil {
+		return err
+	}
+
+	return importResp(verbose, reqFname, outDir, name, transcoded)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yazgazan/bacom/grpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func testDescriptors() *grpc.Descriptors {
+	return &grpc.Descriptors{
+		Services: map[string]*grpc.Service{
+			"users.Users": {Methods: map[string]*grpc.Method{
+				"GetUser": {Input: "users.GetUserRequest", Output: "users.User"},
+			}},
+		},
+		Messages: map[string]*grpc.Message{
+			"users.GetUserRequest": {Fields: []*grpc.Field{{Name: "id", Number: 1, Type: "int64"}}},
+			"users.User":           {Fields: []*grpc.Field{{Name: "name", Number: 1, Type: "string"}}},
+		},
+	}
+}
+
+func TestTranscodeResponse(t *testing.T) {
+	d := testDescriptors()
+	m := d.Services["users.Users"].Methods["GetUser"]
+
+	msg, err := d.Encode(m.Output, map[string]interface{}{"name": "foo"})
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %s", err)
+	}
+	body := &bytes.Buffer{}
+	err = grpc.WriteMessage(body, msg)
+	if err != nil {
+		t.Fatalf("WriteMessage: unexpected error: %s", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		resp     *http.Response
+		body     string
+		trailers http.Header
+	}{
+		{
+			name: "message",
+			resp: &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": {grpc.ContentType}, "Trailer": {"Grpc-Status"}},
+				Trailer:    http.Header{"Grpc-Status": {"0"}},
+				Body:       ioutil.NopCloser(body),
+			},
+			body:     `{"name":"foo"}` + "\n",
+			trailers: http.Header{"Grpc-Status": {"0"}},
+		},
+		{
+			name: "trailers-only",
+			resp: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": {grpc.ContentType},
+					"Grpc-Status":  {"5"},
+					"Grpc-Message": {"not found"},
+				},
+				Body: ioutil.NopCloser(strings.NewReader("")),
+			},
+			trailers: http.Header{"Grpc-Status": {"5"}, "Grpc-Message": {"not found"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := transcodeResponse(test.resp, d, m)
+			if err != nil {
+				t.Fatalf("transcodeResponse: unexpected error: %s", err)
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading body: unexpected error: %s", err)
+			}
+			if string(b) != test.body {
+				t.Errorf("body = %q, expected %q", b, test.body)
+			}
+			if !reflect.DeepEqual(resp.Trailer, test.trailers) {
+				t.Errorf("trailers = %v, expected %v", resp.Trailer, test.trailers)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != grpc.JSONContentType {
+				t.Errorf("Content-Type = %q, expected %q", ct, grpc.JSONContentType)
+			}
+			if resp.Header.Get("Grpc-Status") != "" || resp.Header.Get("Trailer") != "" {
+				t.Errorf("unexpected headers %v", resp.Header)
+			}
+		})
+	}
+}
+
+func TestReadGRPCCall(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		body string
+		ok   bool
+	}{
+		{"/users.Users/GetUser", `{"message": {"id": "1"}, "descriptors": %s}`, true},
+		{"/users.Users/DeleteUser", `{"message": {}, "descriptors": %s}`, false},
+		{"/users.Users/GetUser", `{"message": {}}`, false},
+	} {
+		body := test.body
+		if strings.Contains(body, "%s") {
+			b, err := json.Marshal(testDescriptors())
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = strings.Replace(body, "%s", string(b), 1)
+		}
+		req, err := http.NewRequest(http.MethodPost, "http://localhost"+test.path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", grpc.CallContentType+"; charset=utf-8")
+		if !isGRPCCall(req) {
+			t.Errorf("isGRPCCall(%q) = false, expected true", test.path)
+		}
+
+		_, _, err = readGRPCCall(req)
+		if test.ok && err != nil {
+			t.Errorf("readGRPCCall(%q, %s): unexpected error: %s", test.path, test.body, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("readGRPCCall(%q, %s): expected error, got nil", test.path, test.body)
+		}
+	}
+}
+
+func TestGRPCTransport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	h2cSrv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cSrv.Close()
+	tlsSrv := httptest.NewUnstartedServer(handler)
+	tlsSrv.TLS = &tls.Config{NextProtos: []string{http2.NextProtoTLS}}
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	for _, test := range []struct {
+		name      string
+		url       string
+		transport http.RoundTripper
+	}{
+		{name: "h2c", url: h2cSrv.URL, transport: newGRPCTransport(nil)},
+		{name: "tls", url: tlsSrv.URL, transport: newGRPCTransport(&tls.Config{InsecureSkipVerify: true})},
+	} {
+		client := &http.Client{Transport: test.transport}
+		resp, err := client.Post(test.url, grpc.ContentType, strings.NewReader(""))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil || string(b) != "HTTP/2.0" {
+			t.Errorf("%s: protocol = %q, %v, expected HTTP/2.0", test.name, b, err)
+		}
+	}
+}
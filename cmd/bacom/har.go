@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/yazgazan/bacom"
 	"github.com/yazgazan/bacom/har"
@@ -145,7 +146,7 @@ func importResp(verbose bool, reqFname, outDir, name string, resp *http.Response
 	if verbose {
 		fmt.Fprintf(os.Stderr, "imported %q\n", fname)
 	}
-	return resp.Write(outF)
+	return bacom.WriteResponse(outF, resp)
 }
 
 func handleClose(err *error, closer io.Closer) {
@@ -154,3 +155,64 @@ func handleClose(err *error, closer io.Closer) {
 		*err = errClose
 	}
 }
+
+func exportHarCmd(args []string) {
+	c, err := parseExportFlags(harSubCmdName, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	pairs, err := collectExportPairs(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = writeExport(c.Out, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(newHAR(pairs))
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func newHAR(pairs []exportPair) har.HAR {
+	h := har.HAR{
+		Log: har.Log{
+			Version: har.Version,
+			Creator: har.Creator{Name: "bacom", Version: Version},
+			Entries: []har.Entry{},
+		},
+	}
+
+	for _, pair := range pairs {
+		entry := har.Entry{
+			StartedDateTime: pair.modTime.Format(time.RFC3339Nano),
+			Request:         har.FromHTTPRequest(pair.req, pair.reqBody),
+			Timings:         har.UnknownTimings,
+		}
+		if pair.resp != nil {
+			entry.Response = har.FromHTTPResponse(pair.resp, pair.respBody)
+			if date, err := http.ParseTime(pair.resp.Header.Get("Date")); err == nil {
+				entry.StartedDateTime = date.Format(time.RFC3339Nano)
+			}
+		} else {
+			// HAR requires a response, status 0 is used by browsers for requests without responses
+			entry.Response = har.Response{
+				HTTPVersion: pair.req.Proto,
+				HeadersSize: -1,
+				BodySize:    -1,
+				Headers:     []har.KV{},
+				Cookies:     []har.Cookie{},
+			}
+		}
+		h.Log.Entries = append(h.Log.Entries, entry)
+	}
+
+	return h
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+// defaultCommandTimeout bounds the pre-process commands, hooks and validators when -command-timeout isn't set
+const defaultCommandTimeout = time.Minute
+
+// requestHooks are the commands the requests read from request files are passed through (see parseRequest)
+type requestHooks struct {
+	// PreProcess receives the raw request on its standard input, and writes the modified request on its standard output
+	PreProcess string
+	// Hook receives the request as JSON, see hookDocument
+	Hook    string
+	Timeout time.Duration
+
+	// Version and Target are passed to Hook
+	Version string
+	Target  string
+}
+
+// requestHooks returns the hooks of the requests sent to the base or target (named by target), for the request
+// files of version
+func (c targetConf) requestHooks(timeout duration, version, target string) requestHooks {
+	return requestHooks{
+		PreProcess: c.PreProcess,
+		Hook:       c.RequestHook,
+		Timeout:    time.Duration(timeout),
+		Version:    version,
+		Target:     target,
+	}
+}
+
+// hookDocument is written to the standard input of the request and response hooks. Request hooks receive the
+// request, while response hooks receive the response along with the method and path of the request.
+// The hooks write the document back to their standard output, with the request (or response) modified.
+// An empty output leaves it unchanged.
+type hookDocument struct {
+	Version  string        `json:"version"`
+	File     string        `json:"file"`
+	Target   string        `json:"target,omitempty"`
+	Request  *hookRequest  `json:"request,omitempty"`
+	Response *hookResponse `json:"response,omitempty"`
+}
+
+// hookRequest is the JSON representation of a request. Bodies that aren't valid UTF-8 are base64 encoded, with
+// BodyEncoding set to base64.
+type hookRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Host         string      `json:"host,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// hookResponse is the JSON representation of a response, see hookRequest
+type hookResponse struct {
+	Status       int         `json:"status"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+func newHookRequest(req *http.Request) (*hookRequest, error) {
+	b, err := readRequestBody(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading request body")
+	}
+	r := &hookRequest{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Host:    req.Host,
+		Headers: req.Header,
+	}
+	r.Body, r.BodyEncoding = encodeHookBody(b)
+
+	return r, nil
+}
+
+func (r hookRequest) toRequest() (*http.Request, error) {
+	body, err := decodeHookBody(r.Body, r.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(r.Method, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.RequestURI = req.URL.RequestURI()
+	req.Host = r.Host
+	if req.Host == "" {
+		req.Host = req.URL.Host
+	}
+	req.Header = r.Headers
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if req.Header.Get("Content-Length") != "" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	return req, nil
+}
+
+func encodeHookBody(b []byte) (body, encoding string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func decodeHookBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(body)
+		return b, errors.Wrap(err, "decoding body")
+	}
+
+	return nil, errors.Errorf("unknown body encoding %q, expected base64", encoding)
+}
+
+// runRequestHook passes req, read from the request file fname, through hooks.Hook
+func runRequestHook(hooks requestHooks, fname string, req *http.Request) (*http.Request, error) {
+	in, err := newHookRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out hookDocument
+	ok, err := runHook(hooks.Hook, hooks.Timeout, hookDocument{
+		Version: hooks.Version,
+		File:    fname,
+		Target:  hooks.Target,
+		Request: in,
+	}, &out)
+	if err != nil || !ok {
+		return req, err
+	}
+	if out.Request == nil {
+		return nil, errors.Errorf("hook %q: missing request in output", hooks.Hook)
+	}
+	req, err = out.Request.toRequest()
+
+	return req, errors.Wrapf(err, "hook %q", hooks.Hook)
+}
+
+// postProcessResponses passes the responses of job through the response hooks of the target and base
+func postProcessResponses(conf testConf, job *testJob, target, base *http.Response) error {
+	for _, side := range []struct {
+		name string
+		hook string
+		resp *http.Response
+	}{
+		{name: "target", hook: conf.Target.ResponseHook, resp: target},
+		{name: "base", hook: conf.Base.ResponseHook, resp: base},
+	} {
+		if side.hook == "" || side.resp == nil {
+			continue
+		}
+		err := runResponseHook(side.hook, time.Duration(conf.CommandTimeout), hookDocument{
+			Version: job.version,
+			File:    job.fname,
+			Target:  side.name,
+			Request: &hookRequest{Method: job.method, URL: job.path},
+		}, side.resp)
+		if err != nil {
+			return errors.Wrapf(err, "post-processing %s response for %q", side.name, job.fname)
+		}
+	}
+
+	return nil
+}
+
+// runResponseHook passes resp through the hook command, doc holding the metadata passed along with the response.
+// The response is decompressed (dropping its Content-Encoding) beforehand, so that hooks can edit its body.
+func runResponseHook(command string, timeout time.Duration, doc hookDocument, resp *http.Response) error {
+	err := bacom.DecompressBody(resp)
+	if err != nil {
+		return errors.Wrap(err, "decompressing response")
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading response body")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	doc.Response = &hookResponse{
+		Status:  resp.StatusCode,
+		Headers: resp.Header,
+	}
+	doc.Response.Body, doc.Response.BodyEncoding = encodeHookBody(b)
+
+	var out hookDocument
+	ok, err := runHook(command, timeout, doc, &out)
+	if err != nil || !ok {
+		return err
+	}
+	if out.Response == nil || out.Response.Status == 0 {
+		return errors.Errorf("hook %q: missing response in output", command)
+	}
+
+	b, err = decodeHookBody(out.Response.Body, out.Response.BodyEncoding)
+	if err != nil {
+		return errors.Wrapf(err, "hook %q", command)
+	}
+	resp.StatusCode = out.Response.Status
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	resp.Header = out.Response.Headers
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+
+	return nil
+}
+
+// runHook runs the hook command with in as its input, decoding its output into out.
+// It returns false when the hook's output is empty.
+func runHook(command string, timeout time.Duration, in, out interface{}) (bool, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return false, errors.Wrapf(err, "hook %q", command)
+	}
+	b, err = bacom.RunCommand(command, timeout, b)
+	if err != nil {
+		return false, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return false, nil
+	}
+
+	err = json.Unmarshal(b, out)
+
+	return true, errors.Wrapf(err, "hook %q: decoding output", command)
+}
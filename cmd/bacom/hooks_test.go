@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRequestHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestParseRequestHooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"users_req.txt": "POST /users HTTP/1.1\r\nHost: example.com\r\nX-Version: 1\r\n" +
+			"Content-Type: application/json\r\nContent-Length: 15\r\n\r\n" + `{"name":"Jane"}`,
+	})
+	fname := filepath.Join(dir, "users_req.txt")
+
+	for _, test := range []struct {
+		name    string
+		hooks   requestHooks
+		url     string
+		version string
+		body    string
+		err     string
+	}{
+		{name: "none", url: "/users", version: "1", body: `{"name":"Jane"}`},
+		{
+			name:    "preprocess",
+			hooks:   requestHooks{PreProcess: "sed 's/X-Version: 1/X-Version: 2/'"},
+			url:     "/users",
+			version: "2",
+			body:    `{"name":"Jane"}`,
+		},
+		{
+			name:    "hook",
+			hooks:   requestHooks{Hook: `sed 's|"url":"/users"|"url":"/v2/users?hooked=1"|; s/Jane/Johnny/'`},
+			url:     "/v2/users?hooked=1",
+			version: "1",
+			body:    `{"name":"Johnny"}`,
+		},
+		{
+			name: "preprocess and hook",
+			hooks: requestHooks{
+				PreProcess: "sed 's/X-Version: 1/X-Version: 2/'",
+				Hook:       `sed 's/"X-Version":\["2"\]/"X-Version":["3"]/'`,
+			},
+			url:     "/users",
+			version: "3",
+			body:    `{"name":"Jane"}`,
+		},
+		{
+			name:    "empty output",
+			hooks:   requestHooks{Hook: "cat > /dev/null"},
+			url:     "/users",
+			version: "1",
+			body:    `{"name":"Jane"}`,
+		},
+		{name: "invalid output", hooks: requestHooks{Hook: "echo nope"}, err: "decoding output"},
+		{name: "missing request", hooks: requestHooks{Hook: "echo {}"}, err: "missing request"},
+		{name: "timeout", hooks: requestHooks{Hook: "sleep 5", Timeout: 100 * time.Millisecond}, err: "timed out"},
+	} {
+		req, err := parseRequest(nil, test.hooks, fname)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+
+		if req.Method != http.MethodPost || req.URL.String() != test.url || req.Host != "example.com" {
+			t.Errorf("%s: request = %s %s (host %q), expected POST %s (host example.com)", test.name, req.Method, req.URL, req.Host, test.url)
+		}
+		if got := req.Header.Get("X-Version"); got != test.version {
+			t.Errorf("%s: X-Version = %q, expected %q", test.name, got, test.version)
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil || string(b) != test.body {
+			t.Errorf("%s: body = %q, %v, expected %q", test.name, b, err, test.body)
+		}
+		if req.ContentLength != int64(len(test.body)) || req.Header.Get("Content-Length") != strconv.Itoa(len(test.body)) {
+			t.Errorf("%s: Content-Length = %d (header %q), expected %d", test.name, req.ContentLength, req.Header.Get("Content-Length"), len(test.body))
+		}
+	}
+}
+
+func TestRunResponseHook(t *testing.T) {
+	newResp := func(body []byte) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusInternalServerError,
+			Status:        "500 Internal Server Error",
+			Header:        http.Header{"Content-Type": {"application/json"}, "Content-Length": {strconv.Itoa(len(body))}},
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+	}
+	doc := hookDocument{Version: "v1.0.0", File: "users_req.txt", Target: "target", Request: &hookRequest{Method: "GET", URL: "/users"}}
+
+	resp := newResp([]byte(`{"token":"abc"}`))
+	err := runResponseHook(
+		`in=$(cat)
+		echo "$in" | grep -q '"version":"v1.0.0","file":"users_req.txt","target":"target","request":{"method":"GET","url":"/users"}' &&
+		echo "$in" | sed 's/"status":500/"status":200/; s/abc/***/'`,
+		time.Second, doc, resp,
+	)
+	if err != nil {
+		t.Fatalf("runResponseHook: unexpected error: %s", err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(b) != `{"token":"***"}` {
+		t.Errorf("body = %q, %v, expected the modified body", b, err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Status != "200 OK" {
+		t.Errorf("status = %d (%q), expected 200", resp.StatusCode, resp.Status)
+	}
+	if resp.ContentLength != 15 || resp.Header.Get("Content-Length") != "15" || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v (Content-Length %d), expected the original headers", resp.Header, resp.ContentLength)
+	}
+
+	// bodies that aren't valid UTF-8 are base64 encoded
+	binary := []byte{0x1f, 0x8b, 0xff, 0x00}
+	resp = newResp(binary)
+	err = runResponseHook(`in=$(cat); echo "$in" | grep -q '"body_encoding":"base64"' && echo "$in"`, time.Second, doc, resp)
+	if err != nil {
+		t.Fatalf("runResponseHook(binary): unexpected error: %s", err)
+	}
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(b, binary) {
+		t.Errorf("body = %v, %v, expected %v", b, err, binary)
+	}
+
+	resp = newResp([]byte(`{"token":"abc"}`))
+	err = runResponseHook(`echo '{"response": {}}'`, time.Second, doc, resp)
+	if err == nil || !strings.Contains(err.Error(), "missing response") {
+		t.Errorf("runResponseHook(no status): expected an error, got %v", err)
+	}
+}
+
+func TestResponseHookDecompressed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"token": "abc", "name": "Jane"}`))
+		_ = gz.Close()
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "TestResponseHookDecompressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "v1.0.0")
+	err = os.Mkdir(versionDir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"token": "***", "name": "Jane"}`
+	writeTestFiles(t, versionDir, map[string]string{
+		"user_req.txt":  "GET /user HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip, deflate, br\r\n\r\n",
+		"user_resp.txt": "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n" + body,
+	})
+
+	// the requests recorded from browsers ask for compressed responses, which aren't decompressed by the client.
+	// The hook fails if the body or headers it receives aren't decompressed.
+	hook := `in=$(cat)
+		echo "$in" | grep -q '"token\\": \\"abc\\"' || exit 1
+		echo "$in" | grep -q 'Content-Encoding' && exit 1
+		echo "$in" | sed 's/abc/***/'`
+	conf, err := parseTestFlags([]string{
+		"-conf", filepath.Join(dir, "bacom.json"),
+		"-target-host", strings.TrimPrefix(srv.URL, "http://"),
+		"-target-response-hook", hook,
+	})
+	if err != nil {
+		t.Fatalf("parseTestFlags: unexpected error: %s", err)
+	}
+	suites, err := collectTests(conf, []string{versionDir})
+	if err != nil {
+		t.Fatalf("collectTests: unexpected error: %s", err)
+	}
+	runJobs(conf, suites[0].runs[0])
+
+	job := suites[0].tests[0]
+	if job.err != nil {
+		t.Fatalf("unexpected error: %s", job.err)
+	}
+	if len(job.differences) != 0 {
+		t.Errorf("unexpected differences: %+v", job.differences)
+	}
+}
@@ -23,6 +23,12 @@ func importCmd(args []string) {
 		importCurlCmd(args)
 	case proxySubCmdName:
 		importProxyCmd(args)
+	case grpcSubCmdName:
+		importGRPCCmd(args)
+	case postmanSubCmdName:
+		importPostmanCmd(args)
+	case insomniaSubCmdName:
+		importInsomniaCmd(args)
 	}
 }
 
@@ -38,7 +44,7 @@ func getImportSubCommand(args []string) (cmd string, cmdArgs []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown import sub-command %q\n", cmd)
 		os.Exit(2)
-	case curlSubCmdName, harSubCmdName, proxySubCmdName:
+	case curlSubCmdName, harSubCmdName, proxySubCmdName, grpcSubCmdName, postmanSubCmdName, insomniaSubCmdName:
 		return strings.ToLower(cmd), cmdArgs
 	}
 
@@ -52,8 +58,12 @@ func printImportUsage() {
 		`Usage: %s import [SUB-COMMAND] [OPTIONS]
 
 SUB-COMMANDS:
-    har    import requests and responses from har files
-    curl   save a request/response pair by providing curl-like arguments
+    har       import requests and responses from har files
+    curl      save a request/response pair by providing curl-like arguments
+    postman   import requests and example responses from postman v2.1 collections
+    insomnia  import requests from insomnia v4 exports (json or yaml)
+    proxy     record requests and responses through a proxy
+    grpc      record unary gRPC calls through a proxy
 
 Note:
     "%s import SUB-COMMAND -h" to get an overview of each sub-command's flags
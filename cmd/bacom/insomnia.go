@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+func importInsomniaCmd(args []string) {
+	c, err := parseImportInsomniaFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	for _, fname := range c.Files {
+		err := importFromInsomniaFile(fname, c.Dir, c.Verbose, c.Filters, c.Env, c.Vars)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// insomniaExport is an Insomnia v4 export, in either JSON or YAML
+type insomniaExport struct {
+	Type      string             `json:"_type" yaml:"_type"`
+	Format    int                `json:"__export_format" yaml:"__export_format"`
+	Resources []insomniaResource `json:"resources" yaml:"resources"`
+}
+
+// insomniaResource holds the fields used by bacom for all the resource types
+// (workspace, request_group, request and environment)
+type insomniaResource struct {
+	ID       string `json:"_id" yaml:"_id"`
+	Type     string `json:"_type" yaml:"_type"`
+	ParentID string `json:"parentId" yaml:"parentId"`
+	Name     string `json:"name" yaml:"name"`
+
+	// request
+	Method         string                 `json:"method" yaml:"method"`
+	URL            string                 `json:"url" yaml:"url"`
+	Body           insomniaBody           `json:"body" yaml:"body"`
+	Parameters     []insomniaParam        `json:"parameters" yaml:"parameters"`
+	Headers        []insomniaParam        `json:"headers" yaml:"headers"`
+	Authentication map[string]interface{} `json:"authentication" yaml:"authentication"`
+
+	// request_group
+	Environment map[string]interface{} `json:"environment" yaml:"environment"`
+
+	// environment
+	Data map[string]interface{} `json:"data" yaml:"data"`
+}
+
+type insomniaBody struct {
+	MimeType string          `json:"mimeType" yaml:"mimeType"`
+	Text     string          `json:"text" yaml:"text"`
+	Params   []insomniaParam `json:"params" yaml:"params"`
+}
+
+type insomniaParam struct {
+	Name     string `json:"name" yaml:"name"`
+	Value    string `json:"value" yaml:"value"`
+	Type     string `json:"type" yaml:"type"`
+	FileName string `json:"fileName" yaml:"fileName"`
+	Disabled bool   `json:"disabled" yaml:"disabled"`
+}
+
+func readInsomniaExport(fname string) (export insomniaExport, err error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return export, err
+	}
+
+	switch strings.ToLower(filepath.Ext(fname)) {
+	default:
+		err = json.Unmarshal(b, &export)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &export)
+	}
+	if err != nil {
+		return export, errors.Wrapf(err, "decoding insomnia export %q", fname)
+	}
+	if export.Format != 4 {
+		return export, errors.Errorf("unsupported insomnia export format %d in %q, expected 4", export.Format, fname)
+	}
+
+	return export, nil
+}
+
+func importFromInsomniaFile(
+	fname, outDir string,
+	verbose bool,
+	filters reqFilters,
+	envName string,
+	vars map[string]string,
+) error {
+	export, err := readInsomniaExport(fname)
+	if err != nil {
+		return err
+	}
+
+	resources := make(map[string]insomniaResource, len(export.Resources))
+	for _, r := range export.Resources {
+		resources[r.ID] = r
+	}
+
+	for _, r := range export.Resources {
+		if r.Type != "request" {
+			continue
+		}
+		reqVars, err := insomniaRequestVars(resources, export.Resources, r, envName)
+		if err != nil {
+			return err
+		}
+		for k, v := range vars {
+			reqVars[k] = v
+			reqVars["_."+k] = v
+		}
+
+		req, err := newInsomniaRequest(r, reqVars)
+		if err != nil {
+			return errors.Wrapf(err, "importing insomnia request %q", r.Name)
+		}
+
+		err = filters.Match(req)
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "excluding request: %s\n", err)
+			}
+			continue
+		}
+
+		_, err = importReq(verbose, outDir, insomniaTestName(r.Name, req), req)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insomniaRequestVars returns the variables available to a request. In increasing order of precedence:
+// the workspace's base environment, the selected sub-environment and the request groups' environments
+// (from the outermost to the innermost).
+func insomniaRequestVars(
+	resources map[string]insomniaResource,
+	all []insomniaResource,
+	req insomniaResource,
+	envName string,
+) (templateVars, error) {
+	var groups []insomniaResource
+	var workspaceID string
+
+	for parentID := req.ParentID; parentID != ""; {
+		parent, ok := resources[parentID]
+		if !ok {
+			break
+		}
+		if parent.Type == "workspace" {
+			workspaceID = parent.ID
+			break
+		}
+		if parent.Type == "request_group" {
+			groups = append([]insomniaResource{parent}, groups...)
+		}
+		parentID = parent.ParentID
+	}
+
+	vars := templateVars{}
+	var base insomniaResource
+	for _, r := range all {
+		if r.Type == "environment" && r.ParentID == workspaceID {
+			base = r
+			vars.set("", r.Data)
+			break
+		}
+	}
+
+	if envName != "" {
+		var found bool
+		for _, r := range all {
+			if r.Type == "environment" && r.ParentID == base.ID && r.Name == envName {
+				found = true
+				vars.set("", r.Data)
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("insomnia environment %q not found", envName)
+		}
+	}
+
+	for _, group := range groups {
+		vars.set("", group.Environment)
+	}
+
+	return vars, nil
+}
+
+// set flattens the environment data into vars. Nested values are available using dotted names, and
+// every variable is also available with the "_." prefix used by recent Insomnia versions.
+func (vars templateVars) set(prefix string, data map[string]interface{}) {
+	for k, v := range data {
+		name := prefix + k
+
+		switch v := v.(type) {
+		default:
+			s := fmt.Sprint(v)
+			if b, err := json.Marshal(v); err == nil {
+				s = string(b)
+			}
+			vars[name] = s
+			vars["_."+name] = s
+		case nil:
+			vars[name] = ""
+			vars["_."+name] = ""
+		case string:
+			vars[name] = v
+			vars["_."+name] = v
+		case map[string]interface{}:
+			vars.set(name+".", v)
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(v))
+			for mk, mv := range v {
+				m[fmt.Sprint(mk)] = mv
+			}
+			vars.set(name+".", m)
+		}
+	}
+}
+
+func insomniaTestName(name string, req *http.Request) string {
+	var out []byte
+
+	for _, c := range []byte(strings.ToLower(name)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_', c == '.':
+			out = append(out, c)
+		case len(out) != 0 && out[len(out)-1] != '-':
+			out = append(out, '-')
+		}
+	}
+
+	name = strings.TrimRight(string(out), "-")
+	if name == "" {
+		return strings.ToLower(req.Method) + "-" + normalize(req.URL.Path)
+	}
+
+	return name
+}
+
+func newInsomniaRequest(r insomniaResource, vars templateVars) (*http.Request, error) {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	body, contentType, err := insomniaRequestBody(r.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, vars.resolve(r.URL), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		req.Body = nil
+	}
+
+	q := req.URL.Query()
+	for _, p := range r.Parameters {
+		if !p.Disabled {
+			q.Add(vars.resolve(p.Name), vars.resolve(p.Value))
+		}
+	}
+	if len(r.Parameters) != 0 {
+		req.URL.RawQuery = q.Encode()
+	}
+
+	for _, h := range r.Headers {
+		if h.Disabled || h.Name == "" {
+			continue
+		}
+		req.Header.Add(vars.resolve(h.Name), vars.resolve(h.Value))
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	applyInsomniaAuth(req, r.Authentication, vars)
+
+	return req, nil
+}
+
+func insomniaRequestBody(body insomniaBody, vars templateVars) (b []byte, contentType string, err error) {
+	switch body.MimeType {
+	default:
+		return []byte(vars.resolve(body.Text)), body.MimeType, nil
+	case "application/graphql":
+		// graphql bodies are stored as a json document holding the query and variables
+		return []byte(vars.resolve(body.Text)), "application/json", nil
+	case "application/x-www-form-urlencoded":
+		values := url.Values{}
+		for _, p := range body.Params {
+			if !p.Disabled {
+				values.Add(vars.resolve(p.Name), vars.resolve(p.Value))
+			}
+		}
+		return []byte(values.Encode()), body.MimeType, nil
+	case "multipart/form-data":
+		return insomniaFormData(body, vars)
+	}
+}
+
+func insomniaFormData(body insomniaBody, vars templateVars) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	for _, p := range body.Params {
+		if p.Disabled {
+			continue
+		}
+		key := vars.resolve(p.Name)
+		if p.Type != "file" {
+			err := w.WriteField(key, vars.resolve(p.Value))
+			if err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		err := writePostmanFile(w, key, p.FileName)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err := w.Close()
+
+	return buf.Bytes(), w.FormDataContentType(), err
+}
+
+func applyInsomniaAuth(req *http.Request, auth map[string]interface{}, vars templateVars) {
+	param := func(key string) string {
+		s, _ := auth[key].(string)
+		return vars.resolve(s)
+	}
+	if disabled, _ := auth["disabled"].(bool); disabled {
+		return
+	}
+
+	switch param("type") {
+	case "bearer":
+		prefix := param("prefix")
+		if prefix == "" {
+			prefix = "Bearer"
+		}
+		req.Header.Set("Authorization", prefix+" "+param("token"))
+	case "basic":
+		req.SetBasicAuth(param("username"), param("password"))
+	case "apikey":
+		if param("addTo") == "queryParams" {
+			q := req.URL.Query()
+			q.Set(param("key"), param("value"))
+			req.URL.RawQuery = q.Encode()
+			return
+		}
+		req.Header.Set(param("key"), param("value"))
+	}
+}
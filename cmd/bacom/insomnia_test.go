@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInsomniaRequestVars(t *testing.T) {
+	all := []insomniaResource{
+		{ID: "wrk_1", Type: "workspace"},
+		{ID: "env_base", Type: "environment", ParentID: "wrk_1", Data: map[string]interface{}{
+			"host": "localhost:8080",
+			"api":  map[string]interface{}{"version": "v1"},
+			"name": "base",
+		}},
+		{ID: "env_staging", Type: "environment", ParentID: "env_base", Name: "staging", Data: map[string]interface{}{
+			"host": "staging:8080",
+		}},
+		{ID: "fld_1", Type: "request_group", ParentID: "wrk_1", Environment: map[string]interface{}{
+			"name": "outer",
+		}},
+		{ID: "fld_2", Type: "request_group", ParentID: "fld_1", Environment: map[string]interface{}{
+			"name": "inner",
+		}},
+		{ID: "req_1", Type: "request", ParentID: "fld_2", URL: "http://{{ _.host }}/{{ api.version }}/{{name}}"},
+	}
+	resources := map[string]insomniaResource{}
+	for _, r := range all {
+		resources[r.ID] = r
+	}
+
+	for _, test := range []struct {
+		Env      string
+		Expected string
+	}{
+		{"", "http://localhost:8080/v1/inner"},
+		{"staging", "http://staging:8080/v1/inner"},
+	} {
+		vars, err := insomniaRequestVars(resources, all, resources["req_1"], test.Env)
+		if err != nil {
+			t.Errorf("insomniaRequestVars(%q): unexpected error: %s", test.Env, err)
+			continue
+		}
+		got := vars.resolve(resources["req_1"].URL)
+		if got != test.Expected {
+			t.Errorf("insomniaRequestVars(%q): resolved %q, expected %q", test.Env, got, test.Expected)
+		}
+	}
+
+	_, err := insomniaRequestVars(resources, all, resources["req_1"], "unknown")
+	if err == nil {
+		t.Error("insomniaRequestVars(\"unknown\"): expected error, got nil")
+	}
+}
+
+func TestInsomniaTestName(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Expected string
+	}{
+		{"Get users", "get-users"},
+		{"  Create user (v2) ", "create-user-v2"},
+		{"list_items.json", "list_items.json"},
+		{"", "get-api-users"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/api/users", nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest: unexpected error: %s", err)
+		}
+		got := insomniaTestName(test.Name, req)
+		if got != test.Expected {
+			t.Errorf("insomniaTestName(%q) = %q, expected %q", test.Name, got, test.Expected)
+		}
+	}
+}
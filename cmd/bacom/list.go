@@ -41,7 +41,7 @@ func listTests(dirname string, conf listConf) error {
 	}
 
 	for _, fname := range reqFiles {
-		req, err := parseRequest("", fname)
+		req, err := parseRequest(nil, requestHooks{}, fname)
 		if err != nil {
 			return err
 		}
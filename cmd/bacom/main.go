@@ -27,6 +27,12 @@ func main() {
 		mvCmd(args)
 	case cpCmdName:
 		cpCmd(args)
+	case schemaCmdName:
+		schemaCmd(args)
+	case exportCmdName:
+		exportCmd(args)
+	case enumsCmdName:
+		enumsCmd(args)
 	case versionCmdName:
 		versionCmd()
 	}
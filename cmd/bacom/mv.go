@@ -71,20 +71,7 @@ func mvFileToDir(src, dir string) error {
 		return err
 	}
 
-	srcResp, err := bacom.GetResponseFilename(src)
-	if err != nil {
-		return err
-	}
-	dstResp, err := bacom.GetResponseFilename(reqFname)
-	if err != nil {
-		return err
-	}
-	respExists, err := fileExists(srcResp)
-	if err != nil || !respExists {
-		return err
-	}
-
-	return mvFile(srcResp, dstResp)
+	return withCompanions(src, reqFname, mvFile)
 }
 
 func mvFileToFile(src, dst string) error {
@@ -99,20 +86,7 @@ func mvFileToFile(src, dst string) error {
 		return err
 	}
 
-	srcResp, err := bacom.GetResponseFilename(src)
-	if err != nil {
-		return err
-	}
-	dstResp, err := bacom.GetResponseFilename(reqFname)
-	if err != nil {
-		return err
-	}
-	respExists, err := fileExists(srcResp)
-	if err != nil || !respExists {
-		return err
-	}
-
-	return mvFile(srcResp, dstResp)
+	return withCompanions(src, reqFname, mvFile)
 }
 
 func mvFile(srcFname, dstFname string) (err error) {
@@ -166,3 +140,37 @@ func fileExists(fname string) (bool, error) {
 
 	return true, nil
 }
+
+// companionFilenames return the names of the files stored alongside a request file
+var companionFilenames = []func(reqFname string) (string, error){
+	bacom.GetResponseFilename,
+	bacom.GetSessionFilename,
+}
+
+// withCompanions calls op for each existing file stored alongside the request file src, with the name of the
+// matching file for the request file dst
+func withCompanions(src, dst string, op func(srcFname, dstFname string) error) error {
+	for _, companion := range companionFilenames {
+		srcFname, err := companion(src)
+		if err != nil {
+			return err
+		}
+		dstFname, err := companion(dst)
+		if err != nil {
+			return err
+		}
+		exists, err := fileExists(srcFname)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		err = op(srcFname, dstFname)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
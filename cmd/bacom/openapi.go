@@ -0,0 +1,535 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/jaydiff/jpath"
+	"gopkg.in/yaml.v2"
+)
+
+// openAPISpec is a loosely-typed OpenAPI 3 document, as decoded from JSON or YAML.
+// Only the parts needed to validate responses are interpreted.
+type openAPISpec struct {
+	doc      map[string]interface{}
+	prefixes []string
+}
+
+func readOpenAPISpec(fname string) (spec *openAPISpec, err error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading OpenAPI document %q", fname)
+	}
+	defer handleClose(&err, f)
+
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(f).Decode(&doc)
+		doc = normalizeYAML(doc)
+	default:
+		err = json.NewDecoder(f).Decode(&doc)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding OpenAPI document %q", fname)
+	}
+
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("invalid OpenAPI document %q", fname)
+	}
+	if v, _ := m["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		return nil, errors.Errorf("unsupported OpenAPI version %q in %q", v, fname)
+	}
+
+	return newOpenAPISpec(m), nil
+}
+
+func newOpenAPISpec(doc map[string]interface{}) *openAPISpec {
+	spec := &openAPISpec{doc: doc}
+
+	servers, _ := doc["servers"].([]interface{})
+	for _, server := range servers {
+		s, _ := server.(map[string]interface{})
+		u, err := url.Parse(stringField(s, "url"))
+		if err != nil {
+			continue
+		}
+		if p := strings.TrimRight(u.Path, "/"); p != "" {
+			spec.prefixes = append(spec.prefixes, p)
+		}
+	}
+
+	return spec
+}
+
+// normalizeYAML converts the map[interface{}]interface{} produced by yaml.v2
+// into the map[string]interface{} used by encoding/json.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	default:
+		return v
+	case int:
+		return float64(v)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	}
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+
+	return s
+}
+
+func mapField(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+
+	return v
+}
+
+func openAPIViolation(path, format string, args ...interface{}) bacom.Difference {
+	return bacom.Difference{
+		Kind:    bacom.SpecViolationDifference,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Validate checks the response against the operation matching the request method and path.
+// body is the decoded JSON body, as returned by readBody.
+func (spec *openAPISpec) Validate(method, reqPath string, resp *http.Response, body interface{}) []bacom.Difference {
+	op := spec.findOperation(method, reqPath)
+	if op == nil {
+		return []bacom.Difference{
+			openAPIViolation("", "no operation matching %s %s", method, reqPath),
+		}
+	}
+
+	respSpec := spec.findResponse(op, resp.StatusCode)
+	if respSpec == nil {
+		return []bacom.Difference{
+			openAPIViolation("", "undocumented status %s", resp.Status),
+		}
+	}
+
+	diffs := spec.validateHeaders(respSpec, resp.Header)
+
+	content := mapField(respSpec, "content")
+	if len(content) == 0 || body == nil {
+		return diffs
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	media := findMediaType(content, mediaType)
+	if media == nil {
+		return append(diffs, openAPIViolation("", "undocumented content type %q", mediaType))
+	}
+	schema := mapField(media, "schema")
+	if schema == nil {
+		return diffs
+	}
+
+	return append(diffs, spec.validateSchema("", schema, unwrapBody(body))...)
+}
+
+func (spec *openAPISpec) findOperation(method, reqPath string) map[string]interface{} {
+	paths := mapField(spec.doc, "paths")
+	method = strings.ToLower(method)
+
+	candidates := []string{reqPath}
+	for _, prefix := range spec.prefixes {
+		if strings.HasPrefix(reqPath, prefix+"/") {
+			candidates = append(candidates, strings.TrimPrefix(reqPath, prefix))
+		}
+	}
+
+	// The templates with the most literal segments are favoured. Ties go to the first template in lexicographic
+	// order, in which literal segments come before path parameters (i.e /users/{id} before /{collection}/42).
+	templates := make([]string, 0, len(paths))
+	for template := range paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	var best map[string]interface{}
+	bestScore := -1
+	for _, template := range templates {
+		for _, p := range candidates {
+			score, ok := matchPathTemplate(template, p)
+			if !ok || score <= bestScore {
+				continue
+			}
+			op := mapField(spec.resolve(paths[template]), method)
+			if op == nil {
+				continue
+			}
+			best, bestScore = op, score
+		}
+	}
+
+	return best
+}
+
+// matchPathTemplate matches a path against an OpenAPI path template (i.e /users/{id}).
+// The score is the number of literal segments, used to favour the most specific template.
+func matchPathTemplate(template, p string) (score int, ok bool) {
+	tParts := strings.Split(strings.Trim(template, "/"), "/")
+	pParts := strings.Split(strings.Trim(p, "/"), "/")
+
+	if len(tParts) != len(pParts) {
+		return 0, false
+	}
+	for i, t := range tParts {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if pParts[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if t != pParts[i] {
+			return 0, false
+		}
+		score++
+	}
+
+	return score, true
+}
+
+func (spec *openAPISpec) findResponse(op map[string]interface{}, status int) map[string]interface{} {
+	responses := mapField(op, "responses")
+	code := strconv.Itoa(status)
+
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if r, ok := responses[key]; ok {
+			return spec.resolve(r)
+		}
+	}
+
+	return nil
+}
+
+func findMediaType(content map[string]interface{}, mediaType string) map[string]interface{} {
+	candidates := []string{mediaType}
+	if i := strings.Index(mediaType, "/"); i != -1 {
+		candidates = append(candidates, mediaType[:i]+"/*")
+	}
+	candidates = append(candidates, "*/*")
+
+	for _, candidate := range candidates {
+		for k, v := range content {
+			if strings.EqualFold(k, candidate) {
+				m, _ := v.(map[string]interface{})
+				return m
+			}
+		}
+	}
+
+	return nil
+}
+
+func (spec *openAPISpec) validateHeaders(respSpec map[string]interface{}, h http.Header) []bacom.Difference {
+	var diffs []bacom.Difference
+
+	headers := mapField(respSpec, "headers")
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := http.CanonicalHeaderKey(name)
+		// Content-Type is described by the content of the response, and ignored in headers
+		if key == "Content-Type" {
+			continue
+		}
+		header := spec.resolve(headers[name])
+		values, ok := h[key]
+		if !ok {
+			if required, _ := header["required"].(bool); required {
+				diffs = append(diffs, bacom.Difference{
+					Kind:    bacom.SpecViolationDifference,
+					Header:  key,
+					Message: "missing required header",
+				})
+			}
+			continue
+		}
+
+		schema := spec.resolve(header["schema"])
+		for _, d := range spec.validateSchema("", schema, headerValue(spec.resolve(schema), strings.Join(values, ","))) {
+			d.Header = key
+			diffs = append(diffs, d)
+		}
+	}
+
+	return diffs
+}
+
+// headerValue decodes the value of a header according to its schema (using the simple style): numbers, booleans
+// and comma-separated arrays are decoded, the values that can't be decoded being kept as strings.
+func headerValue(schema map[string]interface{}, s string) interface{} {
+	switch {
+	case schemaAllowsType(schema, "array"):
+		items := mapField(schema, "items")
+		values := []interface{}{}
+		for _, v := range strings.Split(s, ",") {
+			values = append(values, headerValue(items, strings.TrimSpace(v)))
+		}
+		return values
+	case schemaAllowsType(schema, "integer") || schemaAllowsType(schema, "number"):
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case schemaAllowsType(schema, "boolean"):
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+
+	return s
+}
+
+// resolve follows local references (i.e #/components/schemas/Pet)
+func (spec *openAPISpec) resolve(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+
+	for i := 0; i < 32; i++ {
+		ref := stringField(m, "$ref")
+		if !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		var target interface{} = spec.doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+			target = mapField(asMap(target), part)
+		}
+		m = asMap(target)
+	}
+
+	return m
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+
+	return m
+}
+
+func (spec *openAPISpec) validateSchema(path string, schema map[string]interface{}, v interface{}) []bacom.Difference {
+	schema = spec.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if v == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schemaAllowsType(schema, "null") {
+			return nil
+		}
+		if _, ok := schema["type"]; ok {
+			return []bacom.Difference{openAPIViolation(path, "unexpected null value")}
+		}
+	}
+
+	var diffs []bacom.Difference
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		subSchemas, ok := schema[key].([]interface{})
+		if !ok {
+			continue
+		}
+		diffs = append(diffs, spec.validateCombination(path, key, subSchemas, v)...)
+	}
+
+	if t, ok := schema["type"]; ok && !valueMatchesType(t, v) {
+		return append(diffs, openAPIViolation(path, "expected type %v, got %s", t, jsonType(v)))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(enum, v) {
+		diffs = append(diffs, openAPIViolation(path, "value %v is not one of %v", v, enum))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		diffs = append(diffs, spec.validateObject(path, schema, v)...)
+	case []interface{}:
+		items := mapField(schema, "items")
+		if items == nil {
+			break
+		}
+		for i, item := range v {
+			diffs = append(diffs, spec.validateSchema(path+"["+strconv.Itoa(i)+"]", items, item)...)
+		}
+	}
+
+	return diffs
+}
+
+func (spec *openAPISpec) validateCombination(path, key string, subSchemas []interface{}, v interface{}) []bacom.Difference {
+	var diffs []bacom.Difference
+	matches := 0
+
+	for _, sub := range subSchemas {
+		subDiffs := spec.validateSchema(path, asMap(sub), v)
+		if len(subDiffs) == 0 {
+			matches++
+		}
+		diffs = append(diffs, subDiffs...)
+	}
+
+	switch key {
+	case "allOf":
+		return diffs
+	case "anyOf":
+		if matches == 0 {
+			return []bacom.Difference{openAPIViolation(path, "value does not match any of the anyOf schemas")}
+		}
+	case "oneOf":
+		if matches != 1 {
+			return []bacom.Difference{openAPIViolation(path, "value matches %d of the oneOf schemas, expected 1", matches)}
+		}
+	}
+
+	return nil
+}
+
+func (spec *openAPISpec) validateObject(path string, schema, obj map[string]interface{}) []bacom.Difference {
+	var diffs []bacom.Difference
+
+	required, _ := schema["required"].([]interface{})
+	for _, r := range required {
+		name, _ := r.(string)
+		if _, ok := obj[name]; !ok {
+			diffs = append(diffs, openAPIViolation(path+"."+jpath.EscapeKey(name), "missing required property"))
+		}
+	}
+
+	props := mapField(schema, "properties")
+	additional, hasAdditional := schema["additionalProperties"]
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propPath := path + "." + jpath.EscapeKey(k)
+		if prop, ok := props[k]; ok {
+			diffs = append(diffs, spec.validateSchema(propPath, asMap(prop), obj[k])...)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		switch additional := additional.(type) {
+		case bool:
+			if !additional {
+				diffs = append(diffs, openAPIViolation(propPath, "unexpected property"))
+			}
+		case map[string]interface{}:
+			diffs = append(diffs, spec.validateSchema(propPath, additional, obj[k])...)
+		}
+	}
+
+	return diffs
+}
+
+func schemaAllowsType(schema map[string]interface{}, name string) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == name
+	case []interface{}:
+		for _, tt := range t {
+			if tt == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func valueMatchesType(t interface{}, v interface{}) bool {
+	switch t := t.(type) {
+	default:
+		return true
+	case string:
+		return jsonTypeMatches(t, v)
+	case []interface{}:
+		for _, tt := range t {
+			s, _ := tt.(string)
+			if jsonTypeMatches(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func jsonTypeMatches(t string, v interface{}) bool {
+	actual := jsonType(v)
+
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		return actual == "number"
+	}
+
+	return actual == t
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	default:
+		return reflect.TypeOf(v).String()
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string, bacom.RawBody:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	if raw, ok := v.(bacom.RawBody); ok {
+		v = string(raw)
+	}
+	for _, e := range enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+
+	return false
+}
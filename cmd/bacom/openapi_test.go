@@ -0,0 +1,282 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yazgazan/bacom"
+)
+
+var testOpenAPIDoc = map[string]interface{}{
+	"openapi": "3.0.0",
+	"servers": []interface{}{
+		map[string]interface{}{"url": "https://example.org/v1"},
+	},
+	"paths": map[string]interface{}{
+		"/users/{id}": map[string]interface{}{
+			"get": map[string]interface{}{
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"headers": map[string]interface{}{
+							"X-Request-Id": map[string]interface{}{"required": true},
+							"X-Rate-Limit": map[string]interface{}{
+								"schema": map[string]interface{}{"type": "integer"},
+							},
+							"X-Roles": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":  "array",
+									"items": map[string]interface{}{"$ref": "#/components/schemas/Role"},
+								},
+							},
+						},
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/User"},
+							},
+						},
+					},
+					"default": map[string]interface{}{
+						"description": "error",
+					},
+				},
+			},
+		},
+	},
+	"components": map[string]interface{}{
+		"schemas": map[string]interface{}{
+			"User": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"id", "name"},
+				"properties": map[string]interface{}{
+					"id":    map[string]interface{}{"type": "integer"},
+					"name":  map[string]interface{}{"type": "string"},
+					"email": map[string]interface{}{"type": "string", "nullable": true},
+					"role":  map[string]interface{}{"type": "string", "enum": []interface{}{"admin", "user"}},
+				},
+				"additionalProperties": false,
+			},
+			"Role": map[string]interface{}{"type": "string", "enum": []interface{}{"admin", "user"}},
+		},
+	},
+}
+
+func TestOpenAPIValidate(t *testing.T) {
+	spec := newOpenAPISpec(testOpenAPIDoc)
+
+	for _, test := range []struct {
+		method string
+		path   string
+		status int
+		header http.Header
+		body   interface{}
+		paths  []string
+	}{
+		{
+			method: "GET", path: "/v1/users/42", status: 200,
+			header: http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"foo"}},
+			body: []interface{}{map[string]interface{}{
+				"id": 42.0, "name": "foo", "email": nil, "role": "admin",
+			}},
+		},
+		{
+			method: "GET", path: "/v1/users/42", status: 200,
+			header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			body: []interface{}{map[string]interface{}{
+				"id": 4.2, "role": "owner", "extra": true,
+			}},
+			paths: []string{"", ".name", ".extra", ".id", ".role"},
+		},
+		{
+			method: "GET", path: "/v1/users/42", status: 200,
+			header: http.Header{"X-Request-Id": {"foo"}, "X-Rate-Limit": {"100"}, "X-Roles": {"admin, user"}},
+		},
+		{
+			method: "GET", path: "/v1/users/42", status: 200,
+			header: http.Header{"X-Request-Id": {"foo"}, "X-Rate-Limit": {"1.5"}, "X-Roles": {"admin,owner"}},
+			paths:  []string{"", "[1]"},
+		},
+		{
+			method: "GET", path: "/users/42", status: 404,
+			header: http.Header{},
+		},
+		{
+			method: "POST", path: "/users/42", status: 200,
+			header: http.Header{},
+			paths:  []string{""},
+		},
+	} {
+		resp := &http.Response{
+			StatusCode: test.status,
+			Status:     http.StatusText(test.status),
+			Header:     test.header,
+		}
+
+		diffs := spec.Validate(test.method, test.path, resp, test.body)
+		if len(diffs) != len(test.paths) {
+			t.Errorf("Validate(%q, %q) = %+v, expected %d violations", test.method, test.path, diffs, len(test.paths))
+			continue
+		}
+		for i, d := range diffs {
+			if d.Kind != bacom.SpecViolationDifference {
+				t.Errorf("Validate(%q, %q)[%d].Kind = %q, expected %q", test.method, test.path, i, d.Kind, bacom.SpecViolationDifference)
+			}
+			if d.Path != test.paths[i] {
+				t.Errorf("Validate(%q, %q)[%d].Path = %q, expected %q", test.method, test.path, i, d.Path, test.paths[i])
+			}
+		}
+	}
+}
+
+func TestMatchPathTemplate(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		path     string
+		score    int
+		ok       bool
+	}{
+		{"/users", "/users", 1, true},
+		{"/users/{id}", "/users/42", 1, true},
+		{"/users/{id}", "/users", 0, false},
+		{"/users/{id}/posts", "/users/42/posts", 2, true},
+		{"/users/me", "/users/42", 0, false},
+	} {
+		score, ok := matchPathTemplate(test.template, test.path)
+		if score != test.score || ok != test.ok {
+			t.Errorf("matchPathTemplate(%q, %q) = %d, %v, expected %d, %v", test.template, test.path, score, ok, test.score, test.ok)
+		}
+	}
+}
+
+func TestOpenAPIHeaderViolations(t *testing.T) {
+	spec := newOpenAPISpec(testOpenAPIDoc)
+	resp := &http.Response{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Header:     http.Header{"X-Rate-Limit": {"unlimited"}},
+	}
+
+	diffs := spec.Validate("GET", "/users/42", resp, nil)
+	expected := []string{"X-Rate-Limit", "X-Request-Id"}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Validate = %+v, expected %d violations", diffs, len(expected))
+	}
+	for i, d := range diffs {
+		if d.Header != expected[i] {
+			t.Errorf("Validate[%d].Header = %q, expected %q", i, d.Header, expected[i])
+		}
+	}
+}
+
+func TestOpenAPIFindOperation(t *testing.T) {
+	op := func(id string) map[string]interface{} {
+		return map[string]interface{}{"get": map[string]interface{}{"operationId": id}}
+	}
+	spec := newOpenAPISpec(map[string]interface{}{
+		"openapi": "3.0.0",
+		"paths": map[string]interface{}{
+			"/{collection}/42":   op("collection"),
+			"/users/{id}":        op("user"),
+			"/users/me":          op("me"),
+			"/{collection}/{id}": op("item"),
+			"/users/{id}/posts":  op("posts"),
+		},
+	})
+
+	for _, test := range []struct {
+		path     string
+		expected string
+	}{
+		{"/users/me", "me"},
+		{"/users/42", "user"},
+		{"/posts/42", "collection"},
+		{"/posts/1", "item"},
+		{"/users/42/posts", "posts"},
+	} {
+		// the paths are held in a map, ranged over in a random order
+		for i := 0; i < 20; i++ {
+			got := stringField(spec.findOperation("GET", test.path), "operationId")
+			if got != test.expected {
+				t.Errorf("findOperation(%q) = %q, expected %q", test.path, got, test.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestOpenAPITextResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/status/")))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "TestOpenAPITextResponse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "v1.0.0")
+	err = os.Mkdir(versionDir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := func(s string) string {
+		return "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: " + strconv.Itoa(len(s)) + "\r\n\r\n" + s
+	}
+	writeTestFiles(t, dir, map[string]string{
+		"openapi.yaml": `openapi: 3.0.0
+paths:
+  /status/{value}:
+    get:
+      responses:
+        "200":
+          content:
+            text/plain:
+              schema:
+                type: string
+                enum: [up, down]
+`,
+	})
+	writeTestFiles(t, versionDir, map[string]string{
+		"up_req.txt":       "GET /status/up HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"up_resp.txt":      text("up"),
+		"unknown_req.txt":  "GET /status/unknown HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"unknown_resp.txt": text("unknown"),
+	})
+
+	conf, err := parseTestFlags([]string{
+		"-conf", filepath.Join(dir, "bacom.json"),
+		"-openapi", filepath.Join(dir, "openapi.yaml"),
+		"-target-host", strings.TrimPrefix(srv.URL, "http://"),
+	})
+	if err != nil {
+		t.Fatalf("parseTestFlags: unexpected error: %s", err)
+	}
+	suites, err := collectTests(conf, []string{versionDir})
+	if err != nil {
+		t.Fatalf("collectTests: unexpected error: %s", err)
+	}
+	for _, jobs := range suites[0].runs {
+		runJobs(conf, jobs)
+	}
+
+	for _, job := range suites[0].tests {
+		if job.err != nil {
+			t.Fatalf("%s: unexpected error: %s", job.fname, job.err)
+		}
+		var expected []bacom.Difference
+		if filepath.Base(job.fname) == "unknown_req.txt" {
+			expected = []bacom.Difference{openAPIViolation("", "value %v is not one of %v", "unknown", []interface{}{"up", "down"})}
+		}
+		if !reflect.DeepEqual(job.differences, expected) {
+			t.Errorf("%s: differences = %+v, expected %+v", filepath.Base(job.fname), job.differences, expected)
+		}
+	}
+}
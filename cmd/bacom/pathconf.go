@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -23,17 +24,140 @@ const (
 )
 
 type pathConf struct {
-	Path     string
-	Method   string
-	Versions constraints
-	JSON     jsonConf
-	Headers  headersConf
+	Path       string
+	Method     string
+	Versions   constraints
+	JSON       jsonConf
+	Body       bodyConf
+	Headers    headersConf
+	Trailers   headersConf
+	Validators []string
 }
 
 type jsonConf struct {
-	Ignore        []string
-	IgnoreMissing []string `yaml:"ignore_missing"`
-	IgnoreNull    bool     `yaml:"ignore_null"`
+	Ignore         []string
+	IgnoreMissing  []string      `yaml:"ignore_missing"`
+	IgnoreNull     bool          `yaml:"ignore_null"`
+	CompareContent []contentRule `yaml:"compare_content"`
+	Enums          []string
+	Arrays         []bacom.ArrayRule
+	Repeated       []string
+}
+
+func (c jsonConf) bodyOptions() bacom.BodyOptions {
+	opts := bacom.BodyOptions{
+		Ignore:        c.Ignore,
+		IgnoreMissing: c.IgnoreMissing,
+		IgnoreNull:    c.IgnoreNull,
+		Arrays:        c.Arrays,
+		Repeated:      c.Repeated,
+	}
+	for _, rule := range c.CompareContent {
+		opts.CompareContent = append(opts.CompareContent, bacom.ContentRule(rule))
+	}
+
+	return opts
+}
+
+// contentRule is configured using either a JSON path or an object with the bacom.ContentRule fields
+type contentRule bacom.ContentRule
+
+func (r *contentRule) UnmarshalJSON(b []byte) error {
+	var path string
+	if err := json.Unmarshal(b, &path); err == nil {
+		*r = contentRule{Path: path}
+		return nil
+	}
+
+	return json.Unmarshal(b, (*bacom.ContentRule)(r))
+}
+
+func (r *contentRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		*r = contentRule{Path: path}
+		return nil
+	}
+
+	var rule struct {
+		Path      string
+		Tolerance float64
+		Normalize []string
+		Pattern   string
+	}
+	err := unmarshal(&rule)
+	*r = contentRule(rule)
+
+	return err
+}
+
+func (r *contentRule) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	default:
+		return errors.Errorf("invalid compare_content rule %v", v)
+	case string:
+		*r = contentRule{Path: v}
+	case map[string]interface{}:
+		*r = contentRule{}
+		for k, value := range v {
+			var ok bool
+			switch strings.ToLower(k) {
+			default:
+				return errors.Errorf("unknown compare_content field %q", k)
+			case "path":
+				r.Path, ok = value.(string)
+			case "pattern":
+				r.Pattern, ok = value.(string)
+			case "tolerance":
+				r.Tolerance, ok = tomlNumber(value)
+			case "normalize":
+				r.Normalize, ok = tomlStrings(value)
+			}
+			if !ok {
+				return errors.Errorf("invalid value for compare_content field %q: %v", k, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func tomlNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+func tomlStrings(v interface{}) ([]string, bool) {
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	ss := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		ss = append(ss, s)
+	}
+
+	return ss, true
+}
+
+// bodyConf selects how the bodies are decoded (see bacom.BodyFormat). The decoded XML and form bodies
+// are compared using the json configuration.
+type bodyConf struct {
+	Format bacom.BodyFormat
+	// EventType is the key holding the name of NDJSON events
+	EventType string `yaml:"event_type"`
+	Stream    bacom.StreamOptions
 }
 
 type headersConf struct {
@@ -44,6 +168,22 @@ type headersConf struct {
 func getPathConf(verbose bool, conf []pathConf, version, method, path string) pathConf {
 	var pConf pathConf
 
+	for _, c := range matchPathConf(verbose, conf, version, method, path) {
+		err := mergo.Merge(&pConf, c, mergo.WithOverride, mergo.WithAppendSlice)
+		if err != nil {
+			continue
+		}
+	}
+
+	pConf.Path = ""
+	pConf.Method = ""
+	return pConf
+}
+
+// matchPathConf returns the configurations matching a request, in order
+func matchPathConf(verbose bool, conf []pathConf, version, method, path string) []pathConf {
+	var matches []pathConf
+
 	method = strings.ToLower(method)
 	for _, c := range conf {
 		ok, err := bacom.MatchPath(c.Path, path)
@@ -57,15 +197,10 @@ func getPathConf(verbose bool, conf []pathConf, version, method, path string) pa
 		if err != nil || !ok {
 			continue
 		}
-		err = mergo.Merge(&pConf, c, mergo.WithOverride, mergo.WithAppendSlice)
-		if err != nil {
-			continue
-		}
+		matches = append(matches, c)
 	}
 
-	pConf.Path = ""
-	pConf.Method = ""
-	return pConf
+	return matches
 }
 
 func readPathConf(fname string, defaultConf []pathConf) ([]pathConf, error) {
@@ -89,7 +224,34 @@ func readPathConf(fname string, defaultConf []pathConf) ([]pathConf, error) {
 		return defaultConf, nil
 	}
 
-	return conf, nil
+	return conf, validatePathConf(conf)
+}
+
+func validatePathConf(conf []pathConf) error {
+	for _, c := range conf {
+		for _, rule := range c.JSON.CompareContent {
+			err := bacom.ContentRule(rule).Validate()
+			if err != nil {
+				return errors.Wrapf(err, "compare_content for path %q", c.Path)
+			}
+		}
+		err := c.Body.Format.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "body for path %q", c.Path)
+		}
+		err = c.Body.Stream.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "body.stream for path %q", c.Path)
+		}
+		for _, rule := range c.JSON.Arrays {
+			err := rule.Validate()
+			if err != nil {
+				return errors.Wrapf(err, "arrays for path %q", c.Path)
+			}
+		}
+	}
+
+	return nil
 }
 
 func pathConfReader(format pathConfFormat) (func(string) ([]pathConf, error), error) {
@@ -105,6 +267,8 @@ func pathConfReader(format pathConfFormat) (func(string) ([]pathConf, error), er
 	}
 }
 
+// readJSONPathConf reads either an array of path configurations, or an object holding them in `Conf`
+// (along with the `Clients` section, see readClientsConf)
 func readJSONPathConf(fname string) (conf []pathConf, err error) {
 	f, err := os.Open(fname)
 	if err != nil {
@@ -112,9 +276,23 @@ func readJSONPathConf(fname string) (conf []pathConf, err error) {
 	}
 	defer handleClose(&err, f)
 
-	err = json.NewDecoder(f).Decode(&conf)
+	var raw json.RawMessage
+	err = json.NewDecoder(f).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		err = json.Unmarshal(raw, &conf)
+
+		return conf, err
+	}
+
+	var file struct {
+		Conf []pathConf
+	}
+	err = json.Unmarshal(raw, &file)
 
-	return conf, err
+	return file.Conf, err
 }
 
 func readYAMLPathConf(fname string) (conf []pathConf, err error) {
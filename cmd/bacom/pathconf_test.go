@@ -1,8 +1,12 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
+
+	"github.com/yazgazan/bacom"
 )
 
 func TestGetPathConf(t *testing.T) {
@@ -345,3 +349,140 @@ func TestPathConfReader(t *testing.T) {
 		}
 	}
 }
+
+func TestReadCompareContent(t *testing.T) {
+	expected := []contentRule{
+		{Path: ".currency"},
+		{Path: ".price", Tolerance: 0.01},
+		{Path: ".code", Normalize: []string{"trim", "lowercase"}, Pattern: "^([a-z]+)-"},
+	}
+
+	for ext, content := range map[string]string{
+		".json": `[{"Path": "**", "JSON": {"CompareContent": [
+			".currency",
+			{"Path": ".price", "Tolerance": 0.01},
+			{"Path": ".code", "Normalize": ["trim", "lowercase"], "Pattern": "^([a-z]+)-"}
+		]}}]`,
+		".yaml": `conf:
+  - path: "**"
+    json:
+      compare_content:
+        - .currency
+        - path: .price
+          tolerance: 0.01
+        - path: .code
+          normalize: [trim, lowercase]
+          pattern: "^([a-z]+)-"
+`,
+		".toml": `[[conf]]
+    path = "**"
+    [[conf.json.compareContent]]
+        path = ".currency"
+    [[conf.json.compareContent]]
+        path = ".price"
+        tolerance = 0.01
+    [[conf.json.compareContent]]
+        path = ".code"
+        normalize = ["trim", "lowercase"]
+        pattern = "^([a-z]+)-"
+`,
+	} {
+		f, err := ioutil.TempFile("", "bacom-conf-*"+ext)
+		if err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		if err != nil {
+			t.Fatalf("writing temporary file: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("closing temporary file: %s", err)
+		}
+
+		conf, err := readPathConf(f.Name(), nil)
+		if err != nil {
+			t.Errorf("readPathConf(%s): unexpected error: %s", ext, err)
+			continue
+		}
+		if len(conf) != 1 || !reflect.DeepEqual(conf[0].JSON.CompareContent, expected) {
+			t.Errorf("readPathConf(%s) = %+v, expected compare_content %+v", ext, conf, expected)
+		}
+	}
+}
+
+func TestReadArrays(t *testing.T) {
+	expected := []bacom.ArrayRule{
+		{Path: ".items", Strategy: bacom.ArrayMatchByKey, Key: "id"},
+		{Path: ".tags", Strategy: bacom.ArraySet},
+	}
+
+	for ext, content := range map[string]string{
+		".json": `[{"Path": "**", "JSON": {"Arrays": [
+			{"Path": ".items", "Strategy": "key", "Key": "id"},
+			{"Path": ".tags", "Strategy": "set"}
+		]}}]`,
+		".yaml": `conf:
+  - path: "**"
+    json:
+      arrays:
+        - path: .items
+          strategy: key
+          key: id
+        - path: .tags
+          strategy: set
+`,
+		".toml": `[[conf]]
+    path = "**"
+    [[conf.json.arrays]]
+        path = ".items"
+        strategy = "key"
+        key = "id"
+    [[conf.json.arrays]]
+        path = ".tags"
+        strategy = "set"
+`,
+	} {
+		f, err := ioutil.TempFile("", "bacom-conf-*"+ext)
+		if err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(content)
+		if err != nil {
+			t.Fatalf("writing temporary file: %s", err)
+		}
+		err = f.Close()
+		if err != nil {
+			t.Fatalf("closing temporary file: %s", err)
+		}
+
+		conf, err := readPathConf(f.Name(), nil)
+		if err != nil {
+			t.Errorf("readPathConf(%s): unexpected error: %s", ext, err)
+			continue
+		}
+		if len(conf) != 1 || !reflect.DeepEqual(conf[0].JSON.Arrays, expected) {
+			t.Errorf("readPathConf(%s) = %+v, expected arrays %+v", ext, conf, expected)
+		}
+	}
+}
+
+func TestValidatePathConf(t *testing.T) {
+	for _, rule := range []contentRule{
+		{Path: ".code", Pattern: "("},
+		{Path: ".code", Normalize: []string{"capitalize"}},
+		{Path: ".price", Tolerance: -1},
+	} {
+		err := validatePathConf([]pathConf{{Path: "**", JSON: jsonConf{CompareContent: []contentRule{rule}}}})
+		if err == nil {
+			t.Errorf("validatePathConf(%+v): expected error, got nil", rule)
+		}
+	}
+
+	err := validatePathConf([]pathConf{{Path: "**", JSON: jsonConf{Arrays: []bacom.ArrayRule{{Strategy: "sorted"}}}}})
+	if err == nil {
+		t.Error("validatePathConf(arrays sorted): expected error, got nil")
+	}
+}
@@ -0,0 +1,659 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+func importPostmanCmd(args []string) {
+	c, err := parseImportPostmanFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	vars := map[string]string{}
+	if c.EnvFile != "" {
+		vars, err = readPostmanEnvironment(c.EnvFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for k, v := range c.Vars {
+		vars[k] = v
+	}
+
+	for _, fname := range c.Files {
+		err := importFromPostmanFile(fname, c.Dir, c.Verbose, c.Filters, vars)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// postmanCollection is a Postman v2.1 collection
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem `json:"item"`
+	Variable []postmanKV   `json:"variable,omitempty"`
+	Auth     *postmanAuth  `json:"auth,omitempty"`
+}
+
+// postmanItem is either a folder (with sub-items) or a request
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item,omitempty"`
+	Request  *postmanRequest   `json:"request,omitempty"`
+	Response []postmanResponse `json:"response,omitempty"`
+	Variable []postmanKV       `json:"variable,omitempty"`
+	Auth     *postmanAuth      `json:"auth,omitempty"`
+}
+
+type postmanRequest struct {
+	Method string       `json:"method"`
+	URL    postmanURL   `json:"url"`
+	Header []postmanKV  `json:"header"`
+	Body   *postmanBody `json:"body,omitempty"`
+	Auth   *postmanAuth `json:"auth,omitempty"`
+}
+
+type postmanResponse struct {
+	Name            string          `json:"name"`
+	OriginalRequest *postmanRequest `json:"originalRequest,omitempty"`
+	Status          string          `json:"status"`
+	Code            int             `json:"code"`
+	Header          []postmanKV     `json:"header"`
+	Body            string          `json:"body"`
+}
+
+type postmanKV struct {
+	Key      string       `json:"key"`
+	Value    postmanValue `json:"value"`
+	Disabled bool         `json:"disabled,omitempty"`
+}
+
+// postmanValue accepts any JSON scalar, as variables' values are not always strings
+type postmanValue string
+
+func (v *postmanValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = postmanValue(s)
+		return nil
+	}
+	if string(b) == "null" {
+		*v = ""
+		return nil
+	}
+
+	*v = postmanValue(b)
+	return nil
+}
+
+type postmanURL struct {
+	Raw      string
+	Protocol string
+	Host     []string
+	Path     []string
+	Query    []postmanKV
+}
+
+// UnmarshalJSON handles urls defined either as a string or an object
+func (u *postmanURL) UnmarshalJSON(b []byte) error {
+	var raw string
+	if err := json.Unmarshal(b, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+
+	type plain postmanURL
+	return json.Unmarshal(b, (*plain)(u))
+}
+
+// MarshalJSON always produces the string form of the url
+func (u postmanURL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+func (u postmanURL) String() string {
+	if u.Raw != "" {
+		return u.Raw
+	}
+
+	// Postman defaults to http, and the url wouldn't have a host without a scheme
+	protocol := u.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+	s := protocol + "://" + strings.Join(u.Host, ".")
+	if len(u.Path) != 0 {
+		s += "/" + strings.Join(u.Path, "/")
+	}
+
+	var query []string
+	for _, kv := range u.Query {
+		if kv.Disabled {
+			continue
+		}
+		query = append(query, url.QueryEscape(kv.Key)+"="+url.QueryEscape(string(kv.Value)))
+	}
+	if len(query) != 0 {
+		s += "?" + strings.Join(query, "&")
+	}
+
+	return s
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw,omitempty"`
+	URLEncoded []postmanKV `json:"urlencoded,omitempty"`
+	FormData   []struct {
+		postmanKV
+		Type string      `json:"type"`
+		Src  interface{} `json:"src,omitempty"`
+	} `json:"formdata,omitempty"`
+	GraphQL *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql,omitempty"`
+	Options *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Bearer []postmanKV `json:"bearer,omitempty"`
+	Basic  []postmanKV `json:"basic,omitempty"`
+	APIKey []postmanKV `json:"apikey,omitempty"`
+}
+
+func (a *postmanAuth) param(params []postmanKV, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return string(p.Value)
+		}
+	}
+
+	return ""
+}
+
+type postmanEnvironment struct {
+	Values []struct {
+		Key     string
+		Value   postmanValue
+		Enabled *bool
+	}
+}
+
+func readPostmanEnvironment(fname string) (vars map[string]string, err error) {
+	var env postmanEnvironment
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer handleClose(&err, f)
+
+	err = json.NewDecoder(f).Decode(&env)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding postman environment %q", fname)
+	}
+
+	vars = make(map[string]string, len(env.Values))
+	for _, v := range env.Values {
+		if v.Enabled != nil && !*v.Enabled {
+			continue
+		}
+		vars[v.Key] = string(v.Value)
+	}
+
+	return vars, nil
+}
+
+// with returns vars along with the variables of an inner scope (kvs), which take precedence over vars.
+// overrides (the command-line and environment variables) take precedence over every scope.
+func (vars templateVars) with(kvs []postmanKV, overrides templateVars) templateVars {
+	if len(kvs) == 0 {
+		return vars
+	}
+
+	merged := make(templateVars, len(vars)+len(kvs)+len(overrides))
+	for k, v := range vars {
+		merged[k] = v
+	}
+	for _, kv := range kvs {
+		if !kv.Disabled {
+			merged[kv.Key] = string(kv.Value)
+		}
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+func importFromPostmanFile(fname, outDir string, verbose bool, filters reqFilters, vars map[string]string) (err error) {
+	var collection postmanCollection
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer handleClose(&err, f)
+
+	err = json.NewDecoder(f).Decode(&collection)
+	if err != nil {
+		return errors.Wrapf(err, "decoding postman collection %q", fname)
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, "v2.1") {
+		return errors.Errorf("unsupported postman collection schema %q, expected v2.1", collection.Info.Schema)
+	}
+
+	// Folder and request variables take precedence over the collection's variables, while command-line and
+	// environment variables take precedence over all of them
+	overrides := templateVars(vars)
+	return importPostmanItems(
+		collection.Item, outDir, verbose, filters,
+		overrides.with(collection.Variable, overrides), overrides, collection.Auth,
+	)
+}
+
+func importPostmanItems(
+	items []postmanItem,
+	outDir string,
+	verbose bool,
+	filters reqFilters,
+	vars, overrides templateVars,
+	auth *postmanAuth,
+) error {
+	for _, item := range items {
+		itemVars := vars.with(item.Variable, overrides)
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+
+		if item.Request == nil {
+			err := importPostmanItems(item.Item, outDir, verbose, filters, itemVars, overrides, itemAuth)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := importPostmanItem(item, outDir, verbose, filters, itemVars, itemAuth)
+		if err != nil {
+			return errors.Wrapf(err, "importing postman request %q", item.Name)
+		}
+	}
+
+	return nil
+}
+
+func importPostmanItem(
+	item postmanItem,
+	outDir string,
+	verbose bool,
+	filters reqFilters,
+	vars templateVars,
+	auth *postmanAuth,
+) error {
+	if len(item.Response) == 0 {
+		req, err := newPostmanRequest(*item.Request, vars, auth)
+		if err != nil {
+			return err
+		}
+		return importPostmanPair(outDir, verbose, filters, req, nil)
+	}
+
+	for _, example := range item.Response {
+		reqDef := *item.Request
+		if example.OriginalRequest != nil {
+			reqDef = *example.OriginalRequest
+		}
+		req, err := newPostmanRequest(reqDef, vars, auth)
+		if err != nil {
+			return err
+		}
+		resp := newPostmanResponse(example, req)
+
+		err = importPostmanPair(outDir, verbose, filters, req, resp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func importPostmanPair(outDir string, verbose bool, filters reqFilters, req *http.Request, resp *http.Response) error {
+	err := filters.Match(req)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "excluding request: %s\n", err)
+		}
+		return nil
+	}
+
+	name := strings.ToLower(req.Method) + "-" + normalize(req.URL.Path)
+
+	reqFname, err := importReq(verbose, outDir, name, req)
+	if err != nil || resp == nil {
+		return err
+	}
+
+	return importResp(verbose, reqFname, outDir, name, resp)
+}
+
+func newPostmanRequest(def postmanRequest, vars templateVars, auth *postmanAuth) (*http.Request, error) {
+	if def.Auth != nil {
+		auth = def.Auth
+	}
+	method := def.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	body, contentType, err := postmanRequestBody(def.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, vars.resolve(def.URL.String()), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		req.Body = nil
+	}
+
+	for _, h := range def.Header {
+		if h.Disabled {
+			continue
+		}
+		req.Header.Add(vars.resolve(h.Key), vars.resolve(string(h.Value)))
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	applyPostmanAuth(req, auth, vars)
+
+	return req, nil
+}
+
+func postmanRequestBody(body *postmanBody, vars templateVars) (b []byte, contentType string, err error) {
+	if body == nil {
+		return nil, "", nil
+	}
+
+	switch body.Mode {
+	default:
+		return nil, "", nil
+	case "raw":
+		if body.Options != nil && strings.ToLower(body.Options.Raw.Language) == "json" {
+			contentType = "application/json"
+		}
+		return []byte(vars.resolve(body.Raw)), contentType, nil
+	case "urlencoded":
+		values := url.Values{}
+		for _, kv := range body.URLEncoded {
+			if kv.Disabled {
+				continue
+			}
+			values.Add(vars.resolve(kv.Key), vars.resolve(string(kv.Value)))
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+	case "formdata":
+		return postmanFormData(body, vars)
+	case "graphql":
+		var variables interface{}
+		if body.GraphQL == nil {
+			return nil, "application/json", nil
+		}
+		if body.GraphQL.Variables != "" {
+			err = json.Unmarshal([]byte(vars.resolve(body.GraphQL.Variables)), &variables)
+			if err != nil {
+				return nil, "", errors.Wrap(err, "decoding graphql variables")
+			}
+		}
+		b, err = json.Marshal(map[string]interface{}{
+			"query":     vars.resolve(body.GraphQL.Query),
+			"variables": variables,
+		})
+		return b, "application/json", err
+	}
+}
+
+func postmanFormData(body *postmanBody, vars templateVars) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	for _, param := range body.FormData {
+		if param.Disabled {
+			continue
+		}
+		key := vars.resolve(param.Key)
+		if param.Type != "file" {
+			err := w.WriteField(key, vars.resolve(string(param.Value)))
+			if err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		src, _ := param.Src.(string)
+		err := writePostmanFile(w, key, src)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err := w.Close()
+
+	return buf.Bytes(), w.FormDataContentType(), err
+}
+
+func writePostmanFile(w *multipart.Writer, key, src string) (err error) {
+	part, err := w.CreateFormFile(key, src)
+	if err != nil || src == "" {
+		return err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "reading form-data file %q", src)
+	}
+	defer handleClose(&err, f)
+
+	_, err = io.Copy(part, f)
+
+	return err
+}
+
+func applyPostmanAuth(req *http.Request, auth *postmanAuth, vars templateVars) {
+	if auth == nil {
+		return
+	}
+
+	switch auth.Type {
+	case "bearer":
+		token := vars.resolve(auth.param(auth.Bearer, "token"))
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "basic":
+		req.SetBasicAuth(
+			vars.resolve(auth.param(auth.Basic, "username")),
+			vars.resolve(auth.param(auth.Basic, "password")),
+		)
+	case "apikey":
+		key := vars.resolve(auth.param(auth.APIKey, "key"))
+		value := vars.resolve(auth.param(auth.APIKey, "value"))
+		if auth.param(auth.APIKey, "in") == "query" {
+			q := req.URL.Query()
+			q.Set(key, value)
+			req.URL.RawQuery = q.Encode()
+			return
+		}
+		req.Header.Set(key, value)
+	}
+}
+
+func newPostmanResponse(example postmanResponse, req *http.Request) *http.Response {
+	header := make(http.Header)
+	for _, h := range example.Header {
+		if h.Disabled {
+			continue
+		}
+		header.Add(h.Key, string(h.Value))
+	}
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(example.Body)))
+
+	status := example.Status
+	if status == "" {
+		status = http.StatusText(example.Code)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(example.Code) + " " + status,
+		StatusCode:    example.Code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(example.Body)),
+		ContentLength: int64(len(example.Body)),
+		Request:       req,
+	}
+}
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+func exportPostmanCmd(args []string) {
+	c, err := parseExportFlags(postmanSubCmdName, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	pairs, err := collectExportPairs(c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = writeExport(c.Out, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(newPostmanCollection(filepath.Base(c.Dir), pairs))
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newPostmanCollection creates a collection with one folder per version
+func newPostmanCollection(name string, pairs []exportPair) postmanCollection {
+	var collection postmanCollection
+
+	collection.Info.Name = name
+	collection.Info.Schema = postmanSchema
+	collection.Item = []postmanItem{}
+
+	folders := map[string]int{}
+	for _, pair := range pairs {
+		idx, ok := folders[pair.version]
+		if !ok {
+			idx = len(collection.Item)
+			folders[pair.version] = idx
+			collection.Item = append(collection.Item, postmanItem{Name: pair.version, Item: []postmanItem{}})
+		}
+		collection.Item[idx].Item = append(collection.Item[idx].Item, newPostmanItem(pair))
+	}
+
+	return collection
+}
+
+func newPostmanItem(pair exportPair) postmanItem {
+	name, _ := bacom.NameFromReqFileName(pair.fname)
+	req := &postmanRequest{
+		Method: pair.req.Method,
+		URL:    postmanURL{Raw: pair.req.URL.String()},
+		Header: exportHeaders(pair.req.Header),
+	}
+	if req.Header == nil {
+		req.Header = []postmanKV{}
+	}
+	if len(pair.reqBody) != 0 {
+		req.Body = &postmanBody{
+			Mode: "raw",
+			Raw:  string(pair.reqBody),
+		}
+		if isJSONContentType(pair.req.Header.Get("Content-Type")) {
+			req.Body.Options = &postmanBodyOptions{}
+			req.Body.Options.Raw.Language = "json"
+		}
+	}
+
+	item := postmanItem{
+		Name:    name,
+		Request: req,
+	}
+	if pair.resp == nil {
+		return item
+	}
+
+	header := []postmanKV{}
+	for _, name := range sortedHeaderNames(pair.resp.Header) {
+		for _, v := range pair.resp.Header[name] {
+			header = append(header, postmanKV{Key: name, Value: postmanValue(v)})
+		}
+	}
+	item.Response = []postmanResponse{{
+		Name:            name,
+		OriginalRequest: req,
+		Status:          strings.TrimSpace(strings.TrimPrefix(pair.resp.Status, strconv.Itoa(pair.resp.StatusCode))),
+		Code:            pair.resp.StatusCode,
+		Header:          header,
+		Body:            string(pair.respBody),
+	}}
+
+	return item
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTemplateVarsResolve(t *testing.T) {
+	vars := templateVars{
+		"host":    "localhost:8080",
+		"baseURL": "http://{{host}}",
+	}
+
+	for _, test := range []struct {
+		In       string
+		Expected string
+	}{
+		{"{{baseURL}}/api", "http://localhost:8080/api"},
+		{"{{ host }}", "localhost:8080"},
+		{"{{unknown}}/api", "{{unknown}}/api"},
+		{"no variables", "no variables"},
+	} {
+		got := vars.resolve(test.In)
+		if got != test.Expected {
+			t.Errorf("resolve(%q) = %q, expected %q", test.In, got, test.Expected)
+		}
+	}
+}
+
+func TestTemplateVarsWith(t *testing.T) {
+	overrides := templateVars{"host": "cli"}
+	collection := overrides.with([]postmanKV{
+		{Key: "host", Value: "collection"},
+		{Key: "token", Value: "abc"},
+		{Key: "user", Value: "collection"},
+		{Key: "disabled", Value: "x", Disabled: true},
+	}, overrides)
+	item := collection.with([]postmanKV{
+		{Key: "host", Value: "item"},
+		{Key: "user", Value: "item"},
+	}, overrides)
+
+	for _, test := range []struct {
+		name     string
+		vars     templateVars
+		key      string
+		expected string
+	}{
+		{"collection", collection, "host", "cli"},
+		{"collection", collection, "token", "abc"},
+		{"collection", collection, "user", "collection"},
+		{"item", item, "host", "cli"},
+		{"item", item, "token", "abc"},
+		{"item", item, "user", "item"},
+	} {
+		if got := test.vars[test.key]; got != test.expected {
+			t.Errorf("%s: vars[%q] = %q, expected %q", test.name, test.key, got, test.expected)
+		}
+	}
+	if _, ok := collection["disabled"]; ok {
+		t.Errorf("vars[%q] should not be set", "disabled")
+	}
+	if collection["user"] != "collection" {
+		t.Errorf("with modified the outer scope: vars[%q] = %q", "user", collection["user"])
+	}
+}
+
+func TestPostmanURL(t *testing.T) {
+	for _, test := range []struct {
+		In       string
+		Expected string
+	}{
+		{`"http://{{host}}/api?q=1"`, "http://{{host}}/api?q=1"},
+		{
+			`{"protocol": "http", "host": ["{{host}}"], "path": ["api", "users"], "query": [{"key": "q", "value": "1"}, {"key": "off", "value": "1", "disabled": true}]}`,
+			"http://{{host}}/api/users?q=1",
+		},
+		{
+			`{"host": ["example", "org"], "path": ["api", "users"]}`,
+			"http://example.org/api/users",
+		},
+		{
+			`{"protocol": "https", "host": ["example", "org"]}`,
+			"https://example.org",
+		},
+	} {
+		var u postmanURL
+		err := json.Unmarshal([]byte(test.In), &u)
+		if err != nil {
+			t.Errorf("json.Unmarshal(%s): unexpected error: %s", test.In, err)
+			continue
+		}
+		if u.String() != test.Expected {
+			t.Errorf("postmanURL(%s).String() = %q, expected %q", test.In, u.String(), test.Expected)
+		}
+	}
+}
+
+func TestNewPostmanRequest(t *testing.T) {
+	var def postmanRequest
+	err := json.Unmarshal([]byte(`{
+		"method": "POST",
+		"url": "{{baseURL}}/api/users",
+		"header": [{"key": "X-Version", "value": 2}],
+		"body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}},
+		"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]}
+	}`), &def)
+	if err != nil {
+		t.Fatalf("json.Unmarshal: unexpected error: %s", err)
+	}
+
+	vars := templateVars{"baseURL": "http://localhost:8080", "name": "foo", "token": "abc"}
+	req, err := newPostmanRequest(def, vars, nil)
+	if err != nil {
+		t.Fatalf("newPostmanRequest: unexpected error: %s", err)
+	}
+
+	if req.URL.String() != "http://localhost:8080/api/users" {
+		t.Errorf("req.URL = %q, expected %q", req.URL, "http://localhost:8080/api/users")
+	}
+	for k, expected := range map[string]string{
+		"X-Version":     "2",
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer abc",
+	} {
+		if req.Header.Get(k) != expected {
+			t.Errorf("req.Header.Get(%q) = %q, expected %q", k, req.Header.Get(k), expected)
+		}
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("reading body: unexpected error: %s", err)
+	}
+	if string(b) != `{"name": "foo"}` {
+		t.Errorf("req.Body = %q, expected %q", b, `{"name": "foo"}`)
+	}
+}
+
+func TestImportFromPostmanFileScopes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestImportFromPostmanFileScopes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"collection.json": `{
+			"info": {"name": "scopes", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+			"variable": [
+				{"key": "host", "value": "collection.example.com"},
+				{"key": "resource", "value": "collection"},
+				{"key": "id", "value": "0"}
+			],
+			"item": [{
+				"name": "folder",
+				"variable": [{"key": "resource", "value": "folder"}],
+				"item": [
+					{"name": "folder request", "request": {"method": "GET", "url": "http://{{host}}/{{resource}}/{{id}}"}},
+					{"name": "object url", "request": {"method": "GET", "url": {"host": ["{{host}}"], "path": ["objects", "{{id}}"]}}},
+					{
+						"name": "request",
+						"variable": [{"key": "resource", "value": "request"}, {"key": "id", "value": "1"}],
+						"request": {"method": "GET", "url": "http://{{host}}/{{resource}}/{{id}}"}
+					}
+				]
+			}]
+		}`,
+	})
+	outDir := filepath.Join(dir, "out")
+	err = os.Mkdir(outDir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = importFromPostmanFile(filepath.Join(dir, "collection.json"), outDir, false, reqFilters{}, map[string]string{"id": "42"})
+	if err != nil {
+		t.Fatalf("importFromPostmanFile: unexpected error: %s", err)
+	}
+
+	var got []string
+	fnames, err := filepath.Glob(filepath.Join(outDir, "*_req.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fname := range fnames {
+		req, err := parseRequest(nil, requestHooks{}, fname)
+		if err != nil {
+			t.Fatalf("reading %q: unexpected error: %s", fname, err)
+		}
+		got = append(got, req.Host+req.URL.Path)
+	}
+	sort.Strings(got)
+	// the innermost scope wins, except for the command-line and environment variables
+	expected := []string{
+		"collection.example.com/folder/42",
+		"collection.example.com/objects/42",
+		"collection.example.com/request/42",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("imported requests = %q, expected %q", got, expected)
+	}
+}
@@ -13,6 +13,9 @@ import (
 	"os"
 	"path"
 	"strings"
+
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/websocket"
 )
 
 func importProxyCmd(args []string) {
@@ -27,14 +30,14 @@ func importProxyCmd(args []string) {
 		log.Fatal(err)
 	}
 
-	err = runProxy(c.Listen, c.Target, c.Dir, c.Graph, c.Verbose, c.Filters)
+	err = runProxy(c.Listen, c.Target, c.Dir, c.Graph, c.Verbose, c.Decompress, c.Filters)
 
 	if err != nil && !errors.Is(err, os.ErrExist) {
 		log.Fatal(err)
 	}
 }
 
-func runProxy(listen, target, outDir string, graph, verbose bool, filters reqFilters) error {
+func runProxy(listen, target, outDir string, graph, verbose, decompress bool, filters reqFilters) error {
 	targetURL, err := url.Parse(target)
 	if err != nil {
 		return err
@@ -42,16 +45,22 @@ func runProxy(listen, target, outDir string, graph, verbose bool, filters reqFil
 
 	srv := &http.Server{
 		Addr:    listen,
-		Handler: proxyHandler(targetURL, outDir, graph, verbose, filters),
+		Handler: proxyHandler(targetURL, outDir, graph, verbose, decompress, filters),
 	}
 
 	log.Printf("listening on %s", listen)
 	return srv.ListenAndServe()
 }
 
-func proxyHandler(target *url.URL, outDir string, graph, verbose bool, filters reqFilters) http.HandlerFunc {
+func proxyHandler(target *url.URL, outDir string, graph, verbose, decompress bool, filters reqFilters) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
+		u := proxiedURL(target, r)
+		if websocket.IsUpgrade(r.Header) {
+			proxyWebSocket(w, r, &u, outDir, verbose, filters)
+			return
+		}
+
 		reqBody, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, "failed to read request body", http.StatusInternalServerError)
@@ -59,18 +68,6 @@ func proxyHandler(target *url.URL, outDir string, graph, verbose bool, filters r
 			return
 		}
 
-		u := *target // copies the URL
-
-		u.Path = path.Join(u.Path, r.URL.Path)
-		q := u.Query()
-		for k, v := range r.URL.Query() {
-			q[k] = v
-		}
-		u.RawQuery = q.Encode()
-		if target.Fragment == "" {
-			u.Fragment = r.URL.Fragment
-		}
-
 		req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(reqBody))
 		if err != nil {
 			http.Error(w, "failed to create proxied request", http.StatusInternalServerError)
@@ -106,12 +103,18 @@ func proxyHandler(target *url.URL, outDir string, graph, verbose bool, filters r
 				w.Header().Add(k, v)
 			}
 		}
+		for k := range resp.Trailer {
+			w.Header().Add("Trailer", k)
+		}
 		w.WriteHeader(resp.StatusCode)
 		_, err = io.Copy(w, bytes.NewReader(respBody))
 		if err != nil {
 			log.Printf("failed to write response: %v", err)
 			return
 		}
+		for k, vv := range resp.Trailer {
+			w.Header()[k] = vv
+		}
 		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
 		if err := filters.Match(req); err != nil {
 			if verbose {
@@ -136,6 +139,11 @@ func proxyHandler(target *url.URL, outDir string, graph, verbose bool, filters r
 		}
 
 		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		if decompress {
+			if err := bacom.DecompressBody(resp); err != nil {
+				log.Printf("failed to decompress response for %q: %v", u.String(), err)
+			}
+		}
 		err = importResp(verbose, reqFname, outDir, name, resp)
 		if err != nil {
 			if verbose {
@@ -148,6 +156,23 @@ func proxyHandler(target *url.URL, outDir string, graph, verbose bool, filters r
 	}
 }
 
+// proxiedURL returns the URL of the request r on target
+func proxiedURL(target *url.URL, r *http.Request) url.URL {
+	u := *target // copies the URL
+
+	u.Path = path.Join(u.Path, r.URL.Path)
+	q := u.Query()
+	for k, v := range r.URL.Query() {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	if target.Fragment == "" {
+		u.Fragment = r.URL.Fragment
+	}
+
+	return u
+}
+
 func getGraphOp(b []byte) (string, error) {
 	var payload struct {
 		OperationName string
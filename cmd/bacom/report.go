@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"gopkg.in/yaml.v2"
+)
+
+type reportFormat string
+
+const (
+	noReport    reportFormat = ""
+	jsonReport  reportFormat = "json"
+	junitReport reportFormat = "junit"
+	tapReport   reportFormat = "tap"
+)
+
+func (f reportFormat) String() string {
+	return string(f)
+}
+
+func (f *reportFormat) Set(s string) error {
+	switch reportFormat(strings.ToLower(s)) {
+	default:
+		return errors.Errorf("unknown report format %q. Supported formats are json, junit and tap", s)
+	case jsonReport, junitReport, tapReport:
+		*f = reportFormat(strings.ToLower(s))
+	}
+
+	return nil
+}
+
+type testReport struct {
+	Pass     bool            `json:"pass"`
+	Duration float64         `json:"duration"`
+	Versions []versionReport `json:"versions"`
+}
+
+type versionReport struct {
+	Version  string          `json:"version"`
+	Dir      string          `json:"dir"`
+	Pass     bool            `json:"pass"`
+	Duration float64         `json:"duration"`
+	Tests    []requestReport `json:"tests"`
+}
+
+type requestReport struct {
+	File        string             `json:"file"`
+	Method      string             `json:"method,omitempty"`
+	Path        string             `json:"path,omitempty"`
+	Pass        bool               `json:"pass"`
+	Duration    float64            `json:"duration"`
+	Differences []bacom.Difference `json:"differences,omitempty"`
+}
+
+func newTestReport(suites []testSuite, duration time.Duration) testReport {
+	report := testReport{
+		Pass:     true,
+		Duration: duration.Seconds(),
+	}
+
+	for _, suite := range suites {
+		vReport := versionReport{
+			Version: filepath.Base(suite.dirname),
+			Dir:     suite.dirname,
+			Pass:    true,
+		}
+		for _, job := range suite.tests {
+			vReport.Tests = append(vReport.Tests, requestReport{
+				File:        job.fname,
+				Method:      job.method,
+				Path:        job.path,
+				Pass:        len(job.differences) == 0,
+				Duration:    job.duration.Seconds(),
+				Differences: job.differences,
+			})
+			vReport.Pass = vReport.Pass && len(job.differences) == 0
+			vReport.Duration += job.duration.Seconds()
+		}
+		report.Versions = append(report.Versions, vReport)
+		report.Pass = report.Pass && vReport.Pass
+	}
+
+	return report
+}
+
+func writeReportFile(format reportFormat, fname string, report testReport) (err error) {
+	f, err := os.Create(fname)
+	if err != nil {
+		return errors.Wrapf(err, "creating report file %q", fname)
+	}
+	defer handleClose(&err, f)
+
+	return errors.Wrapf(writeReport(format, f, report), "writing report to %q", fname)
+}
+
+func writeReport(format reportFormat, w io.Writer, report testReport) error {
+	switch format {
+	default:
+		return errors.Errorf("unknown report format %q", format)
+	case jsonReport:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case junitReport:
+		return writeJUnitReport(w, report)
+	case tapReport:
+		return writeTAPReport(w, report)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, report testReport) error {
+	suites := junitTestSuites{
+		Name: "bacom",
+		Time: report.Duration,
+	}
+
+	for _, v := range report.Versions {
+		suite := junitTestSuite{
+			Name: v.Version,
+			Time: v.Duration,
+		}
+		for _, test := range v.Tests {
+			testCase := junitTestCase{
+				Name:      testName(test),
+				ClassName: v.Version,
+				Time:      test.Duration,
+			}
+			if !test.Pass {
+				testCase.Failure = &junitFailure{
+					Message: fmt.Sprintf("%d backward-compatibility differences", len(test.Differences)),
+					Type:    "BackwardCompatibility",
+					Text:    differencesText(test.Differences),
+				}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, testCase)
+			suite.Tests++
+		}
+		suites.Suites = append(suites.Suites, suite)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(suites)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
+
+func writeTAPReport(w io.Writer, report testReport) error {
+	var n int
+
+	for _, v := range report.Versions {
+		n += len(v.Tests)
+	}
+
+	_, err := fmt.Fprintf(w, "TAP version 13\n1..%d\n", n)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for _, v := range report.Versions {
+		for _, test := range v.Tests {
+			i++
+			status := "ok"
+			if !test.Pass {
+				status = "not ok"
+			}
+			_, err = fmt.Fprintf(w, "%s %d - %s %s\n", status, i, v.Version, testName(test))
+			if err != nil {
+				return err
+			}
+			if test.Pass {
+				continue
+			}
+			err = writeTAPDiagnostic(w, test)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeTAPDiagnostic(w io.Writer, test requestReport) error {
+	b, err := yaml.Marshal(struct {
+		File        string             `yaml:"file"`
+		Duration    float64            `yaml:"duration_s"`
+		Differences []bacom.Difference `yaml:"differences"`
+	}{test.File, test.Duration, test.Differences})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "  ---\n")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(strings.TrimRight(string(b), "\n"), "\n") {
+		_, err = io.WriteString(w, "  "+strings.TrimRight(line, "\n")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "  ...\n")
+
+	return err
+}
+
+func testName(test requestReport) string {
+	name := filepath.Base(test.File)
+	if test.Method == "" {
+		return name
+	}
+
+	return name + " (" + test.Method + " " + test.Path + ")"
+}
+
+func differencesText(diffs []bacom.Difference) string {
+	lines := make([]string, 0, len(diffs))
+
+	for _, d := range diffs {
+		lines = append(lines, d.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yazgazan/bacom"
+)
+
+func TestReportFormat(t *testing.T) {
+	for _, test := range []struct {
+		s           string
+		expected    reportFormat
+		expectError bool
+	}{
+		{"json", jsonReport, false},
+		{"JUnit", junitReport, false},
+		{"tap", tapReport, false},
+		{"xml", noReport, true},
+		{"", noReport, true},
+	} {
+		var f reportFormat
+
+		err := f.Set(test.s)
+		if err != nil && !test.expectError {
+			t.Errorf("reportFormat.Set(%q): unexpected error: %s", test.s, err)
+		}
+		if err == nil && test.expectError {
+			t.Errorf("reportFormat.Set(%q): expected error, got nil", test.s)
+		}
+		if f != test.expected {
+			t.Errorf("reportFormat.Set(%q) = %q, expected %q", test.s, f, test.expected)
+		}
+	}
+}
+
+func testReportSuites() []testSuite {
+	newJob := func(fname, method, path string, diffs ...bacom.Difference) *testJob {
+		return &testJob{
+			fname:       fname,
+			method:      method,
+			path:        path,
+			differences: diffs,
+			duration:    10 * time.Millisecond,
+		}
+	}
+
+	return []testSuite{
+		{
+			dirname: "tests/v1.0.0",
+			tests: []*testJob{
+				newJob("tests/v1.0.0/users_req.txt", "GET", "/users"),
+				newJob("tests/v1.0.0/user_req.txt", "GET", "/users/1",
+					bacom.Difference{Kind: bacom.ContentDifference, Path: ".name", Expected: "<Jane & co>", Actual: "Jane"},
+				),
+			},
+		},
+		{
+			dirname: "tests/v1.1.0",
+			tests: []*testJob{
+				newJob("tests/v1.1.0/orders_req.txt", "POST", "/orders",
+					bacom.Difference{Kind: bacom.StatusDifference, Expected: 201, Actual: 500},
+					bacom.Difference{Kind: bacom.MissingKeyDifference, Path: ".id"},
+				),
+			},
+		},
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := writeReport(jsonReport, buf, newTestReport(testReportSuites(), time.Second))
+	if err != nil {
+		t.Fatalf("writeReport(json): unexpected error: %s", err)
+	}
+
+	var report testReport
+	err = json.Unmarshal(buf.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("writeReport(json): invalid output: %s\n%s", err, buf)
+	}
+	if report.Pass || report.Duration != 1 || len(report.Versions) != 2 {
+		t.Fatalf("writeReport(json) = %+v, expected 2 failing versions lasting 1s", report)
+	}
+	for i, expected := range []struct {
+		version string
+		passes  []bool
+	}{
+		{"v1.0.0", []bool{true, false}},
+		{"v1.1.0", []bool{false}},
+	} {
+		v := report.Versions[i]
+		if v.Version != expected.version || v.Pass || len(v.Tests) != len(expected.passes) {
+			t.Errorf("writeReport(json): version %d = %+v, expected %d tests for failing %s", i, v, len(expected.passes), expected.version)
+			continue
+		}
+		for j, pass := range expected.passes {
+			if v.Tests[j].Pass != pass || (len(v.Tests[j].Differences) == 0) != pass {
+				t.Errorf("writeReport(json): %s test %d = %+v, expected pass = %t", v.Version, j, v.Tests[j], pass)
+			}
+		}
+	}
+	if diffs := report.Versions[1].Tests[0].Differences; len(diffs) != 2 || diffs[1].Kind != bacom.MissingKeyDifference {
+		t.Errorf("writeReport(json): differences = %+v, expected the status and missing_key differences", diffs)
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := writeReport(junitReport, buf, newTestReport(testReportSuites(), time.Second))
+	if err != nil {
+		t.Fatalf("writeReport(junit): unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("writeReport(junit): missing XML header in\n%s", buf)
+	}
+
+	var suites junitTestSuites
+	err = xml.Unmarshal(buf.Bytes(), &suites)
+	if err != nil {
+		t.Fatalf("writeReport(junit): invalid output: %s\n%s", err, buf)
+	}
+	if suites.Tests != 3 || suites.Failures != 2 || len(suites.Suites) != 2 {
+		t.Fatalf("writeReport(junit) = %d tests, %d failures in %d suites, expected 3 tests, 2 failures in 2 suites",
+			suites.Tests, suites.Failures, len(suites.Suites))
+	}
+	for i, expected := range []struct {
+		name     string
+		tests    int
+		failures int
+	}{
+		{"v1.0.0", 2, 1},
+		{"v1.1.0", 1, 1},
+	} {
+		suite := suites.Suites[i]
+		if suite.Name != expected.name || suite.Tests != expected.tests || suite.Failures != expected.failures {
+			t.Errorf("writeReport(junit): suite %d = %s (%d tests, %d failures), expected %s (%d tests, %d failures)",
+				i, suite.Name, suite.Tests, suite.Failures, expected.name, expected.tests, expected.failures)
+		}
+	}
+	// passing tests are neither failed nor skipped
+	if strings.Contains(buf.String(), "<skipped") {
+		t.Errorf("writeReport(junit): unexpected skipped test in\n%s", buf)
+	}
+	if c := suites.Suites[0].Cases[0]; c.Name != "users_req.txt (GET /users)" || c.Failure != nil {
+		t.Errorf("writeReport(junit): test case = %+v, expected users_req.txt (GET /users) to pass", c)
+	}
+
+	failure := suites.Suites[0].Cases[1].Failure
+	if failure == nil || failure.Message != "1 backward-compatibility differences" {
+		t.Fatalf("writeReport(junit): failure = %+v, expected a single difference", failure)
+	}
+	// the diff messages are escaped, and decoded back to the original text
+	if strings.Contains(buf.String(), "<Jane & co>") || !strings.Contains(buf.String(), "&lt;Jane &amp; co&gt;") {
+		t.Errorf("writeReport(junit): expected the differences to be escaped in\n%s", buf)
+	}
+	if !strings.Contains(failure.Text, "<Jane & co>") {
+		t.Errorf("writeReport(junit): failure text = %q, expected it to hold the difference", failure.Text)
+	}
+}
+
+func TestWriteTAPReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := writeReport(tapReport, buf, newTestReport(testReportSuites(), time.Second))
+	if err != nil {
+		t.Fatalf("writeReport(tap): unexpected error: %s", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 2 || lines[0] != "TAP version 13" || lines[1] != "1..3" {
+		t.Fatalf("writeReport(tap): expected the version and plan lines, got\n%s", buf)
+	}
+	var results []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "ok ") || strings.HasPrefix(line, "not ok ") {
+			results = append(results, line)
+		}
+	}
+	expected := []string{
+		"ok 1 - v1.0.0 users_req.txt (GET /users)",
+		"not ok 2 - v1.0.0 user_req.txt (GET /users/1)",
+		"not ok 3 - v1.1.0 orders_req.txt (POST /orders)",
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("writeReport(tap): results = %q, expected %q", results, expected)
+	}
+	if strings.Contains(buf.String(), "# SKIP") {
+		t.Errorf("writeReport(tap): unexpected skipped test in\n%s", buf)
+	}
+	if n := strings.Count(buf.String(), "  ---\n"); n != 2 || strings.Count(buf.String(), "  ...\n") != 2 {
+		t.Errorf("writeReport(tap): expected a diagnostic block for each of the 2 failures, got %d in\n%s", n, buf)
+	}
+	if !strings.Contains(buf.String(), "  - kind: missing_key\n") {
+		t.Errorf("writeReport(tap): expected the differences in the diagnostics of\n%s", buf)
+	}
+}
+
+func TestReportFlags(t *testing.T) {
+	for _, test := range []struct {
+		args        []string
+		expectError bool
+	}{
+		{[]string{"-report-format", "junit", "-report-file", "report.xml"}, false},
+		{[]string{"-report-format", "junit"}, true},
+		{[]string{"-report-file", "report.xml"}, true},
+	} {
+		_, err := parseTestFlags(append([]string{"-conf", "no-such-file.json"}, test.args...))
+		if err != nil && !test.expectError {
+			t.Errorf("parseTestFlags(%q): unexpected error: %s", test.args, err)
+		}
+		if test.expectError && (err == nil || !strings.Contains(err.Error(), "-report-format and -report-file")) {
+			t.Errorf("parseTestFlags(%q): expected error, got %v", test.args, err)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/jaydiff/jpath"
+	"gopkg.in/yaml.v2"
+)
+
+// scenarioSuffix is the suffix of scenario files, stored alongside the request files of a version
+const scenarioSuffix = "_scenario.yaml"
+
+// scenarioSaveMu serializes the file name allocation done by saveScenario
+var scenarioSaveMu sync.Mutex
+
+// scenario is a sequence of request files of a version, run in order by bacom test. The values extracted from the
+// responses of a step are available as {{variables}} to the requests of the following steps.
+type scenario struct {
+	fname string
+
+	Steps []scenarioStep `json:"steps" yaml:"steps"`
+}
+
+// scenarioStep is a request file of a scenario (relative to the scenario file) and the values to extract from its
+// response, by variable name
+type scenarioStep struct {
+	Request string                `json:"request" yaml:"request"`
+	Extract map[string]extraction `json:"extract,omitempty" yaml:"extract,omitempty"`
+}
+
+// extraction captures a value from a response, using a JSON path in the body (`.data.items[0].id`),
+// a header or a cookie set by the response
+type extraction struct {
+	JSON   string `json:"json,omitempty" yaml:"json,omitempty"`
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	Cookie string `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+}
+
+func (e extraction) String() string {
+	switch {
+	case e.Header != "":
+		return "header " + e.Header
+	case e.Cookie != "":
+		return "cookie " + e.Cookie
+	}
+
+	return "JSON path " + e.JSON
+}
+
+func (e extraction) validate() error {
+	n := 0
+	for _, s := range []string{e.JSON, e.Header, e.Cookie} {
+		if s != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("exactly one of json, header or cookie is expected")
+	}
+	if e.JSON != "" && e.JSON[0] != '.' && e.JSON[0] != '[' {
+		return errors.Errorf("invalid JSON path %q", e.JSON)
+	}
+
+	return nil
+}
+
+// readScenarios reads the scenario files of a version directory
+func readScenarios(dirname string) ([]scenario, error) {
+	fis, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "finding scenarios in %q", dirname)
+	}
+
+	var scenarios []scenario
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), scenarioSuffix) {
+			continue
+		}
+		s, err := readScenario(filepath.Join(dirname, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, s)
+	}
+
+	return scenarios, nil
+}
+
+// readScenario reads and validates the scenario file fname. The request files of the steps are returned
+// relative to the working directory.
+func readScenario(fname string) (s scenario, err error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return s, errors.Wrapf(err, "reading scenario %q", fname)
+	}
+	defer handleClose(&err, f)
+
+	err = yaml.NewDecoder(f).Decode(&s)
+	if err != nil {
+		return s, errors.Wrapf(err, "reading scenario %q", fname)
+	}
+	s.fname = fname
+	if len(s.Steps) == 0 {
+		return s, errors.Errorf("reading scenario %q: no steps", fname)
+	}
+
+	for i, step := range s.Steps {
+		if !bacom.IsRequestFilename(step.Request) {
+			return s, errors.Errorf("reading scenario %q: step %d: invalid request file name %q", fname, i+1, step.Request)
+		}
+		s.Steps[i].Request = filepath.Join(filepath.Dir(fname), step.Request)
+		if _, err = os.Stat(s.Steps[i].Request); err != nil {
+			return s, errors.Wrapf(err, "reading scenario %q: step %d", fname, i+1)
+		}
+		for name, e := range step.Extract {
+			if err = e.validate(); err != nil {
+				return s, errors.Wrapf(err, "reading scenario %q: step %d: extracting %q", fname, i+1, name)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// matches returns true if the scenario file or one of its request files is listed in fnames (see reqFilenameMatches)
+func (s scenario) matches(fnames []string) bool {
+	if reqFilenameMatches(fnames, s.fname) {
+		return true
+	}
+	for _, step := range s.Steps {
+		if reqFilenameMatches(fnames, step.Request) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scenarioVars holds the values extracted by the steps of a scenario so far. As the base can be a live host, the
+// values are extracted from the target and base responses separately.
+type scenarioVars struct {
+	target, base templateVars
+}
+
+func newScenarioVars() *scenarioVars {
+	return &scenarioVars{
+		target: templateVars{},
+		base:   templateVars{},
+	}
+}
+
+// extract captures the values of extract from the target and base responses (if any). Values that can't be found
+// are reported as differences.
+func (v *scenarioVars) extract(extract map[string]extraction, target, base *http.Response) ([]bacom.Difference, error) {
+	diffs, err := extractValues(v.target, extract, "target", target)
+	if err != nil || base == nil {
+		return diffs, err
+	}
+	baseDiffs, err := extractValues(v.base, extract, "base", base)
+
+	return append(diffs, baseDiffs...), err
+}
+
+// extractValues sets the values of extract found in resp to vars. The body of resp is replaced by a copy.
+func extractValues(vars templateVars, extract map[string]extraction, side string, resp *http.Response) ([]bacom.Difference, error) {
+	names := make([]string, 0, len(extract))
+	for name := range extract {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		diffs []bacom.Difference
+		body  interface{}
+		read  bool
+	)
+	for _, name := range names {
+		e := extract[name]
+		var (
+			v  string
+			ok bool
+		)
+		switch {
+		case e.Header != "":
+			v, ok = resp.Header.Get(e.Header), len(resp.Header[http.CanonicalHeaderKey(e.Header)]) != 0
+		case e.Cookie != "":
+			for _, c := range resp.Cookies() {
+				if c.Name == e.Cookie {
+					v, ok = c.Value, true
+				}
+			}
+		default:
+			if !read {
+				var err error
+				body, err = readExtractionBody(resp)
+				if err != nil {
+					return diffs, err
+				}
+				read = true
+			}
+			v, ok = extractJSON(body, e.JSON)
+		}
+
+		if !ok {
+			diffs = append(diffs, bacom.Difference{
+				Kind:    bacom.ExtractionDifference,
+				Path:    e.JSON,
+				Message: fmt.Sprintf("{{%s}}: %s not found in the %s response", name, e, side),
+			})
+			continue
+		}
+		vars[name] = v
+	}
+
+	return diffs, nil
+}
+
+// readExtractionBody decodes the JSON body of resp, leaving a copy of the (still compressed) body in its place
+func readExtractionBody(resp *http.Response) (body interface{}, err error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response body")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	decoded := *resp
+	decoded.Header = resp.Header.Clone()
+	decoded.Body = ioutil.NopCloser(bytes.NewReader(b))
+	err = bacom.DecompressBody(&decoded)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(decoded.Body)
+	dec.UseNumber()
+	if dec.Decode(&body) != nil {
+		// bodies that aren't JSON documents have no values to extract
+		return nil, nil
+	}
+
+	return body, nil
+}
+
+// extractJSON returns the value at path in body, formatted as a string. Objects and arrays are formatted as JSON.
+func extractJSON(body interface{}, path string) (string, bool) {
+	if body == nil {
+		return "", false
+	}
+	v, ok := lookupJSON(body, path)
+	if !ok || v == nil {
+		return "", false
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	b, err := json.Marshal(v)
+
+	return string(b), err == nil
+}
+
+// lookupJSON returns the value at path in v. Keys can be quoted (`."foo bar"`), and array elements are
+// referenced by index (`.items[0]`).
+func lookupJSON(v interface{}, path string) (interface{}, bool) {
+	for path != "" {
+		var head string
+		head, path = jpath.Split(path)
+
+		switch parent := v.(type) {
+		case map[string]interface{}:
+			if head[0] != '.' {
+				return nil, false
+			}
+			key := head[1:]
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			}
+			var ok bool
+			if v, ok = parent[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if head[0] != '[' || head[len(head)-1] != ']' {
+				return nil, false
+			}
+			i, err := strconv.Atoi(head[1 : len(head)-1])
+			if err != nil || i < 0 || i >= len(parent) {
+				return nil, false
+			}
+			v = parent[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// saveScenario saves the scenario run by jobs to dir, referencing the request files saved by its steps (see
+// bacom.Saver.RequestName). The file name is adjusted if a different scenario with the same name already exists in dir.
+func saveScenario(dir string, jobs []*testJob) (err error) {
+	s := jobs[0].scenario
+	out := scenario{Steps: make([]scenarioStep, len(jobs))}
+	for i, job := range jobs {
+		out.Steps[i] = scenarioStep{Request: job.savedAs, Extract: job.extract}
+	}
+	b, err := yaml.Marshal(out)
+	if err != nil {
+		return errors.Wrapf(err, "saving scenario %q", s.fname)
+	}
+
+	scenarioSaveMu.Lock()
+	defer scenarioSaveMu.Unlock()
+
+	name := strings.TrimSuffix(filepath.Base(s.fname), scenarioSuffix)
+	dst := filepath.Join(dir, name+scenarioSuffix)
+	for i := 1; ; i++ {
+		existing, errRead := ioutil.ReadFile(dst)
+		if os.IsNotExist(errRead) {
+			break
+		}
+		if errRead != nil {
+			return errors.Wrapf(errRead, "saving scenario %q", s.fname)
+		}
+		if bytes.Equal(existing, b) {
+			return nil
+		}
+		dst = filepath.Join(dir, name+strconv.Itoa(i)+scenarioSuffix)
+	}
+	err = ioutil.WriteFile(dst, b, 0600)
+
+	return errors.Wrapf(err, "saving scenario %q", s.fname)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yazgazan/bacom"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for fname, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, fname), []byte(content), 0640)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadScenario(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestReadScenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestFiles(t, dir, map[string]string{
+		"create_req.txt": "POST /users HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"get_req.txt":    "GET /users/{{id}} HTTP/1.1\r\nHost: localhost\r\n\r\n",
+	})
+
+	for _, test := range []struct {
+		name, content string
+		expected      []scenarioStep
+		err           string
+	}{
+		{
+			name: "valid",
+			content: "steps:\n" +
+				"  - request: create_req.txt\n" +
+				"    extract:\n" +
+				"      id: {json: .data.id}\n" +
+				"      session: {cookie: session}\n" +
+				"  - request: get_req.txt\n",
+			expected: []scenarioStep{
+				{
+					Request: filepath.Join(dir, "create_req.txt"),
+					Extract: map[string]extraction{"id": {JSON: ".data.id"}, "session": {Cookie: "session"}},
+				},
+				{Request: filepath.Join(dir, "get_req.txt")},
+			},
+		},
+		{name: "no steps", content: "steps: []\n", err: "no steps"},
+		{name: "invalid name", content: "steps:\n  - request: create.txt\n", err: "invalid request file name"},
+		{name: "missing request", content: "steps:\n  - request: delete_req.txt\n", err: "no such file"},
+		{
+			name:    "two sources",
+			content: "steps:\n  - request: create_req.txt\n    extract:\n      id: {json: .id, header: X-Id}\n",
+			err:     "exactly one of json, header or cookie",
+		},
+		{
+			name:    "invalid path",
+			content: "steps:\n  - request: create_req.txt\n    extract:\n      id: {json: id}\n",
+			err:     "invalid JSON path",
+		},
+	} {
+		fname := filepath.Join(dir, "test"+scenarioSuffix)
+		writeTestFiles(t, dir, map[string]string{"test" + scenarioSuffix: test.content})
+
+		s, err := readScenario(fname)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: readScenario(...): expected error containing %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: readScenario(...): unexpected error: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(s.Steps, test.expected) {
+			t.Errorf("%s: readScenario(...) = %+v, expected %+v", test.name, s.Steps, test.expected)
+		}
+	}
+}
+
+func TestExtractValues(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{
+			"Location":   {"/users/42"},
+			"Set-Cookie": {"session=abc123; Path=/; HttpOnly"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(
+			`{"data": {"id": 12345678901234567890, "name": "Jane", "admin": false, "tags": ["a", "b"]}}`,
+		)),
+	}
+	extract := map[string]extraction{
+		"id":       {JSON: ".data.id"},
+		"name":     {JSON: ".data.name"},
+		"admin":    {JSON: ".data.admin"},
+		"tags":     {JSON: ".data.tags"},
+		"tag":      {JSON: ".data.tags[1]"},
+		"location": {Header: "location"},
+		"session":  {Cookie: "session"},
+		"missing":  {JSON: ".data.email"},
+		"token":    {Header: "X-Token"},
+	}
+
+	vars := templateVars{}
+	diffs, err := extractValues(vars, extract, "target", resp)
+	if err != nil {
+		t.Fatalf("extractValues: unexpected error: %s", err)
+	}
+
+	expected := templateVars{
+		"id":       "12345678901234567890",
+		"name":     "Jane",
+		"admin":    "false",
+		"tags":     `["a","b"]`,
+		"tag":      "b",
+		"location": "/users/42",
+		"session":  "abc123",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("extractValues(...) = %v, expected %v", vars, expected)
+	}
+	expectedDiffs := []bacom.Difference{
+		{
+			Kind:    bacom.ExtractionDifference,
+			Path:    ".data.email",
+			Message: "{{missing}}: JSON path .data.email not found in the target response",
+		},
+		{Kind: bacom.ExtractionDifference, Message: "{{token}}: header X-Token not found in the target response"},
+	}
+	if !reflect.DeepEqual(diffs, expectedDiffs) {
+		t.Errorf("extractValues(...): differences = %+v, expected %+v", diffs, expectedDiffs)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil || !strings.HasPrefix(string(b), `{"data"`) {
+		t.Errorf("extractValues(...): body = %q, %v, expected the original body", b, err)
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 43}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/users/43":
+			_, _ = w.Write([]byte(`{"id": 43, "name": "Jane"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "TestRunScenario")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	versionDir := filepath.Join(dir, "v1.0.0")
+	err = os.Mkdir(versionDir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, versionDir, map[string]string{
+		"create_req.txt": "POST /users HTTP/1.1\r\nHost: localhost\r\nContent-Length: 15\r\n\r\n" +
+			`{"name":"Jane"}`,
+		"create_resp.txt": "HTTP/1.1 201 Created\r\nContent-Type: application/json\r\nContent-Length: 10\r\n\r\n" +
+			`{"id": 12}`,
+		"get_req.txt": "GET /users/{{user_id}} HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		"get_resp.txt": "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 26\r\n\r\n" +
+			`{"id": 12, "name": "Jane"}`,
+		"users" + scenarioSuffix: "steps:\n" +
+			"  - request: create_req.txt\n" +
+			"    extract:\n" +
+			"      user_id: {json: .id}\n" +
+			"  - request: get_req.txt\n",
+	})
+
+	conf := testConf{
+		Dir:      dir,
+		Parallel: 1,
+		Save:     "v2.0.0",
+		Target:   targetConf{Host: strings.TrimPrefix(srv.URL, "http://")},
+		Vars:     requestVars{Root: dir},
+	}
+	err = os.Mkdir(filepath.Join(dir, conf.Save), 0750)
+	if err == nil {
+		err = conf.Vars.load()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	suites, err := collectTests(conf, []string{versionDir})
+	if err != nil {
+		t.Fatalf("collectTests: unexpected error: %s", err)
+	}
+	if len(suites) != 1 || len(suites[0].runs) != 1 || len(suites[0].tests) != 2 {
+		t.Fatalf("collectTests(...) = %+v, expected a single scenario with 2 steps", suites)
+	}
+
+	runJobs(conf, suites[0].runs[0])
+	for _, job := range suites[0].tests {
+		if job.err != nil {
+			t.Errorf("%s: unexpected error: %s", job.fname, job.err)
+		}
+		if len(job.differences) != 0 {
+			t.Errorf("%s: unexpected differences: %+v", job.fname, job.differences)
+		}
+	}
+	if expected := []string{"POST /users", "GET /users/43"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("requests = %q, expected %q", paths, expected)
+	}
+
+	saved, err := readScenario(filepath.Join(dir, conf.Save, "users"+scenarioSuffix))
+	if err != nil {
+		t.Fatalf("reading saved scenario: unexpected error: %s", err)
+	}
+	if len(saved.Steps) != 2 || saved.Steps[1].Request != filepath.Join(dir, conf.Save, "get_req.txt") {
+		t.Errorf("saved scenario = %+v, expected the saved request files", saved)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+func schemaCmd(args []string) {
+	c, err := parseSchemaFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+
+	versions, err := bacom.FindVersions(c.Dir, c.Verbose, c.Constraints)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	schema, err := inferSchema(c, versions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = writeSchema(c.Out, schema)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// endpointSchemas is the document produced by the schema command. Each endpoint's
+// schema is stored in the definitions, keyed by "METHOD /path".
+type endpointSchemas struct {
+	Schema      string                   `json:"$schema"`
+	Title       string                   `json:"title,omitempty"`
+	Definitions map[string]*bacom.Schema `json:"definitions"`
+}
+
+func inferSchema(conf schemaConf, versions []string) (endpointSchemas, error) {
+	builders := map[string]*bacom.SchemaBuilder{}
+
+	for _, dirname := range versions {
+		reqFiles, err := bacom.GetRequestsFiles(dirname)
+		if err != nil {
+			return endpointSchemas{}, errors.Wrapf(err, "looking for requests files in %q", dirname)
+		}
+
+		for _, fname := range reqFiles {
+			err = addSchemaSample(conf, builders, filepath.Base(dirname), fname)
+			if err != nil {
+				return endpointSchemas{}, err
+			}
+		}
+	}
+
+	schemas := endpointSchemas{
+		Schema:      bacom.SchemaDraft,
+		Title:       strings.Join(versions, ", "),
+		Definitions: make(map[string]*bacom.Schema, len(builders)),
+	}
+	for endpoint, b := range builders {
+		schemas.Definitions[endpoint] = b.Schema()
+	}
+
+	return schemas, nil
+}
+
+func addSchemaSample(conf schemaConf, builders map[string]*bacom.SchemaBuilder, version, fname string) (err error) {
+	req, err := parseRequest(nil, requestHooks{}, fname)
+	if err != nil {
+		return err
+	}
+	resp, err := bacom.ReadResponse(req, fname)
+	if os.IsNotExist(err) {
+		if conf.Verbose {
+			fmt.Fprintf(os.Stderr, "skipping %q: missing response\n", fname)
+		}
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "reading response for %q", fname)
+	}
+	defer handleClose(&err, resp.Body)
+
+	pConf := getPathConf(conf.Verbose, conf.Paths, version, req.Method, req.URL.Path)
+	body, err := readBody(resp, pConf.Body)
+	if err != nil {
+		if conf.Verbose {
+			fmt.Fprintf(os.Stderr, "skipping %q: %s\n", fname, err)
+		}
+		return nil
+	}
+	if body == nil {
+		return nil
+	}
+	body = unwrapBody(body)
+
+	endpoint := req.Method + " " + req.URL.Path
+	b, ok := builders[endpoint]
+	if !ok {
+		b = bacom.NewSchemaBuilder(pConf.JSON.Ignore, pConf.JSON.IgnoreMissing)
+		builders[endpoint] = b
+	}
+	b.Add(body)
+
+	return nil
+}
+
+func writeSchema(fname string, schema endpointSchemas) (err error) {
+	var w io.Writer = os.Stdout
+
+	if fname != "" {
+		var f *os.File
+
+		f, err = os.Create(fname)
+		if err != nil {
+			return err
+		}
+		defer handleClose(&err, f)
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(schema)
+}
@@ -3,19 +3,19 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"time"
+	"unicode/utf8"
 
-	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/websocket"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -32,6 +32,20 @@ func testCmd(args []string) {
 		os.Exit(1)
 	}
 
+	if hasEnums(c.Paths) {
+		// enum values are collected from all the stored versions
+		allVersions, err := bacom.FindVersions(c.Dir, c.Verbose, defaultConstraints)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		c.Enums, err = collectEnums(c.Verbose, c.Paths, nil, allVersions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	if c.Save != "" {
 		if err = os.MkdirAll(filepath.Join(c.Dir, c.Save), 0700); err != nil {
 			fmt.Fprintln(os.Stderr, "Error:", err)
@@ -39,17 +53,32 @@ func testCmd(args []string) {
 		}
 	}
 
+	suites, err := collectTests(c, versions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	start := time.Now()
+	go runTests(c, suites)
+
 	failed := false
-	for _, dirname := range versions {
-		pass, err := runTestsForVersion(c, dirname)
+	for _, suite := range suites {
+		pass, err := printSuite(c, suite)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "Error:", err)
 			os.Exit(1)
 		}
-		printPass(pass, c.Quiet, dirname)
 		failed = failed || !pass
 	}
 
+	if c.ReportFormat != noReport {
+		err = writeReportFile(c.ReportFormat, c.ReportFile, newTestReport(suites, time.Since(start)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	if failed {
 		os.Exit(1)
 	}
@@ -64,24 +93,154 @@ func printPass(pass, quiet bool, fname string) {
 	}
 }
 
-func runTestsForVersion(conf testConf, dirname string) (bool, error) {
-	reqFiles, err := bacom.GetRequestsFiles(dirname)
-	if err != nil {
-		return false, errors.Wrapf(err, "looking for requests files in %q", dirname)
+// testSuite groups the tests of a single version directory
+type testSuite struct {
+	dirname string
+	tests   []*testJob
+	// runs groups the tests run in order by the same worker: the steps of a scenario, or a single request file
+	runs [][]*testJob
+}
+
+// testJob holds the outcome of a single request file. done is closed once
+// the test has run, after which the other fields can be read.
+type testJob struct {
+	version string
+	fname   string
+	// scenario and extract are set for the steps of a scenario
+	scenario *scenario
+	extract  map[string]extraction
+	savedAs  string
+
+	method      string
+	path        string
+	differences []bacom.Difference
+	duration    time.Duration
+	dump        []byte
+	err         error
+	done        chan struct{}
+}
+
+func collectTests(conf testConf, versions []string) ([]testSuite, error) {
+	suites := make([]testSuite, 0, len(versions))
+
+	for _, dirname := range versions {
+		reqFiles, err := bacom.GetRequestsFiles(dirname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looking for requests files in %q", dirname)
+		}
+
+		scenarios, err := readScenarios(dirname)
+		if err != nil {
+			return nil, err
+		}
+		// request files used by scenarios depend on the previous steps, and are only run as part of them
+		inScenario := map[string]bool{}
+		for _, s := range scenarios {
+			for _, step := range s.Steps {
+				inScenario[step.Request] = true
+			}
+		}
+
+		suite := testSuite{dirname: dirname}
+		for _, fname := range reqFiles {
+			if inScenario[fname] || !reqFilenameMatches(conf.TestFiles, fname) {
+				continue
+			}
+			job := &testJob{
+				version: filepath.Base(dirname),
+				fname:   fname,
+				done:    make(chan struct{}),
+			}
+			suite.tests = append(suite.tests, job)
+			suite.runs = append(suite.runs, []*testJob{job})
+		}
+		for i := range scenarios {
+			s := &scenarios[i]
+			if !s.matches(conf.TestFiles) {
+				continue
+			}
+			run := make([]*testJob, 0, len(s.Steps))
+			for _, step := range s.Steps {
+				run = append(run, &testJob{
+					version:  filepath.Base(dirname),
+					fname:    step.Request,
+					scenario: s,
+					extract:  step.Extract,
+					done:     make(chan struct{}),
+				})
+			}
+			suite.tests = append(suite.tests, run...)
+			suite.runs = append(suite.runs, run)
+		}
+		suites = append(suites, suite)
 	}
 
+	return suites, nil
+}
+
+// runTests runs all the tests using conf.Parallel workers.
+// Results are reported through each testJob.
+func runTests(conf testConf, suites []testSuite) {
+	queue := make(chan []*testJob)
+
+	for i := 0; i < conf.Parallel; i++ {
+		go func() {
+			for jobs := range queue {
+				runJobs(conf, jobs)
+			}
+		}()
+	}
+
+	for _, suite := range suites {
+		for _, jobs := range suite.runs {
+			queue <- jobs
+		}
+	}
+	close(queue)
+}
+
+// runJobs runs jobs in order. The steps of a scenario share the values extracted from their responses, and the
+// scenario is saved along with its last step when using -save.
+func runJobs(conf testConf, jobs []*testJob) {
+	var vars *scenarioVars
+	if jobs[0].scenario != nil {
+		vars = newScenarioVars()
+	}
+
+	for i, job := range jobs {
+		start := time.Now()
+		job.err = runTest(conf, job, vars)
+		if job.err == nil && vars != nil && conf.Save != "" && i == len(jobs)-1 {
+			job.err = saveScenario(filepath.Join(conf.Dir, conf.Save), jobs)
+		}
+		job.duration = time.Since(start)
+		close(job.done)
+	}
+}
+
+// printSuite waits for the tests in suite to complete and prints their results,
+// in the order they were collected.
+func printSuite(conf testConf, suite testSuite) (bool, error) {
 	passed := true
-	for _, fname := range reqFiles {
-		if !reqFilenameMatches(conf.TestFiles, fname) {
-			continue
+	for _, job := range suite.tests {
+		<-job.done
+		if job.err != nil {
+			return false, job.err
 		}
-		ok, err := runTest(conf, filepath.Base(dirname), fname)
+		_, err := os.Stdout.Write(job.dump)
 		if err != nil {
 			return false, err
 		}
-		printPass(ok, conf.Quiet, fname)
+
+		ok := len(job.differences) == 0
+		err = printResults(job.fname, job.differences)
+		if err != nil {
+			return false, err
+		}
+		printPass(ok, conf.Quiet, job.fname)
 		passed = passed && ok
 	}
+	printPass(passed, conf.Quiet, suite.dirname)
 
 	return passed, nil
 }
@@ -101,74 +260,116 @@ func reqFilenameMatches(fnames []string, fpath string) bool {
 	return false
 }
 
-func compareStatuses(lhsCode, rhsCode int, lhs, rhs string) []string {
-	if lhsCode == rhsCode {
-		return nil
-	}
-
-	return []string{
-		"- (Status) " + color.RedString(lhs),
-		"+ (Status) " + color.GreenString(rhs),
-	}
-}
-
 func compareResponses(
 	conf testConf,
 	version string,
 	reqPath, reqMethod,
 	fname string,
 	baseResp, targetResp *http.Response,
-) (results []string, err error) {
+	dump io.Writer,
+) (diffs []bacom.Difference, err error) {
 	pConf := getPathConf(conf.Verbose, conf.Paths, version, reqMethod, reqPath)
 
-	targetBody, err := readBody(targetResp)
+	targetBody, err := readBody(targetResp, pConf.Body)
 	if err != nil {
 		return nil, errors.Wrapf(err, "reading target response body")
 	}
-	baseBody, err := readBody(baseResp)
+	var violations []bacom.Difference
+	if conf.OpenAPI != nil {
+		violations = conf.OpenAPI.Validate(reqMethod, reqPath, targetResp, targetBody)
+	}
+	if baseResp == nil {
+		graphQLDiffs, err := graphQLDifferences(conf.GraphQL, fname, nil, targetBody)
+		if err != nil {
+			return violations, err
+		}
+		grpcDiffs, err := grpcDifferences(conf.GRPCDescriptors, fname)
+		if err != nil {
+			return violations, err
+		}
+		validatorDiffs, err := runValidators(pConf.Validators, reqMethod, reqPath, version, nil, targetBody)
+
+		return append(append(append(violations, graphQLDiffs...), grpcDiffs...), validatorDiffs...), err
+	}
+
+	baseBody, err := readBody(baseResp, pConf.Body)
 	if err != nil {
 		return nil, errors.Wrapf(err, "reading base response body")
 	}
 
-	results, err = bacom.CompareHeaders(
+	headerDiffs, err := bacom.HeaderDifferences(
 		pConf.Headers.Ignore,
 		pConf.Headers.IgnoreContent,
 		baseResp.Header,
 		targetResp.Header,
 	)
 	if err != nil {
-		return results, errors.Wrapf(err, "comparing headers for %q", fname)
+		return nil, errors.Wrapf(err, "comparing headers for %q", fname)
 	}
 
-	results = append(compareStatuses(
+	diffs = append(bacom.StatusDifferences(
 		baseResp.StatusCode, targetResp.StatusCode,
 		baseResp.Status, targetResp.Status,
-	), results...)
-
-	bodyResults, err := bacom.Compare(
-		pConf.JSON.Ignore,
-		pConf.JSON.IgnoreMissing,
-		pConf.JSON.IgnoreNull,
-		baseBody,
-		targetBody,
+	), headerDiffs...)
+
+	bodyDiffs, err := compareBodies(pConf, baseBody, targetBody)
+	if err != nil {
+		return diffs, errors.Wrapf(err, "comparing bodies")
+	}
+
+	diffs = append(diffs, bodyDiffs...)
+	endpoint := enumEndpoint(conf.Verbose, conf.Paths, version, reqMethod, reqPath)
+	if enums, ok := conf.Enums[endpoint]; ok && len(pConf.JSON.Enums) != 0 {
+		diffs = append(diffs, enums.Unseen(unwrapBody(targetBody))...)
+	}
+
+	// trailers are only known once the bodies have been read entirely
+	err = drainBodies(baseResp, targetResp)
+	if err != nil {
+		return diffs, errors.Wrapf(err, "reading response bodies")
+	}
+	trailerDiffs, err := bacom.TrailerDifferences(
+		pConf.Trailers.Ignore,
+		pConf.Trailers.IgnoreContent,
+		baseResp.Trailer,
+		targetResp.Trailer,
 	)
 	if err != nil {
-		return results, errors.Wrapf(err, "comparing bodies")
+		return diffs, errors.Wrapf(err, "comparing trailers for %q", fname)
 	}
 
-	results = append(results, bodyResults...)
+	diffs = append(diffs, trailerDiffs...)
+	diffs = append(diffs, violations...)
 
-	if conf.DumpResponses && len(bodyResults) != 0 {
-		err = json.NewEncoder(os.Stdout).Encode(targetBody)
+	graphQLDiffs, err := graphQLDifferences(conf.GraphQL, fname, baseBody, targetBody)
+	if err != nil {
+		return diffs, err
 	}
+	diffs = append(diffs, graphQLDiffs...)
 
-	return results, err
+	grpcDiffs, err := grpcDifferences(conf.GRPCDescriptors, fname)
+	if err != nil {
+		return diffs, err
+	}
+	diffs = append(diffs, grpcDiffs...)
+
+	validatorDiffs, err := runValidators(pConf.Validators, reqMethod, reqPath, version, baseBody, targetBody)
+	if err != nil {
+		return diffs, errors.Wrapf(err, "validating %q", fname)
+	}
+	diffs = append(diffs, validatorDiffs...)
+
+	if conf.DumpResponses && len(bodyDiffs) != 0 {
+		err = json.NewEncoder(dump).Encode(targetBody)
+	}
+
+	return diffs, err
 }
 
-func runTest(conf testConf, version, fname string) (pass bool, err error) {
-	var results []string
+func runTest(conf testConf, job *testJob, vars *scenarioVars) (err error) {
+	fname := job.fname
 
-	targetResp, baseResp, reqPath, reqMethod, err := getResponses(conf, fname)
+	targetResp, baseResp, reqPath, reqMethod, err := getResponses(conf, job, vars)
 	if targetResp != nil {
 		defer handleClose(&err, targetResp.Body)
 	}
@@ -176,28 +377,62 @@ func runTest(conf testConf, version, fname string) (pass bool, err error) {
 		defer handleClose(&err, baseResp.Body)
 	}
 	if err != nil {
-		return false, errors.Wrapf(err, "getting responses for %q", fname)
+		return errors.Wrapf(err, "getting responses for %q", fname)
 	}
+	job.method, job.path = reqMethod, reqPath
 
-	errg := &errgroup.Group{}
-
+	// the target response is saved as received, before the response hooks
+	var saveResp *http.Response
 	if conf.Save != "" {
 		b := &bytes.Buffer{}
 		_, err = io.Copy(b, targetResp.Body)
 		if err != nil {
-			return false, errors.Wrapf(err, "reading target response for %q", fname)
+			return errors.Wrapf(err, "reading target response for %q", fname)
 		}
-		saveResp := &http.Response{}
+		saveResp = &http.Response{}
 		*saveResp = *targetResp
 		targetResp.Body = ioutil.NopCloser(b)
 		saveResp.Body = ioutil.NopCloser(duplicateBuffer(b))
+	}
+	err = postProcessResponses(conf, job, targetResp, baseResp)
+	if err != nil {
+		return err
+	}
+
+	var extractDiffs []bacom.Difference
+	if vars != nil {
+		extractDiffs, err = vars.extract(job.extract, targetResp, baseResp)
+		if err != nil {
+			return errors.Wrapf(err, "extracting values from the responses for %q", fname)
+		}
+	}
+
+	errg := &errgroup.Group{}
+
+	if saveResp != nil {
 		errg.Go(func() error {
 			saver := bacom.NewSaver(filepath.Join(conf.Dir, conf.Save), fname)
 
-			err = saver.SaveRequest()
+			err := saver.SaveRequest()
 			if err != nil {
 				return err
 			}
+			job.savedAs = saver.RequestName()
+
+			if conf.Decompress {
+				err = bacom.DecompressBody(saveResp)
+				if err != nil {
+					return errors.Wrapf(err, "decompressing response for %q", fname)
+				}
+			}
+			if session, ok, err := takeSession(saveResp); ok {
+				if err == nil {
+					err = saver.SaveSession(session)
+				}
+				if err != nil {
+					return errors.Wrapf(err, "saving websocket session to %s for %q", conf.Save, fname)
+				}
+			}
 
 			err = saver.SaveResponse(saveResp)
 			if err != nil {
@@ -208,27 +443,36 @@ func runTest(conf testConf, version, fname string) (pass bool, err error) {
 		})
 	}
 
-	if baseResp != nil {
+	if baseResp != nil || conf.OpenAPI != nil || conf.GraphQL.Enabled || conf.GRPCDescriptors != nil {
 		errg.Go(func() error {
 			var errCmp error
+			dump := &bytes.Buffer{}
 
-			results, errCmp = compareResponses(
-				conf, version, reqPath, reqMethod, fname,
-				baseResp, targetResp,
+			job.differences, errCmp = compareResponses(
+				conf, job.version, reqPath, reqMethod, fname,
+				baseResp, targetResp, dump,
 			)
+			job.dump = dump.Bytes()
 
 			return errCmp
 		})
 	}
 
 	err = errg.Wait()
-	if err != nil {
-		return false, err
-	}
+	job.differences = append(extractDiffs, job.differences...)
+
+	return err
+}
 
-	printResults(fname, results)
+func drainBodies(responses ...*http.Response) error {
+	for _, resp := range responses {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		if err != nil {
+			return err
+		}
+	}
 
-	return len(results) == 0, nil
+	return nil
 }
 
 func duplicateBuffer(b *bytes.Buffer) *bytes.Buffer {
@@ -239,21 +483,113 @@ func duplicateBuffer(b *bytes.Buffer) *bytes.Buffer {
 	return bytes.NewBuffer(buf)
 }
 
-func printResults(fname string, results []string) {
-	if len(results) != 0 {
+func printResults(fname string, diffs []bacom.Difference) error {
+	if len(diffs) != 0 {
 		fmt.Printf("\n%s:\n", fname)
 	}
-	for _, result := range results {
-		fmt.Println(result)
-	}
+
+	return bacom.TextRenderer{Colorized: true}.Render(os.Stdout, diffs)
 }
 
-func readBody(resp *http.Response) (body interface{}, err error) {
+// readBody decompresses and decodes the response body using conf.Format, or using the Content-Type header if
+// the format is empty or bacom.FormatAuto. When the content-type is unknown, the body is decoded as JSON,
+// falling back to text.
+// The decoded bodies are wrapped in an array (to accommodate JSON streams).
+func readBody(resp *http.Response, conf bodyConf) (body interface{}, err error) {
 	if resp == nil {
 		return nil, nil
 	}
+	err = bacom.DecompressBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	format := conf.Format
+	if format == "" || format == bacom.FormatAuto {
+		format = bacom.ContentTypeFormat(resp.Header.Get("Content-Type"))
+	}
+
+	switch format {
+	case bacom.FormatXML:
+		return wrapBody(bacom.DecodeXML(resp.Body))
+	case bacom.FormatForm:
+		return wrapBody(bacom.DecodeForm(resp.Body))
+	case bacom.FormatSSE:
+		return wrapStream(bacom.DecodeSSE(resp.Body))
+	case bacom.FormatNDJSON:
+		return wrapStream(bacom.DecodeNDJSON(resp.Body, conf.EventType))
+	case bacom.FormatWebSocket:
+		return wrapStream(bacom.DecodeWebSocketSession(resp.Body, conf.EventType))
+	case bacom.FormatText, bacom.FormatBytes:
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil || len(b) == 0 {
+			return nil, err
+		}
+		return []interface{}{bacom.NewRawBody(format, b)}, nil
+	case bacom.FormatAuto:
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil || len(bytes.TrimSpace(b)) == 0 {
+			return nil, err
+		}
+		body, err = readJSONBody(bytes.NewReader(b))
+		if err == nil {
+			return body, nil
+		}
+		if utf8.Valid(b) {
+			return []interface{}{bacom.NewRawBody(bacom.FormatText, b)}, nil
+		}
+		return []interface{}{bacom.NewRawBody(bacom.FormatBytes, b)}, nil
+	}
+
+	return readJSONBody(resp.Body)
+}
+
+func wrapBody(body interface{}, err error) (interface{}, error) {
+	if body == nil || err != nil {
+		return nil, err
+	}
+
+	return []interface{}{body}, nil
+}
+
+func wrapStream(stream bacom.EventStream, err error) (interface{}, error) {
+	if len(stream) == 0 || err != nil {
+		return nil, err
+	}
+
+	return []interface{}{stream}, nil
+}
+
+// eventStream returns the event stream held by body, if any
+func eventStream(body interface{}) (bacom.EventStream, bool) {
+	if wrapped, ok := body.([]interface{}); ok && len(wrapped) == 1 {
+		body = wrapped[0]
+	}
+	stream, ok := body.(bacom.EventStream)
+
+	return stream, ok
+}
+
+// compareBodies compares two bodies returned by readBody. Event streams are compared by event type
+// (see bacom.StreamOptions), unless only one of the bodies is a stream.
+func compareBodies(pConf pathConf, base, target interface{}) ([]bacom.Difference, error) {
+	opts := pConf.JSON.bodyOptions()
+
+	baseStream, baseOK := eventStream(base)
+	targetStream, targetOK := eventStream(target)
+	switch {
+	case baseOK && targetOK:
+		return pConf.Body.Stream.Differences(opts, baseStream, targetStream)
+	case baseOK:
+		base = []interface{}{baseStream.Tree()}
+	case targetOK:
+		target = []interface{}{targetStream.Tree()}
+	}
+
+	return opts.Differences(base, target)
+}
 
-	dec := json.NewDecoder(resp.Body)
+func readJSONBody(r io.Reader) (body interface{}, err error) {
+	dec := json.NewDecoder(r)
 	err = dec.Decode(&body)
 	if err == io.EOF {
 		return nil, nil
@@ -285,8 +621,16 @@ func decodeStream(dec *json.Decoder, body []interface{}) (interface{}, error) {
 	return body, nil
 }
 
-func getResponses(conf testConf, fname string) (target, base *http.Response, path, method string, err error) {
-	req, err := parseRequest(conf.Target.PreProcess, fname)
+// getResponses returns the target and base responses for the request file of job. The values extracted by the
+// previous steps of a scenario (if any) take precedence over the other variables.
+func getResponses(conf testConf, job *testJob, vars *scenarioVars) (target, base *http.Response, path, method string, err error) {
+	fname := job.fname
+	targetVars, baseVars := &conf.Vars, &conf.Vars
+	if vars != nil {
+		targetVars, baseVars = conf.Vars.with(vars.target), conf.Vars.with(vars.base)
+	}
+
+	req, err := parseRequest(targetVars, conf.Target.requestHooks(conf.CommandTimeout, job.version, "target"), fname)
 	if err != nil {
 		return nil, nil, "", "", err
 	}
@@ -295,7 +639,7 @@ func getResponses(conf testConf, fname string) (target, base *http.Response, pat
 		return nil, nil, "", "", errors.Wrapf(err, "getting target response for %q", fname)
 	}
 
-	req, err = parseRequest(conf.Base.PreProcess, fname)
+	req, err = parseRequest(baseVars, conf.Base.requestHooks(conf.CommandTimeout, job.version, "base"), fname)
 	if err != nil {
 		return target, nil, "", "", err
 	}
@@ -310,48 +654,75 @@ func getResponses(conf testConf, fname string) (target, base *http.Response, pat
 	return target, base, req.URL.Path, req.Method, nil
 }
 
-func parseRequest(preprocess, fname string) (req *http.Request, err error) {
-	f, err := os.Open(fname)
+// parseRequest reads the request file fname, resolving its {{variables}} if vars is set.
+// The request is then passed through the preprocess command and hook of hooks, if any.
+func parseRequest(vars *requestVars, hooks requestHooks, fname string) (req *http.Request, err error) {
+	b, err := ioutil.ReadFile(fname)
 	if err != nil {
 		return nil, errors.Wrapf(err, "parsing request %q", fname)
 	}
-	defer handleClose(&err, f)
-
-	if preprocess == "" {
-		req, err = http.ReadRequest(bufio.NewReader(f))
 
-		return req, errors.Wrapf(err, "parsing request %q", fname)
+	if vars != nil && bytes.Contains(b, []byte("{{")) {
+		var resolved templateVars
+		resolved, err = vars.forRequest(fname)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing request %q", fname)
+		}
+		req, err = readTemplatedRequest(b, resolved)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing request %q", fname)
+		}
+		if hooks.PreProcess != "" {
+			buf := &bytes.Buffer{}
+			err = req.Write(buf)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing request %q", fname)
+			}
+			b, req = buf.Bytes(), nil
+		}
 	}
 
-	// TODO(yazgazan): add timeout using the context
-	cmd := exec.CommandContext(context.Background(), "/bin/sh", "-c", preprocess)
-	cmd.Stdin = f
-	b := &bytes.Buffer{}
-	cmd.Stdout = b
-
-	err = cmd.Run()
-	if err != nil {
-		return req, errors.Wrapf(err, "parsing request %q", fname)
+	if hooks.PreProcess != "" {
+		b, err = bacom.RunCommand(hooks.PreProcess, hooks.Timeout, b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing request %q", fname)
+		}
+	}
+	if req == nil {
+		req, err = http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing request %q", fname)
+		}
+	}
+	if hooks.Hook == "" {
+		return req, nil
 	}
 
-	req, err = http.ReadRequest(bufio.NewReader(b))
+	req, err = runRequestHook(hooks, fname, req)
 
 	return req, errors.Wrapf(err, "parsing request %q", fname)
 }
 
 func getTargetResponse(req *http.Request, reqFname string, targetConf targetConf) (*http.Response, error) {
-	return getTargetResponseFromHost(req, targetConf.Host, targetConf.UseHTTPS)
+	if websocket.IsUpgrade(req.Header) {
+		return getWebSocketResponse(req, reqFname, targetConf)
+	}
+
+	return getTargetResponseFromHost(req, targetConf)
 }
 
 func getBaseResponse(req *http.Request, reqFname string, targetConf targetConf) (*http.Response, error) {
 	if targetConf.Host != "" {
-		return getTargetResponseFromHost(req, targetConf.Host, targetConf.UseHTTPS)
+		return getTargetResponse(req, reqFname, targetConf)
+	}
+	if websocket.IsUpgrade(req.Header) {
+		return readWebSocketResponse(req, reqFname)
 	}
 
 	return bacom.ReadResponse(req, reqFname)
 }
 
-func getTargetResponseFromHost(req *http.Request, host string, useHTTPS bool) (*http.Response, error) {
+func setTargetHost(req *http.Request, host string, useHTTPS bool) {
 	req.Host = host
 	req.RequestURI = ""
 	req.URL.Host = host
@@ -360,6 +731,46 @@ func getTargetResponseFromHost(req *http.Request, host string, useHTTPS bool) (*
 	} else {
 		req.URL.Scheme = "http"
 	}
+}
+
+// prepareRequest points req to the host of targetConf, and sets its credentials
+func prepareRequest(req *http.Request, targetConf targetConf) error {
+	setTargetHost(req, targetConf.Host, targetConf.UseHTTPS)
+	if targetConf.Auth == nil {
+		return nil
+	}
+
+	return errors.Wrap(targetConf.Auth.authenticate(req), "authenticating request")
+}
+
+func getTargetResponseFromHost(req *http.Request, targetConf targetConf) (*http.Response, error) {
+	err := prepareRequest(req, targetConf)
+	if err != nil {
+		return nil, err
+	}
+	if isGRPCCall(req) {
+		return getGRPCResponse(req, targetConf.grpcClient)
+	}
+	client := targetConf.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	err = setGetBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// setGetBody buffers the body of the requests read from request files, so that it can be sent again when following
+// 307 and 308 redirects, or when retrying the request
+func setGetBody(req *http.Request) error {
+	if req.GetBody != nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	_, err := readRequestBody(req)
 
-	return http.DefaultClient.Do(req)
+	return errors.Wrap(err, "reading request body")
 }
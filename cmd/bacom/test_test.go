@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCmdArgsEnv holds the arguments of the test command run by TestParallelOrder in a sub-process
+const testCmdArgsEnv = "BACOM_TEST_CMD_ARGS"
+
+func TestParallelOrder(t *testing.T) {
+	if args := os.Getenv(testCmdArgsEnv); args != "" {
+		testCmd(strings.Split(args, "\n"))
+		os.Exit(0)
+	}
+
+	// the requests take different times, so that they complete in a different order when run concurrently
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := strconv.Atoi(r.URL.Query().Get("delay"))
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "TestParallelOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := func(paths ...string) map[string]string {
+		m := make(map[string]string, 2*len(paths))
+		for i, p := range paths {
+			name := strconv.Itoa(i)
+			delay := strconv.Itoa((len(paths) - i) * 40)
+			body := `{"path": "` + p + `"}`
+			m[name+"_req.txt"] = "GET " + p + "?delay=" + delay + " HTTP/1.1\r\nHost: localhost\r\n\r\n"
+			m[name+"_resp.txt"] = "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: " +
+				strconv.Itoa(len(body)) + "\r\n\r\n" + body
+		}
+		return m
+	}
+	for version, fs := range map[string]map[string]string{
+		"v1.0.0": files("/a", "/b", "/c", "/d"),
+		"v1.1.0": files("/e", "/f", "/g", "/h"),
+	} {
+		versionDir := filepath.Join(dir, version)
+		err = os.Mkdir(versionDir, 0750)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestFiles(t, versionDir, fs)
+	}
+	// failing tests, whose content type changed
+	writeTestFiles(t, filepath.Join(dir, "v1.0.0"), map[string]string{
+		"1_resp.txt": "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n[]",
+	})
+	writeTestFiles(t, filepath.Join(dir, "v1.1.0"), map[string]string{
+		"3_resp.txt": "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n[]",
+	})
+
+	run := func(parallel int) (string, int) {
+		args := []string{
+			"-dir", dir,
+			"-conf", filepath.Join(dir, "bacom.json"),
+			"-target-host", strings.TrimPrefix(srv.URL, "http://"),
+			"-parallel", strconv.Itoa(parallel),
+			"-v",
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestParallelOrder$")
+		cmd.Env = append(os.Environ(), testCmdArgsEnv+"="+strings.Join(args, "\n"))
+		stdout := &bytes.Buffer{}
+		cmd.Stdout = stdout
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return stdout.String(), exitErr.ExitCode()
+		}
+		if err != nil {
+			t.Fatalf("-parallel %d: unexpected error: %s", parallel, err)
+		}
+		return stdout.String(), 0
+	}
+
+	sequential, sequentialCode := run(1)
+	if sequentialCode != 1 || strings.Count(sequential, "FAIL") != 4 {
+		t.Fatalf("-parallel 1: exit code %d, expected 1 with 2 failing tests in 2 versions:\n%s", sequentialCode, sequential)
+	}
+	parallel, parallelCode := run(4)
+	if parallelCode != sequentialCode {
+		t.Errorf("-parallel 4: exit code %d, expected %d", parallelCode, sequentialCode)
+	}
+	if parallel != sequential {
+		t.Errorf("-parallel 4: output\n%s\nexpected the output of -parallel 1:\n%s", parallel, sequential)
+	}
+}
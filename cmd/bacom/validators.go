@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+)
+
+// registerCommandValidators registers the external validators provided using -validator, killed after timeout
+func registerCommandValidators(commands map[string]string, timeout time.Duration) error {
+	for name, command := range commands {
+		if _, ok := bacom.GetValidator(name); ok {
+			return errors.Errorf("validator %q is already registered", name)
+		}
+		bacom.RegisterValidator(name, bacom.CommandValidator{
+			Name:    name,
+			Command: command,
+			Timeout: timeout,
+		})
+	}
+
+	return nil
+}
+
+// checkValidators ensures all the validators referenced in the configuration are registered
+func checkValidators(conf []pathConf) error {
+	for _, c := range conf {
+		for _, name := range c.Validators {
+			if _, ok := bacom.GetValidator(name); !ok {
+				return errors.Errorf("unknown validator %q for path %q", name, c.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+func runValidators(names []string, method, path, version string, base, target interface{}) ([]bacom.Difference, error) {
+	var diffs []bacom.Difference
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		v, ok := bacom.GetValidator(name)
+		if !ok {
+			return diffs, errors.Errorf("unknown validator %q", name)
+		}
+		vDiffs, err := v.Validate(method, path, version, unwrapBody(base), unwrapBody(target))
+		if err != nil {
+			return diffs, err
+		}
+		diffs = append(diffs, vDiffs...)
+	}
+
+	return diffs, nil
+}
+
+// unwrapBody returns the document from bodies holding a single JSON document, and the tree of
+// event streams (see readBody)
+func unwrapBody(body interface{}) interface{} {
+	if stream, ok := eventStream(body); ok {
+		return stream.Tree()
+	}
+	if stream, ok := body.([]interface{}); ok && len(stream) == 1 {
+		return stream[0]
+	}
+
+	return body
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// envFileName is the name of the files holding the variables of the requests of a directory (and its sub-directories)
+const envFileName = "env.yaml"
+
+var templateVarRegexp = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// templateVars resolves {{variable}} references. Unknown variables are left untouched.
+type templateVars map[string]string
+
+func (vars templateVars) resolve(s string) string {
+	// resolving a few times allows variables referencing other variables
+	for i := 0; i < 5 && strings.Contains(s, "{{"); i++ {
+		s = templateVarRegexp.ReplaceAllStringFunc(s, func(m string) string {
+			name := templateVarRegexp.FindStringSubmatch(m)[1]
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	return s
+}
+
+func (vars templateVars) merge(other map[string]string) {
+	for k, v := range other {
+		vars[k] = v
+	}
+}
+
+// requestVars holds the variables resolved in request files (see parseRequest). In increasing order of precedence,
+// they are read from the process environment, the env.yaml files of the directories from Root to the request file,
+// the environment files and the -var flags.
+type requestVars struct {
+	Root     string
+	EnvFiles stringsFlag
+	Vars     varsFlag
+
+	environ   map[string]string
+	overrides map[string]string
+}
+
+// SetupFlags registers the -env and -var flags
+func (v *requestVars) SetupFlags(flags *flag.FlagSet) {
+	flags.Var(&v.EnvFiles, "env", "environment file (yaml) used to resolve {{variables}} in requests (can be repeated)")
+	flags.Var(&v.Vars, "var", "variable used to resolve {{variables}} in requests (name=value, can be repeated)")
+}
+
+// load reads the process environment and the environment files
+func (v *requestVars) load() error {
+	v.environ = map[string]string{}
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			v.environ[parts[0]] = parts[1]
+		}
+	}
+
+	v.overrides = map[string]string{}
+	for _, fname := range v.EnvFiles {
+		vars, err := readEnvFile(fname)
+		if err != nil {
+			return err
+		}
+		templateVars(v.overrides).merge(vars)
+	}
+	templateVars(v.overrides).merge(v.Vars)
+
+	return nil
+}
+
+// global returns the variables that don't depend on the request file (the env.yaml files are ignored)
+func (v *requestVars) global() templateVars {
+	vars := templateVars{}
+	vars.merge(v.environ)
+	vars.merge(v.overrides)
+
+	return vars
+}
+
+// forRequest returns the variables of the request file fname
+func (v *requestVars) forRequest(fname string) (templateVars, error) {
+	vars := templateVars{}
+	vars.merge(v.environ)
+
+	for _, dir := range envDirs(v.Root, filepath.Dir(fname)) {
+		dirVars, err := readEnvFile(filepath.Join(dir, envFileName))
+		if os.IsNotExist(errors.Cause(err)) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		vars.merge(dirVars)
+	}
+	vars.merge(v.overrides)
+
+	return vars, nil
+}
+
+// with returns a copy of v where vars take precedence over all the other variables
+func (v *requestVars) with(vars templateVars) *requestVars {
+	overrides := templateVars{}
+	overrides.merge(v.overrides)
+	overrides.merge(vars)
+	out := *v
+	out.overrides = overrides
+
+	return &out
+}
+
+// envDirs returns the directories from root to dir (included), or only dir if it isn't inside root
+func envDirs(root, dir string) []string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return []string{dir}
+	}
+
+	dirs := []string{root}
+	if rel == "." {
+		return dirs
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dirs = append(dirs, filepath.Join(dirs[len(dirs)-1], part))
+	}
+
+	return dirs
+}
+
+// readEnvFile reads a yaml (or json) file holding variables (`name: value`)
+func readEnvFile(fname string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading environment file %q", fname)
+	}
+
+	var vars map[string]string
+	err = yaml.Unmarshal(b, &vars)
+
+	return vars, errors.Wrapf(err, "reading environment file %q", fname)
+}
+
+// readTemplatedRequest parses the request dump b, resolving its variables. Unless the body is chunked, it is made of
+// everything following the headers (the stored Content-Length is likely stale once the file has been edited), and
+// the Content-Length header is updated to match the resolved body.
+func readTemplatedRequest(b []byte, vars templateVars) (*http.Request, error) {
+	end := headEnd(b)
+	head := vars.resolve(string(b[:end]))
+
+	req, err := http.ReadRequest(bufio.NewReader(io.MultiReader(strings.NewReader(head), bytes.NewReader(b[end:]))))
+	if err != nil {
+		return nil, err
+	}
+	body := b[end:]
+	if len(req.TransferEncoding) != 0 {
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	body = []byte(vars.resolve(string(body)))
+
+	req.Body = http.NoBody
+	if len(body) != 0 {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	req.ContentLength = int64(len(body))
+	if req.Header.Get("Content-Length") != "" {
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	return req, nil
+}
+
+// headEnd returns the position of the end of the request line and headers (after the empty line)
+func headEnd(b []byte) int {
+	for i, c := range b {
+		if c != '\n' {
+			continue
+		}
+		rest := b[i+1:]
+		if bytes.HasPrefix(rest, []byte("\n")) {
+			return i + 2
+		}
+		if bytes.HasPrefix(rest, []byte("\r\n")) {
+			return i + 3
+		}
+	}
+
+	return len(b)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadTemplatedRequest(t *testing.T) {
+	vars := templateVars{"tenant": "acme", "token": "s3cr3t", "name": "Jane Doe"}
+	dump := "POST /api/{{tenant}}/users?q={{unknown}} HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Authorization: Bearer {{token}}\r\n" +
+		"Content-Length: 16\r\n" +
+		"\r\n" +
+		`{"name":"{{name}}"}`
+
+	req, err := readTemplatedRequest([]byte(dump), vars)
+	if err != nil {
+		t.Fatalf("readTemplatedRequest: unexpected error: %s", err)
+	}
+	if req.URL.Path != "/api/acme/users" || req.URL.RawQuery != "q={{unknown}}" {
+		t.Errorf("URL = %q, expected /api/acme/users?q={{unknown}}", req.URL)
+	}
+	if v := req.Header.Get("Authorization"); v != "Bearer s3cr3t" {
+		t.Errorf("Authorization = %q, expected %q", v, "Bearer s3cr3t")
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"name":"Jane Doe"}` || req.ContentLength != 19 || req.Header.Get("Content-Length") != "19" {
+		t.Errorf("body = %q (length %d, header %q), expected %q", b, req.ContentLength, req.Header.Get("Content-Length"), `{"name":"Jane Doe"}`)
+	}
+
+	req, err = readTemplatedRequest([]byte("GET /{{tenant}} HTTP/1.1\nHost: localhost\n\n"), vars)
+	if err != nil {
+		t.Fatalf("readTemplatedRequest: unexpected error: %s", err)
+	}
+	if req.URL.Path != "/acme" || req.ContentLength != 0 {
+		t.Errorf("readTemplatedRequest(GET) = %q (length %d), expected /acme", req.URL, req.ContentLength)
+	}
+}
+
+func TestEnvDirs(t *testing.T) {
+	for _, test := range []struct {
+		root, dir string
+		expected  []string
+	}{
+		{"tests", "tests", []string{"tests"}},
+		{"tests", "tests/v1/users", []string{"tests", "tests/v1", "tests/v1/users"}},
+		{"tests", "other/v1", []string{"other/v1"}},
+		{".", "v1", []string{".", "v1"}},
+	} {
+		got := envDirs(test.root, test.dir)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("envDirs(%q, %q) = %q, expected %q", test.root, test.dir, got, test.expected)
+		}
+	}
+}
+
+func TestRequestVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestRequestVars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for fname, content := range map[string]string{
+		envFileName:                        "host: root\nport: 8080\ntenant: root\nregion: eu\n",
+		filepath.Join("v1", envFileName):   "tenant: v1\n",
+		"staging.yaml":                     "region: us\nuser: staging\n",
+		filepath.Join("v1", "foo_req.txt"): "",
+	} {
+		err = os.MkdirAll(filepath.Join(dir, filepath.Dir(fname)), 0750)
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, fname), []byte(content), 0640)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.Setenv("BACOM_TEST_VAR", "from-env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("BACOM_TEST_VAR")
+
+	v := requestVars{
+		Root:     dir,
+		EnvFiles: stringsFlag{filepath.Join(dir, "staging.yaml")},
+		Vars:     varsFlag{"user": "cli"},
+	}
+	err = v.load()
+	if err != nil {
+		t.Fatalf("load: unexpected error: %s", err)
+	}
+	vars, err := v.forRequest(filepath.Join(dir, "v1", "foo_req.txt"))
+	if err != nil {
+		t.Fatalf("forRequest: unexpected error: %s", err)
+	}
+
+	for name, expected := range map[string]string{
+		"host":           "root",
+		"port":           "8080",
+		"tenant":         "v1",
+		"region":         "us",
+		"user":           "cli",
+		"BACOM_TEST_VAR": "from-env",
+	} {
+		if vars[name] != expected {
+			t.Errorf("vars[%q] = %q, expected %q", name, vars[name], expected)
+		}
+	}
+
+	v.EnvFiles = stringsFlag{filepath.Join(dir, "missing.yaml")}
+	if err = v.load(); err == nil {
+		t.Error("load(missing env file): expected error, got nil")
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/websocket"
+)
+
+// webSocketGrace is how long the server messages are waited for, after the time of the last message of the
+// recorded session
+const webSocketGrace = time.Second
+
+// sessionMessage converts a message received start after the handshake to its representation in session files
+func sessionMessage(from string, start time.Time, msg websocket.Message) bacom.WebSocketMessage {
+	m := bacom.WebSocketMessage{
+		From: from,
+		Time: int64(time.Since(start) / time.Millisecond),
+		Type: bacom.TextMessage,
+		Data: string(msg.Data),
+	}
+	switch msg.Opcode {
+	case websocket.OpBinary:
+		m.Type = bacom.BinaryMessage
+		m.Data = base64.StdEncoding.EncodeToString(msg.Data)
+	case websocket.OpClose:
+		m.Type = bacom.CloseMessage
+		m.Code, m.Data = websocket.ParseClose(msg.Data)
+	}
+
+	return m
+}
+
+// wireMessage converts a message of a session file to the message sent over the wire
+func wireMessage(m bacom.WebSocketMessage) (websocket.Message, error) {
+	switch m.Type {
+	case bacom.TextMessage:
+		return websocket.Message{Opcode: websocket.OpText, Data: []byte(m.Data)}, nil
+	case bacom.BinaryMessage:
+		b, err := base64.StdEncoding.DecodeString(m.Data)
+		return websocket.Message{Opcode: websocket.OpBinary, Data: b}, errors.Wrap(err, "decoding binary message")
+	case bacom.CloseMessage:
+		var data []byte
+		if m.Code != 0 {
+			data = websocket.CloseData(m.Code, m.Data)
+		}
+		return websocket.Message{Opcode: websocket.OpClose, Data: data}, nil
+	}
+
+	return websocket.Message{}, errors.Errorf("unknown websocket message type %q", m.Type)
+}
+
+// getWebSocketResponse replays the session stored alongside reqFname (if any) against the target. The response
+// to the handshake is returned, holding the replayed session as its body (see sessionResponse).
+func getWebSocketResponse(req *http.Request, reqFname string, targetConf targetConf) (*http.Response, error) {
+	session, err := bacom.ReadSession(reqFname)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err = prepareRequest(req, targetConf)
+	if err != nil {
+		return nil, err
+	}
+	conn, resp, err := websocket.Dial(req)
+	if conn == nil || err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+
+	replayed, err := replaySession(conn, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionResponse(resp, replayed)
+}
+
+// replaySession sends the client messages of session at the time they were recorded, and returns the messages
+// exchanged. It returns once the server closed the connection, or sent as many messages as in session, waiting
+// at most webSocketGrace after the time of the last recorded message.
+func replaySession(conn *websocket.Conn, session bacom.WebSocketSession) (replayed bacom.WebSocketSession, err error) {
+	var (
+		expected int
+		end      time.Duration
+	)
+	for _, m := range session.Messages {
+		if m.From == bacom.FromServer {
+			expected++
+		}
+		end = time.Duration(m.Time) * time.Millisecond
+	}
+
+	start := time.Now()
+	done := make(chan struct{})
+	defer close(done)
+	messages := make(chan bacom.WebSocketMessage)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case messages <- sessionMessage(bacom.FromServer, start, msg):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	received, closed := 0, false
+	// receive collects the server messages until the deadline, or until the connection is closed
+	receive := func(deadline time.Time, stopAtExpected bool) {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		for !closed && !(stopAtExpected && received >= expected) {
+			select {
+			case m, ok := <-messages:
+				if !ok {
+					closed = true
+					continue
+				}
+				replayed.Messages = append(replayed.Messages, m)
+				received++
+			case <-timer.C:
+				return
+			}
+		}
+	}
+
+	for _, m := range session.Messages {
+		if m.From != bacom.FromClient {
+			continue
+		}
+		receive(start.Add(time.Duration(m.Time)*time.Millisecond), false)
+		if closed {
+			break
+		}
+		msg, err := wireMessage(m)
+		if err != nil {
+			return replayed, err
+		}
+		err = conn.WriteMessage(msg)
+		if err != nil {
+			return replayed, errors.Wrap(err, "replaying websocket session")
+		}
+		replayed.Messages = append(replayed.Messages, sessionMessage(bacom.FromClient, start, msg))
+	}
+	receive(start.Add(end+webSocketGrace), true)
+
+	if !closed {
+		// the close handshake is not waited for, the connection is closed right after
+		_ = conn.WriteMessage(websocket.Message{
+			Opcode: websocket.OpClose,
+			Data:   websocket.CloseData(websocket.CloseNormal, ""),
+		})
+	}
+
+	return replayed, nil
+}
+
+// sessionResponse returns resp, the response to a websocket handshake, with session as its body.
+// Refused handshakes are returned as is.
+func sessionResponse(resp *http.Response, session bacom.WebSocketSession) (*http.Response, error) {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return resp, nil
+	}
+
+	b, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *resp
+	out.Header = resp.Header.Clone()
+	out.Header.Set("Content-Type", bacom.WebSocketSessionContentType)
+	out.Body = ioutil.NopCloser(bytes.NewReader(b))
+	out.ContentLength = int64(len(b))
+
+	return &out, nil
+}
+
+// readWebSocketResponse reads the stored response to a websocket handshake, along with its session
+func readWebSocketResponse(req *http.Request, reqFname string) (*http.Response, error) {
+	resp, err := bacom.ReadResponse(req, reqFname)
+	if err != nil {
+		return nil, err
+	}
+	session, err := bacom.ReadSession(reqFname)
+	if os.IsNotExist(err) {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sessionResponse(resp, session)
+}
+
+// takeSession extracts the session held by a response returned by sessionResponse, restoring the original response
+func takeSession(resp *http.Response) (session bacom.WebSocketSession, ok bool, err error) {
+	if resp.Header.Get("Content-Type") != bacom.WebSocketSessionContentType {
+		return session, false, nil
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	if err != nil {
+		return session, true, errors.Wrap(err, "reading websocket session")
+	}
+	resp.Header = resp.Header.Clone()
+	resp.Header.Del("Content-Type")
+	resp.Body = http.NoBody
+	resp.ContentLength = 0
+
+	return session, true, nil
+}
+
+// sessionRecorder records the messages going through a proxied websocket connection
+type sessionRecorder struct {
+	start time.Time
+
+	mu      sync.Mutex
+	session bacom.WebSocketSession
+}
+
+// pump forwards the frames read from one side of the connection to the other, until either fails
+func (r *sessionRecorder) pump(from string, read func() (websocket.Frame, error), write func(websocket.Frame) error) {
+	var asm websocket.Assembler
+
+	for {
+		f, err := read()
+		if err != nil {
+			return
+		}
+		err = write(f)
+		if err != nil {
+			return
+		}
+		if msg, ok := asm.Add(f); ok {
+			r.mu.Lock()
+			r.session.Messages = append(r.session.Messages, sessionMessage(from, r.start, msg))
+			r.mu.Unlock()
+		}
+	}
+}
+
+// proxyWebSocket forwards a websocket session to u, saving the handshake as a request/response pair and the
+// messages exchanged in a session file (see bacom.GetSessionFilename)
+func proxyWebSocket(w http.ResponseWriter, r *http.Request, u *url.URL, outDir string, verbose bool, filters reqFilters) {
+	req, err := http.NewRequest(r.Method, u.String(), http.NoBody)
+	if err != nil {
+		http.Error(w, "failed to create proxied request", http.StatusInternalServerError)
+		log.Printf("failed to create proxied request: %v", err)
+		return
+	}
+	for k, vv := range r.Header {
+		if k == "Host" {
+			continue
+		}
+		req.Header[k] = vv
+	}
+
+	server, resp, err := websocket.Dial(req)
+	if err != nil {
+		http.Error(w, "failed to proxy request", http.StatusBadGateway)
+		log.Printf("failed to proxy request: %v", err)
+		return
+	}
+	if server == nil {
+		for k, vv := range resp.Header {
+			w.Header()[k] = vv
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, err = io.Copy(w, resp.Body)
+		if err != nil {
+			log.Printf("failed to write response: %v", err)
+		}
+		return
+	}
+	defer server.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "failed to proxy websocket", http.StatusInternalServerError)
+		log.Printf("failed to proxy websocket: connection can't be hijacked")
+		return
+	}
+	client, clientRW, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("failed to proxy websocket: %v", err)
+		return
+	}
+	defer client.Close()
+	err = resp.Write(client)
+	if err != nil {
+		log.Printf("failed to write response: %v", err)
+		return
+	}
+
+	rec := &sessionRecorder{start: time.Now()}
+	done := make(chan struct{}, 2)
+	go func() {
+		rec.pump(bacom.FromClient, func() (websocket.Frame, error) {
+			return websocket.ReadFrame(clientRW.Reader)
+		}, server.WriteFrame)
+		done <- struct{}{}
+	}()
+	go func() {
+		rec.pump(bacom.FromServer, server.ReadFrame, func(f websocket.Frame) error {
+			return websocket.WriteFrame(client, f, false)
+		})
+		done <- struct{}{}
+	}()
+	<-done
+	_ = client.Close()
+	_ = server.Close()
+	<-done
+
+	if err := filters.Match(req); err != nil {
+		if verbose {
+			log.Printf("skipping %s: %v", u.String(), err)
+		}
+		return
+	}
+
+	name := "ws-" + normalize(u.Path)
+	reqFname, err := importReq(verbose, outDir, name, req)
+	if err != nil {
+		log.Printf("failed to save request for %q: %v", u.String(), err)
+		return
+	}
+	resp.Body = http.NoBody
+	err = importResp(verbose, reqFname, outDir, name, resp)
+	if err != nil {
+		log.Printf("failed to save response for %q: %v", u.String(), err)
+		return
+	}
+	sessionFname, err := bacom.GetSessionFilename(reqFname)
+	if err == nil {
+		err = bacom.WriteSession(sessionFname, rec.session)
+	}
+	if err != nil {
+		log.Printf("failed to save session for %q: %v", u.String(), err)
+		return
+	}
+
+	log.Printf("saved websocket session %q for %q", name, u.String())
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/yazgazan/bacom"
+	"github.com/yazgazan/bacom/websocket"
+)
+
+// echoWebSocket echoes the text messages it receives, and answers close messages
+func echoWebSocket(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + websocket.AcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		_ = rw.Flush()
+
+		for {
+			f, err := websocket.ReadFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			_ = websocket.WriteFrame(conn, f, false)
+			if f.Opcode == websocket.OpClose {
+				return
+			}
+		}
+	}))
+}
+
+func TestReplaySession(t *testing.T) {
+	srv := echoWebSocket(t)
+	defer srv.Close()
+
+	session := bacom.WebSocketSession{Messages: []bacom.WebSocketMessage{
+		{From: bacom.FromClient, Type: bacom.TextMessage, Data: `{"type": "subscribe"}`},
+		{From: bacom.FromServer, Type: bacom.TextMessage, Data: `{"type": "subscribe"}`},
+		{From: bacom.FromClient, Time: 20, Type: bacom.BinaryMessage, Data: "AAE="},
+		{From: bacom.FromServer, Time: 20, Type: bacom.BinaryMessage, Data: "AAE="},
+		{From: bacom.FromClient, Time: 30, Type: bacom.CloseMessage, Code: 1000, Data: "bye"},
+		{From: bacom.FromServer, Time: 30, Type: bacom.CloseMessage, Code: 1000, Data: "bye"},
+	}}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/live", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, _, err := websocket.Dial(req)
+	if err != nil {
+		t.Fatalf("Dial: unexpected error: %s", err)
+	}
+	defer conn.Close()
+
+	replayed, err := replaySession(conn, session)
+	if err != nil {
+		t.Fatalf("replaySession: unexpected error: %s", err)
+	}
+	if len(replayed.Messages) != len(session.Messages) {
+		t.Fatalf("replaySession(...) = %+v, expected %d messages", replayed, len(session.Messages))
+	}
+	for i, m := range replayed.Messages {
+		expected := session.Messages[i]
+		if m.From != expected.From || m.Type != expected.Type || m.Data != expected.Data || m.Code != expected.Code {
+			t.Errorf("message %d = %+v, expected %+v", i, m, expected)
+		}
+		if m.Time < expected.Time {
+			t.Errorf("message %d sent after %dms, expected at least %dms", i, m.Time, expected.Time)
+		}
+	}
+}
+
+func TestTakeSession(t *testing.T) {
+	session := bacom.WebSocketSession{Messages: []bacom.WebSocketMessage{
+		{From: bacom.FromServer, Time: 3, Type: bacom.TextMessage, Data: "hello"},
+	}}
+	resp := &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     http.Header{"Upgrade": {"websocket"}},
+		Body:       http.NoBody,
+	}
+
+	withSession, err := sessionResponse(resp, session)
+	if err != nil {
+		t.Fatalf("sessionResponse: unexpected error: %s", err)
+	}
+	got, ok, err := takeSession(withSession)
+	if err != nil || !ok {
+		t.Fatalf("takeSession(...) = %v, %v, expected a session", ok, err)
+	}
+	if !reflect.DeepEqual(got, session) {
+		t.Errorf("takeSession(...) = %+v, expected %+v", got, session)
+	}
+	if !reflect.DeepEqual(withSession.Header, resp.Header) {
+		t.Errorf("takeSession(...): headers = %v, expected %v", withSession.Header, resp.Header)
+	}
+
+	refused := &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("unauthorized"))),
+	}
+	withSession, err = sessionResponse(refused, session)
+	if err != nil || withSession != refused {
+		t.Errorf("sessionResponse(refused) = %v, %v, expected the response as is", withSession, err)
+	}
+	if _, ok, _ = takeSession(refused); ok {
+		t.Error("takeSession(refused): expected no session")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -96,6 +97,11 @@ func v0Handler(stream bool) func(w http.ResponseWriter, req *http.Request) {
 
 	return func(w http.ResponseWriter, req *http.Request) {
 		setV0Headers(w.Header())
+		encode := encode
+		if stream && acceptsEvents(req) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			encode = encodeV0Events
+		}
 
 		defer func() {
 			err := req.Body.Close()
@@ -127,6 +133,17 @@ func encodeV0(w io.Writer, resp ResponseV0) error {
 	return json.NewEncoder(w).Encode(resp)
 }
 
+func encodeV0Events(w io.Writer, resp ResponseV0) error {
+	for _, result := range resp.Results {
+		err := writeEvent(w, "result", result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeEvent(w, "done", map[string]int{"Count": len(resp.Results)})
+}
+
 func encodeV0Stream(w io.Writer, resp ResponseV0) error {
 	enc := json.NewEncoder(w)
 	for _, result := range resp.Results {
@@ -146,6 +163,11 @@ func v1Handler(stream bool) func(w http.ResponseWriter, req *http.Request) {
 	}
 	return func(w http.ResponseWriter, req *http.Request) {
 		setV1Headers(w.Header())
+		encode := encode
+		if stream && acceptsEvents(req) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			encode = encodeV1Events
+		}
 
 		defer func() {
 			err := req.Body.Close()
@@ -174,6 +196,17 @@ func encodeV1(w io.Writer, resp ResponseV1) error {
 	return json.NewEncoder(w).Encode(resp)
 }
 
+func encodeV1Events(w io.Writer, resp ResponseV1) error {
+	for _, result := range resp.Results {
+		err := writeEvent(w, "result", result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeEvent(w, "done", map[string]int{"Count": len(resp.Results)})
+}
+
 func encodeV1Stream(w io.Writer, resp ResponseV1) error {
 	enc := json.NewEncoder(w)
 	for _, result := range resp.Results {
@@ -193,6 +226,11 @@ func v2Handler(stream bool) func(w http.ResponseWriter, req *http.Request) {
 	}
 	return func(w http.ResponseWriter, req *http.Request) {
 		setV2Headers(w.Header())
+		encode := encode
+		if stream && acceptsEvents(req) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			encode = encodeV2Events
+		}
 
 		defer func() {
 			err := req.Body.Close()
@@ -228,6 +266,17 @@ func encodeV2(w io.Writer, resp ResponseV2) error {
 	return json.NewEncoder(w).Encode(resp)
 }
 
+func encodeV2Events(w io.Writer, resp ResponseV2) error {
+	for _, result := range resp.Results {
+		err := writeEvent(w, "result", result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeEvent(w, "done", map[string]int{"Count": len(resp.Results)})
+}
+
 func encodeV2Stream(w io.Writer, resp ResponseV2) error {
 	enc := json.NewEncoder(w)
 	for _, result := range resp.Results {
@@ -240,6 +289,20 @@ func encodeV2Stream(w io.Writer, resp ResponseV2) error {
 	return nil
 }
 
+func acceptsEvents(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+}
+
+func writeEvent(w io.Writer, name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+
+	return err
+}
+
 func notFoundHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(200)
 }
@@ -261,7 +324,7 @@ func main() {
 	mux := &http.ServeMux{}
 
 	flag.StringVar(&listen, "listen", "localhost:1235", "host:port to listen on")
-	flag.BoolVar(&stream, "stream", false, "return JSON stream")
+	flag.BoolVar(&stream, "stream", false, "return JSON stream (or server-sent events, if requested using the Accept header)")
 	flag.Var(&v, "version", "version of the server to run (v0, v1 or v2)")
 	flag.Parse()
 
@@ -0,0 +1,52 @@
+package bacom
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RunCommand runs command using /bin/sh, writing stdin to its standard input, and returns its standard output.
+// The command and the processes it started are killed after timeout (unless 0).
+// The error of failing commands holds their standard error.
+func RunCommand(command string, timeout time.Duration, stdin []byte) ([]byte, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+
+	err := cmd.Start()
+	if err != nil {
+		return nil, errors.Wrapf(err, "command %q", command)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case err = <-done:
+	case <-expired:
+		// the processes started by the command are killed along with it, as they would keep its output open
+		_ = killCommand(cmd)
+		<-done
+		return nil, errors.Errorf("command %q timed out after %s", command, timeout)
+	}
+
+	if err != nil && stderr.Len() != 0 {
+		return nil, errors.Wrapf(err, "command %q: %s", command, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), errors.Wrapf(err, "command %q", command)
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package bacom
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killCommand kills cmd. The processes it started are left running.
+func killCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,60 @@
+package bacom
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	for _, test := range []struct {
+		command  string
+		expected string
+		err      string
+	}{
+		{command: "tr a-z A-Z", expected: "HELLO"},
+		{command: "echo oops >&2; exit 3", err: "oops"},
+		{command: "exit 3", err: "exit status 3"},
+		{command: "sleep 5", err: "timed out after 100ms"},
+	} {
+		start := time.Now()
+		out, err := RunCommand(test.command, 100*time.Millisecond, []byte("hello"))
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("RunCommand(%q) took %s, expected to be killed after the timeout", test.command, d)
+		}
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("RunCommand(%q): expected error containing %q, got %v", test.command, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RunCommand(%q): unexpected error: %s", test.command, err)
+		} else if string(out) != test.expected {
+			t.Errorf("RunCommand(%q) = %q, expected %q", test.command, out, test.expected)
+		}
+	}
+}
+
+func TestRunCommandTimeoutChildren(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// the background process keeps the command's output open
+	start := time.Now()
+	_, err := RunCommand("sleep 5 & sleep 5", 100*time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("RunCommand: expected a timeout, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("RunCommand took %s, expected the background process to be killed", d)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running, expected %d", n, before)
+	}
+}
@@ -0,0 +1,151 @@
+package bacom
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/jaydiff/diff"
+)
+
+// Normalizers supported by ContentRule
+const (
+	NormalizeLowercase = "lowercase"
+	NormalizeUppercase = "uppercase"
+	NormalizeTrim      = "trim"
+)
+
+// ContentRule enables the comparison of the content of the scalar values matching Path
+// (using the same suffix semantics as the ignore rules).
+type ContentRule struct {
+	Path string
+	// Tolerance is the maximum absolute difference allowed between two numbers
+	Tolerance float64
+	// Normalize is a list of normalizers (lowercase, uppercase, trim) applied to strings before comparing them
+	Normalize []string
+	// Pattern is a regular expression matched against strings. When set, strings are considered equal
+	// if they both match the pattern, or if the pattern has sub-matches, if their sub-matches are equal.
+	Pattern string
+}
+
+// Validate checks the normalizers and pattern of the rule
+func (r ContentRule) Validate() error {
+	for _, n := range r.Normalize {
+		switch n {
+		default:
+			return errors.Errorf("unknown normalizer %q for %q", n, r.Path)
+		case NormalizeLowercase, NormalizeUppercase, NormalizeTrim:
+		}
+	}
+	if r.Tolerance < 0 {
+		return errors.Errorf("invalid negative tolerance for %q", r.Path)
+	}
+	if r.Pattern == "" {
+		return nil
+	}
+	_, err := regexp.Compile(r.Pattern)
+
+	return errors.Wrapf(err, "invalid pattern for %q", r.Path)
+}
+
+// Equal returns true if lhs and rhs are considered equal by the rule
+func (r ContentRule) Equal(lhs, rhs interface{}) bool {
+	switch lhs := lhs.(type) {
+	case float64:
+		rhs, ok := rhs.(float64)
+		return ok && math.Abs(lhs-rhs) <= r.Tolerance
+	case string:
+		rhs, ok := rhs.(string)
+		return ok && r.equalStrings(lhs, rhs)
+	}
+
+	return reflect.DeepEqual(lhs, rhs)
+}
+
+func (r ContentRule) equalStrings(lhs, rhs string) bool {
+	lhs, rhs = r.normalize(lhs), r.normalize(rhs)
+	if r.Pattern == "" {
+		return lhs == rhs
+	}
+
+	re, err := compilePattern(r.Pattern)
+	if err != nil {
+		return lhs == rhs
+	}
+	lhsMatch, rhsMatch := re.FindStringSubmatch(lhs), re.FindStringSubmatch(rhs)
+	switch {
+	case lhsMatch == nil && rhsMatch == nil:
+		return lhs == rhs
+	case lhsMatch == nil || rhsMatch == nil:
+		return false
+	}
+
+	return reflect.DeepEqual(lhsMatch[1:], rhsMatch[1:])
+}
+
+func (r ContentRule) normalize(s string) string {
+	for _, n := range r.Normalize {
+		switch n {
+		case NormalizeLowercase:
+			s = strings.ToLower(s)
+		case NormalizeUppercase:
+			s = strings.ToUpper(s)
+		case NormalizeTrim:
+			s = strings.TrimSpace(s)
+		}
+	}
+
+	return s
+}
+
+var patterns sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+
+	return re, nil
+}
+
+// ContentRules is a list of ContentRule. When several rules match a path, the last one is used.
+type ContentRules []ContentRule
+
+// Match returns the rule matching path
+func (rules ContentRules) Match(path string) (ContentRule, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if pathMatches([]string{rules[i].Path}, path) {
+			return rules[i], true
+		}
+	}
+
+	return ContentRule{}, false
+}
+
+// breaking returns true if the content difference d should be reported
+func (rules ContentRules) breaking(d diff.Differ, path string) bool {
+	rule, ok := rules.Match(path)
+	if !ok {
+		return false
+	}
+
+	lhs, err := diff.LHS(d)
+	if err != nil {
+		return false
+	}
+	rhs, err := diff.RHS(d)
+	if err != nil {
+		return false
+	}
+
+	return !rule.Equal(lhs, rhs)
+}
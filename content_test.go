@@ -0,0 +1,78 @@
+package bacom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContentRuleEqual(t *testing.T) {
+	for _, test := range []struct {
+		Rule     ContentRule
+		LHS, RHS interface{}
+		Expected bool
+	}{
+		{ContentRule{}, "EUR", "EUR", true},
+		{ContentRule{}, "EUR", "eur", false},
+		{ContentRule{Normalize: []string{NormalizeLowercase}}, "EUR", "eur", true},
+		{ContentRule{Normalize: []string{NormalizeTrim, NormalizeUppercase}}, " eur", "EUR ", true},
+		{ContentRule{}, 1.0, 1.001, false},
+		{ContentRule{Tolerance: 0.01}, 1.0, 1.001, true},
+		{ContentRule{Tolerance: 0.01}, 1.0, 1.1, false},
+		{ContentRule{}, true, false, false},
+		{ContentRule{Pattern: `^[0-9a-f]{4}$`}, "12ab", "ff00", true},
+		{ContentRule{Pattern: `^[0-9a-f]{4}$`}, "12ab", "12AB", false},
+		{ContentRule{Pattern: `^(\d{4}-\d{2}-\d{2})T`}, "2020-01-01T10:00:00Z", "2020-01-01T12:30:00+02:00", true},
+		{ContentRule{Pattern: `^(\d{4}-\d{2}-\d{2})T`}, "2020-01-01T10:00:00Z", "2020-01-02T10:00:00Z", false},
+	} {
+		got := test.Rule.Equal(test.LHS, test.RHS)
+		if got != test.Expected {
+			t.Errorf("%+v.Equal(%v, %v) = %v, expected %v", test.Rule, test.LHS, test.RHS, got, test.Expected)
+		}
+	}
+}
+
+func TestBodyOptionsCompareContent(t *testing.T) {
+	lhs := map[string]interface{}{
+		"currency": "EUR",
+		"price":    10.0,
+		"label":    "foo",
+		"items": []interface{}{
+			map[string]interface{}{"code": "A"},
+		},
+	}
+	rhs := map[string]interface{}{
+		"currency": "eur",
+		"price":    10.001,
+		"label":    "bar",
+		"items": []interface{}{
+			map[string]interface{}{"code": "B"},
+		},
+	}
+
+	diffs, err := BodyOptions{
+		CompareContent: []ContentRule{
+			{Path: ".currency"},
+			{Path: ".price", Tolerance: 0.01},
+			{Path: ".items[].code"},
+		},
+	}.Differences(lhs, rhs)
+	if err != nil {
+		t.Fatalf("Differences: unexpected error: %s", err)
+	}
+
+	expected := []Difference{
+		{Kind: ContentDifference, Path: ".currency", Expected: "EUR", Actual: "eur"},
+		{Kind: ContentDifference, Path: ".items[0].code", Expected: "A", Actual: "B"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Differences(...) = %+v, expected %+v", diffs, expected)
+	}
+
+	diffs, err = Differences(nil, nil, false, lhs, rhs)
+	if err != nil {
+		t.Fatalf("Differences: unexpected error: %s", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("Differences(...) = %+v, expected no differences without content rules", diffs)
+	}
+}
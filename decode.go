@@ -0,0 +1,257 @@
+package bacom
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/jaydiff/diff"
+	"github.com/yazgazan/jaydiff/jpath"
+)
+
+// BodyFormat describes how a body is decoded before being compared
+type BodyFormat string
+
+// Formats supported when decoding bodies
+const (
+	// FormatAuto selects the format using the Content-Type header
+	FormatAuto BodyFormat = "auto"
+	FormatJSON BodyFormat = "json"
+	// FormatXML decodes XML documents into trees, see DecodeXML
+	FormatXML BodyFormat = "xml"
+	// FormatForm decodes application/x-www-form-urlencoded bodies, see DecodeForm
+	FormatForm BodyFormat = "form"
+	// FormatSSE decodes text/event-stream bodies, see DecodeSSE
+	FormatSSE BodyFormat = "sse"
+	// FormatNDJSON decodes newline-delimited JSON bodies as event streams, see DecodeNDJSON
+	FormatNDJSON BodyFormat = "ndjson"
+	// FormatWebSocket decodes recorded WebSocket sessions as streams of the messages sent by the server,
+	// see DecodeWebSocketSession
+	FormatWebSocket BodyFormat = "websocket"
+	// FormatText compares bodies as text, with normalized line endings
+	FormatText BodyFormat = "text"
+	// FormatBytes compares bodies byte for byte
+	FormatBytes BodyFormat = "bytes"
+)
+
+// Validate checks that f is a known format
+func (f BodyFormat) Validate() error {
+	switch f {
+	default:
+		return errors.Errorf("unknown body format %q", f)
+	case "", FormatAuto, FormatJSON, FormatXML, FormatForm, FormatSSE, FormatNDJSON, FormatWebSocket, FormatText, FormatBytes:
+	}
+
+	return nil
+}
+
+// ContentTypeFormat returns the format matching a Content-Type header.
+// FormatAuto is returned when the content-type is unknown or missing.
+func ContentTypeFormat(contentType string) BodyFormat {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return FormatAuto
+	}
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return FormatForm
+	case mediaType == "text/event-stream":
+		return FormatSSE
+	case mediaType == "application/x-ndjson", mediaType == "application/ndjson",
+		mediaType == "application/jsonl", mediaType == "application/x-jsonlines":
+		return FormatNDJSON
+	case mediaType == WebSocketSessionContentType:
+		return FormatWebSocket
+	case strings.HasSuffix(mediaType, "json"):
+		return FormatJSON
+	case strings.HasSuffix(mediaType, "/xml"), strings.HasSuffix(mediaType, "+xml"):
+		return FormatXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return FormatText
+	case mediaType == "application/octet-stream",
+		strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"):
+		return FormatBytes
+	}
+
+	return FormatAuto
+}
+
+// RawBody is a body compared as a whole (see FormatText and FormatBytes).
+// Unlike other strings, content differences between two RawBody are always reported.
+type RawBody string
+
+// NewRawBody returns the RawBody for b. Line endings are normalized if format is FormatText.
+func NewRawBody(format BodyFormat, b []byte) RawBody {
+	if format == FormatText {
+		return RawBody(strings.Replace(string(b), "\r\n", "\n", -1))
+	}
+
+	return RawBody(b)
+}
+
+// String returns the body, quoted if it isn't valid UTF-8
+func (b RawBody) String() string {
+	if !utf8.ValidString(string(b)) {
+		return strconv.Quote(string(b))
+	}
+
+	return string(b)
+}
+
+func isRawBody(d diff.Differ) bool {
+	lhs, err := diff.LHS(d)
+	if err != nil {
+		return false
+	}
+	_, ok := lhs.(RawBody)
+
+	return ok
+}
+
+// DecodeXML decodes an XML document into a tree of maps, using the local names of the elements as keys
+// (without namespaces):
+//
+// - Elements holding only text are decoded as strings
+// - Attributes are stored under "@name" and text surrounding child elements under "#text"
+// - Repeated elements are decoded as arrays, while single elements aren't (see BodyOptions.Repeated)
+//
+// The root element is the only key of the returned map. nil is returned for empty documents.
+func DecodeXML(r io.Reader) (interface{}, error) {
+	dec := xml.NewDecoder(r)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		v, err := decodeXMLElement(dec, start)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{start.Name.Local: v}, nil
+	}
+}
+
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var text strings.Builder
+	m := map[string]interface{}{}
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		m["@"+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			addValue(m, tok.Name.Local, v)
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// DecodeForm decodes an application/x-www-form-urlencoded body into a map.
+// Repeated keys are decoded as arrays (see BodyOptions.Repeated). nil is returned for empty bodies.
+func DecodeForm(r io.Reader) (interface{}, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(values))
+	for k, vs := range values {
+		for _, v := range vs {
+			addValue(m, k, v)
+		}
+	}
+
+	return m, nil
+}
+
+// wrapRepeated returns a copy of v (found at path) in which the single values found at the paths matching repeated
+// are turned into one-element arrays
+func wrapRepeated(repeated []string, path string, v interface{}) interface{} {
+	switch v := v.(type) {
+	default:
+		return v
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = wrapRepeated(repeated, path+"["+strconv.Itoa(i)+"]", e)
+		}
+		return values
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			p := path + "." + jpath.EscapeKey(k)
+			e = wrapRepeated(repeated, p, e)
+			if _, ok := e.([]interface{}); !ok && e != nil && pathMatches(repeated, p) {
+				e = []interface{}{e}
+			}
+			m[k] = e
+		}
+		return m
+	}
+}
+
+// addValue sets m[k] to v, turning it into an array if k is already set
+func addValue(m map[string]interface{}, k string, v interface{}) {
+	existing, ok := m[k]
+	if !ok {
+		m[k] = v
+		return
+	}
+
+	if values, ok := existing.([]interface{}); ok {
+		m[k] = append(values, v)
+		return
+	}
+	m[k] = []interface{}{existing, v}
+}
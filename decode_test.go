@@ -0,0 +1,175 @@
+package bacom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContentTypeFormat(t *testing.T) {
+	for _, test := range []struct {
+		ContentType string
+		Expected    BodyFormat
+	}{
+		{"", FormatAuto},
+		{"application/json", FormatJSON},
+		{"application/problem+json; charset=utf-8", FormatJSON},
+		{"application/x-ndjson", FormatNDJSON},
+		{"text/event-stream", FormatSSE},
+		{WebSocketSessionContentType, FormatWebSocket},
+		{"text/xml; charset=utf-8", FormatXML},
+		{"application/soap+xml", FormatXML},
+		{"application/x-www-form-urlencoded", FormatForm},
+		{"text/plain", FormatText},
+		{"text/html; charset=utf-8", FormatText},
+		{"application/octet-stream", FormatBytes},
+		{"image/png", FormatBytes},
+		{"application/vnd.custom", FormatAuto},
+	} {
+		got := ContentTypeFormat(test.ContentType)
+		if got != test.Expected {
+			t.Errorf("ContentTypeFormat(%q) = %q, expected %q", test.ContentType, got, test.Expected)
+		}
+	}
+}
+
+func TestDecodeXML(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body>
+    <GetUserResponse id="42">
+      <Name>foo</Name>
+      <Role>admin</Role>
+      <Role>user</Role>
+      <Note lang="en">hello</Note>
+      <Empty/>
+    </GetUserResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+	expected := map[string]interface{}{
+		"Envelope": map[string]interface{}{
+			"Body": map[string]interface{}{
+				"GetUserResponse": map[string]interface{}{
+					"@id":   "42",
+					"Name":  "foo",
+					"Role":  []interface{}{"admin", "user"},
+					"Note":  map[string]interface{}{"@lang": "en", "#text": "hello"},
+					"Empty": "",
+				},
+			},
+		},
+	}
+
+	got, err := DecodeXML(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeXML: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DecodeXML(...) = %+v, expected %+v", got, expected)
+	}
+
+	got, err = DecodeXML(strings.NewReader(""))
+	if err != nil || got != nil {
+		t.Errorf("DecodeXML(\"\") = %v, %v, expected nil, nil", got, err)
+	}
+
+	_, err = DecodeXML(strings.NewReader("<a><b></a>"))
+	if err == nil {
+		t.Error("DecodeXML(invalid): expected error, got nil")
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	got, err := DecodeForm(strings.NewReader("name=foo&tag=a&tag=b&empty=\n"))
+	if err != nil {
+		t.Fatalf("DecodeForm: unexpected error: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"name":  "foo",
+		"tag":   []interface{}{"a", "b"},
+		"empty": "",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DecodeForm(...) = %+v, expected %+v", got, expected)
+	}
+
+	got, err = DecodeForm(strings.NewReader(""))
+	if err != nil || got != nil {
+		t.Errorf("DecodeForm(\"\") = %v, %v, expected nil, nil", got, err)
+	}
+}
+
+func TestRawBodyDifferences(t *testing.T) {
+	for _, test := range []struct {
+		LHS, RHS interface{}
+		Expected []Difference
+	}{
+		{NewRawBody(FormatText, []byte("foo\r\n")), NewRawBody(FormatText, []byte("foo\n")), nil},
+		{NewRawBody(FormatBytes, []byte("foo\r\n")), NewRawBody(FormatBytes, []byte("foo\n")), []Difference{
+			{Kind: ContentDifference, Expected: RawBody("foo\r\n"), Actual: RawBody("foo\n")},
+		}},
+		{"foo", "bar", nil},
+	} {
+		diffs, err := BodyOptions{}.Differences(test.LHS, test.RHS)
+		if err != nil {
+			t.Errorf("Differences(%v, %v): unexpected error: %s", test.LHS, test.RHS, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.Expected) {
+			t.Errorf("Differences(%v, %v) = %+v, expected %+v", test.LHS, test.RHS, diffs, test.Expected)
+		}
+	}
+
+	if s := RawBody("\xff").String(); s != `"\xff"` {
+		t.Errorf("RawBody.String() = %s, expected %q", s, `"\xff"`)
+	}
+}
+
+func TestBodyOptionsRepeated(t *testing.T) {
+	decodeXML := func(s string) interface{} {
+		v, err := DecodeXML(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("DecodeXML(%q): unexpected error: %s", s, err)
+		}
+		return v
+	}
+	decodeForm := func(s string) interface{} {
+		v, err := DecodeForm(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("DecodeForm(%q): unexpected error: %s", s, err)
+		}
+		return v
+	}
+	one := decodeXML(`<users><user><name>foo</name><tag>a</tag></user></users>`)
+	two := decodeXML(`<users><user><name>foo</name><tag>a</tag></user><user><name>bar</name><tag>b</tag><tag>c</tag></user></users>`)
+
+	for _, test := range []struct {
+		Name     string
+		Repeated []string
+		LHS, RHS interface{}
+		Expected int
+	}{
+		{Name: "xml, not configured", LHS: one, RHS: two, Expected: 1},
+		{Name: "xml", Repeated: []string{".user", ".tag"}, LHS: one, RHS: two},
+		{Name: "xml, reversed", Repeated: []string{".user", ".tag"}, LHS: two, RHS: one},
+		{Name: "xml, other path", Repeated: []string{".name"}, LHS: one, RHS: two, Expected: 1},
+		{Name: "form, not configured", LHS: decodeForm("id=1"), RHS: decodeForm("id=1&id=2"), Expected: 1},
+		{Name: "form", Repeated: []string{".id"}, LHS: decodeForm("id=1"), RHS: decodeForm("id=1&id=2")},
+	} {
+		diffs, err := BodyOptions{Repeated: test.Repeated}.Differences(test.LHS, test.RHS)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.Name, err)
+			continue
+		}
+		if len(diffs) != test.Expected {
+			t.Errorf("%s: differences = %+v, expected %d", test.Name, diffs, test.Expected)
+		}
+	}
+
+	// the decoded values are left untouched
+	if _, ok := one.(map[string]interface{})["users"].(map[string]interface{})["user"].([]interface{}); ok {
+		t.Errorf("BodyOptions.Differences modified the compared values: %v", one)
+	}
+}
@@ -0,0 +1,217 @@
+package bacom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/yazgazan/jaydiff/diff"
+)
+
+// DifferenceKind describes the nature of a Difference
+type DifferenceKind string
+
+// Kinds of differences reported by StatusDifferences, HeaderDifferences and BodyDifferences
+const (
+	StatusDifference        DifferenceKind = "status"
+	MissingHeaderDifference DifferenceKind = "missing_header"
+	HeaderContentDifference DifferenceKind = "header_content"
+	// MissingTrailerDifference and TrailerContentDifference are reported by TrailerDifferences
+	MissingTrailerDifference DifferenceKind = "missing_trailer"
+	TrailerContentDifference DifferenceKind = "trailer_content"
+	MissingKeyDifference     DifferenceKind = "missing_key"
+	// MissingElementDifference is reported for array elements without a match, see ArrayRule
+	MissingElementDifference DifferenceKind = "missing_element"
+	TypeChangeDifference     DifferenceKind = "type_change"
+	ContentDifference        DifferenceKind = "content"
+	// SpecViolationDifference is used when a response doesn't follow the API's specification
+	SpecViolationDifference DifferenceKind = "spec_violation"
+	// ValidationDifference is the default kind for differences reported by custom validators
+	ValidationDifference DifferenceKind = "validation"
+	// EventOrderDifference and EventCountDifference are reported when comparing event streams, see StreamOptions
+	EventOrderDifference DifferenceKind = "event_order"
+	EventCountDifference DifferenceKind = "event_count"
+	// UnknownEnumDifference is reported by EnumCollector for values that haven't been seen before
+	UnknownEnumDifference DifferenceKind = "unknown_enum"
+	// GraphQLErrorDifference is reported for the errors[] of GraphQL responses
+	GraphQLErrorDifference DifferenceKind = "graphql_error"
+	// SchemaBreakDifference is reported for GraphQL schema and protobuf descriptor changes breaking a stored call
+	SchemaBreakDifference DifferenceKind = "schema_break"
+	// ExtractionDifference is reported when a value captured by a scenario step can't be found in its response
+	ExtractionDifference DifferenceKind = "extraction"
+)
+
+// Difference is a single backward-incompatible change between a base (expected)
+// and a target (actual) response.
+type Difference struct {
+	Kind DifferenceKind `json:"kind" yaml:"kind"`
+	// Path is the JSON path of body differences
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Header is the name of the header for header differences
+	Header string `json:"header,omitempty" yaml:"header,omitempty"`
+	// Trailer is the name of the trailer for trailer differences
+	Trailer  string      `json:"trailer,omitempty" yaml:"trailer,omitempty"`
+	Expected interface{} `json:"expected,omitempty" yaml:"expected,omitempty"`
+	Actual   interface{} `json:"actual,omitempty" yaml:"actual,omitempty"`
+	// Message describes the difference when it cannot be expressed with Expected and Actual
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// String returns a plain-text, single line description of the difference
+func (d Difference) String() string {
+	var subject string
+
+	switch {
+	case d.Kind == StatusDifference:
+		subject = "status"
+	case d.Kind == EventOrderDifference:
+		subject = "events"
+	case d.Header != "":
+		subject = "header " + d.Header
+	case d.Trailer != "":
+		subject = "trailer " + d.Trailer
+	default:
+		subject = d.Path
+	}
+
+	switch d.Kind {
+	case MissingHeaderDifference, MissingTrailerDifference, MissingKeyDifference, MissingElementDifference:
+		return fmt.Sprintf("%s: %s missing (expected %v)", d.Kind, subject, d.Expected)
+	case SpecViolationDifference, ValidationDifference, GraphQLErrorDifference, SchemaBreakDifference, ExtractionDifference:
+		return strings.TrimSpace(fmt.Sprintf("%s: %s %s", d.Kind, subject, d.Message))
+	}
+
+	return fmt.Sprintf("%s: %s expected %v, got %v", d.Kind, subject, d.Expected, d.Actual)
+}
+
+// Lines renders the difference in a diff-like format, with the expected value
+// prefixed by "-" and the actual value by "+".
+func (d Difference) Lines(colorized bool) []string {
+	red, green := fmt.Sprint, fmt.Sprint
+	if colorized {
+		red = func(v ...interface{}) string { return color.RedString("%s", fmt.Sprint(v...)) }
+		green = func(v ...interface{}) string { return color.GreenString("%s", fmt.Sprint(v...)) }
+	}
+
+	var prefix string
+	switch {
+	case d.Kind == StatusDifference:
+		prefix = " (Status) "
+	case d.Kind == EventOrderDifference:
+		prefix = " (Events) "
+	case d.Header != "":
+		prefix = " (Header) " + d.Header + ": "
+	case d.Trailer != "":
+		prefix = " (Trailer) " + d.Trailer + ": "
+	case d.Path != "":
+		prefix = " " + d.Path + ": "
+	default:
+		prefix = " "
+	}
+
+	switch d.Kind {
+	case MissingHeaderDifference, MissingTrailerDifference, MissingKeyDifference, MissingElementDifference:
+		return []string{"-" + prefix + red(d.Expected)}
+	case SpecViolationDifference, ValidationDifference, GraphQLErrorDifference, SchemaBreakDifference, ExtractionDifference:
+		return []string{"!" + prefix + red(d.Message)}
+	}
+
+	return []string{
+		"-" + prefix + red(d.Expected),
+		"+" + prefix + green(d.Actual),
+	}
+}
+
+// StatusDifferences returns the differences between two response statuses
+func StatusDifferences(lhsCode, rhsCode int, lhs, rhs string) []Difference {
+	if lhsCode == rhsCode {
+		return nil
+	}
+
+	return []Difference{{
+		Kind:     StatusDifference,
+		Expected: lhs,
+		Actual:   rhs,
+	}}
+}
+
+// BodyDifferences returns the differences found in a diff tree, usually produced by PrunedDiff
+func BodyDifferences(d diff.Differ) ([]Difference, error) {
+	var diffs []Difference
+
+	_, err := diff.Walk(d, func(parent, d diff.Differ, path string) (diff.Differ, error) {
+		var kind DifferenceKind
+
+		switch d.Diff() {
+		default:
+			return nil, nil
+		case diff.TypesDiffer:
+			kind = TypeChangeDifference
+		case diff.ContentDiffer:
+			if _, ok := d.(diff.Walker); ok {
+				return nil, nil
+			}
+			kind = ContentDifference
+			switch {
+			case diff.IsMissing(d):
+				kind = MissingKeyDifference
+			case isMissing(d):
+				kind = MissingElementDifference
+			}
+		}
+
+		lhs, _ := diff.LHS(d)
+		rhs, _ := diff.RHS(d)
+		diffs = append(diffs, Difference{
+			Kind:     kind,
+			Path:     path,
+			Expected: lhs,
+			Actual:   rhs,
+		})
+
+		return nil, nil
+	})
+
+	return diffs, err
+}
+
+// Renderer is implemented by the output formats for differences
+type Renderer interface {
+	Render(w io.Writer, diffs []Difference) error
+}
+
+// TextRenderer renders differences as text, one line per expected and actual value
+type TextRenderer struct {
+	Colorized bool
+}
+
+// Render writes the differences to w
+func (r TextRenderer) Render(w io.Writer, diffs []Difference) error {
+	for _, d := range diffs {
+		_, err := io.WriteString(w, strings.Join(d.Lines(r.Colorized), "\n")+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JSONRenderer renders differences as a JSON array
+type JSONRenderer struct {
+	Indent string
+}
+
+// Render writes the differences to w
+func (r JSONRenderer) Render(w io.Writer, diffs []Difference) error {
+	if diffs == nil {
+		diffs = []Difference{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", r.Indent)
+
+	return enc.Encode(diffs)
+}
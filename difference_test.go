@@ -0,0 +1,90 @@
+package bacom
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBodyDifferences(t *testing.T) {
+	lhs := map[string]interface{}{
+		"foo":  "bar",
+		"fizz": 42.0,
+		"buzz": []interface{}{1.0},
+	}
+	rhs := map[string]interface{}{
+		"fizz": "42",
+		"buzz": []interface{}{2.0},
+	}
+	expected := []Difference{
+		{Kind: TypeChangeDifference, Path: ".fizz", Expected: 42.0, Actual: "42"},
+		{Kind: MissingKeyDifference, Path: ".foo", Expected: "bar"},
+	}
+
+	d, err := PrunedDiff(nil, nil, false, lhs, rhs)
+	if err != nil {
+		t.Fatalf("PrunedDiff(nil, nil, false, lhs, rhs): unexpected error: %s", err)
+	}
+	diffs, err := BodyDifferences(d)
+	if err != nil {
+		t.Errorf("BodyDifferences(d): unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("BodyDifferences(d) = %+v, expected %+v", diffs, expected)
+	}
+}
+
+func TestStatusDifferences(t *testing.T) {
+	diffs := StatusDifferences(200, 200, "200 OK", "200 OK")
+	if len(diffs) != 0 {
+		t.Errorf("StatusDifferences(200, 200, ...) = %+v, expected no differences", diffs)
+	}
+
+	expected := []Difference{
+		{Kind: StatusDifference, Expected: "200 OK", Actual: "404 Not Found"},
+	}
+	diffs = StatusDifferences(200, 404, "200 OK", "404 Not Found")
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("StatusDifferences(200, 404, ...) = %+v, expected %+v", diffs, expected)
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	diffs := []Difference{
+		{Kind: StatusDifference, Expected: "200 OK", Actual: "404 Not Found"},
+		{Kind: MissingHeaderDifference, Header: "Content-Type", Expected: "application/json"},
+		{Kind: TypeChangeDifference, Path: ".foo", Expected: 42.0, Actual: "42"},
+	}
+
+	for _, test := range []struct {
+		renderer Renderer
+		expected string
+	}{
+		{
+			renderer: TextRenderer{},
+			expected: `- (Status) 200 OK
++ (Status) 404 Not Found
+- (Header) Content-Type: application/json
+- .foo: 42
++ .foo: 42
+`,
+		},
+		{
+			renderer: JSONRenderer{},
+			expected: `[{"kind":"status","expected":"200 OK","actual":"404 Not Found"},` +
+				`{"kind":"missing_header","header":"Content-Type","expected":"application/json"},` +
+				`{"kind":"type_change","path":".foo","expected":42,"actual":"42"}]
+`,
+		},
+	} {
+		b := &bytes.Buffer{}
+
+		err := test.renderer.Render(b, diffs)
+		if err != nil {
+			t.Errorf("%T.Render(): unexpected error: %s", test.renderer, err)
+		}
+		if b.String() != test.expected {
+			t.Errorf("%T.Render() = %q, expected %q", test.renderer, b.String(), test.expected)
+		}
+	}
+}
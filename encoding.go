@@ -0,0 +1,167 @@
+package bacom
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+// Decompressor returns a reader decompressing r, for a given content-encoding
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		"gzip":    gzipDecompressor,
+		"x-gzip":  gzipDecompressor,
+		"deflate": deflateDecompressor,
+		"br":      brotliDecompressor,
+	}
+)
+
+// RegisterDecompressor makes a decompressor available for a content-encoding (such as "zstd").
+// gzip, deflate and br are supported by default.
+// It panics if a decompressor is already registered for the encoding, or if d is nil.
+func RegisterDecompressor(encoding string, d Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	encoding = strings.ToLower(encoding)
+	if d == nil {
+		panic("bacom: RegisterDecompressor decompressor is nil")
+	}
+	if _, dup := decompressors[encoding]; dup {
+		panic("bacom: RegisterDecompressor called twice for encoding " + encoding)
+	}
+	decompressors[encoding] = d
+}
+
+func getDecompressor(encoding string) (Decompressor, bool) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+
+	d, ok := decompressors[encoding]
+
+	return d, ok
+}
+
+// gzipDecompressor leaves bodies without the gzip magic number untouched, as some tools
+// (such as browsers exporting HAR files) keep the Content-Encoding header of decoded bodies.
+func gzipDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return ioutil.NopCloser(br), nil
+	}
+
+	return gzip.NewReader(br)
+}
+
+// deflateDecompressor handles zlib streams (as per RFC 7230) as well as raw deflate streams,
+// still sent by some servers.
+func deflateDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint(header[0])<<8|uint(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+func brotliDecompressor(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(r)), nil
+}
+
+// ContentEncodings returns the content-encodings applied to a body, in the order they were applied
+// (excluding identity).
+func ContentEncodings(header http.Header) []string {
+	var encodings []string
+
+	for _, v := range header["Content-Encoding"] {
+		for _, encoding := range strings.Split(v, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding == "" || encoding == "identity" {
+				continue
+			}
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
+// Decompress decodes b, encoded with the provided content-encodings (in the order they were applied)
+func Decompress(encodings []string, b []byte) ([]byte, error) {
+	for i := len(encodings) - 1; i >= 0; i-- {
+		d, ok := getDecompressor(encodings[i])
+		if !ok {
+			return nil, errors.Errorf("unsupported content-encoding %q", encodings[i])
+		}
+
+		r, err := d(bytes.NewReader(b))
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s content", encodings[i])
+		}
+		b, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s content", encodings[i])
+		}
+		err = r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s content", encodings[i])
+		}
+	}
+
+	return b, nil
+}
+
+// DecompressBody replaces the body of resp with its decompressed version, according to the
+// Content-Encoding header. The Content-Encoding header is removed and Content-Length updated.
+// The body is read entirely (making the trailers available) and closed. If the body cannot be
+// decompressed, an error is returned and the response is left unchanged.
+func DecompressBody(resp *http.Response) error {
+	encodings := ContentEncodings(resp.Header)
+	if len(encodings) == 0 || resp.Body == nil {
+		return nil
+	}
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	b, err := Decompress(encodings, raw)
+	if err != nil {
+		// the body is left untouched
+		resp.Body = ioutil.NopCloser(bytes.NewReader(raw))
+		return err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Uncompressed = true
+	// the header can be shared with a copy of the response
+	resp.Header = resp.Header.Clone()
+	resp.Header.Del("Content-Encoding")
+	if resp.Header.Get("Content-Length") != "" {
+		resp.Header.Set("Content-Length", strconv.Itoa(len(b)))
+	}
+
+	return nil
+}
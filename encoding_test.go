@@ -0,0 +1,151 @@
+package bacom
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, encoding string, b []byte) []byte {
+	var w io.WriteCloser
+	buf := &bytes.Buffer{}
+
+	switch encoding {
+	default:
+		t.Fatalf("unknown encoding %q", encoding)
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "zlib":
+		w = zlib.NewWriter(buf)
+	case "br":
+		w = brotli.NewWriter(buf)
+	case "flate":
+		var err error
+		w, err = flate.NewWriter(buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatalf("flate.NewWriter: unexpected error: %s", err)
+		}
+	}
+
+	_, err := w.Write(b)
+	if err != nil {
+		t.Fatalf("compressing with %s: unexpected error: %s", encoding, err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("compressing with %s: unexpected error: %s", encoding, err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	body := []byte(`{"foo": "bar"}`)
+
+	for _, test := range []struct {
+		Name      string
+		Encodings []string
+		Body      []byte
+		Error     bool
+	}{
+		{"none", nil, body, false},
+		{"gzip", []string{"gzip"}, compress(t, "gzip", body), false},
+		{"decoded gzip", []string{"gzip"}, body, false},
+		{"zlib", []string{"deflate"}, compress(t, "zlib", body), false},
+		{"raw deflate", []string{"deflate"}, compress(t, "flate", body), false},
+		{"deflate, gzip", []string{"deflate", "gzip"}, compress(t, "gzip", compress(t, "zlib", body)), false},
+		{"invalid deflate", []string{"deflate"}, body, true},
+		{"br", []string{"br"}, compress(t, "br", body), false},
+		{"br, gzip", []string{"br", "gzip"}, compress(t, "gzip", compress(t, "br", body)), false},
+		{"invalid br", []string{"br"}, body, true},
+		{"zstd", []string{"zstd"}, body, true},
+	} {
+		got, err := Decompress(test.Encodings, test.Body)
+		if test.Error {
+			if err == nil {
+				t.Errorf("%s: Decompress(...): expected error, got nil", test.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Decompress(...): unexpected error: %s", test.Name, err)
+			continue
+		}
+		if !bytes.Equal(got, body) {
+			t.Errorf("%s: Decompress(...) = %q, expected %q", test.Name, got, body)
+		}
+	}
+}
+
+func TestDecompressBody(t *testing.T) {
+	body := []byte(`{"foo": "bar"}`)
+	compressed := compress(t, "gzip", body)
+	header := http.Header{
+		"Content-Encoding": {"gzip"},
+		"Content-Length":   {"42"},
+	}
+	resp := &http.Response{
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(compressed)),
+		ContentLength: int64(len(compressed)),
+	}
+
+	err := DecompressBody(resp)
+	if err != nil {
+		t.Fatalf("DecompressBody: unexpected error: %s", err)
+	}
+	got, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: unexpected error: %s", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("body = %q, expected %q", got, body)
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Content-Encoding = %q, expected it to be removed", resp.Header.Get("Content-Encoding"))
+	}
+	if resp.Header.Get("Content-Length") != "14" || resp.ContentLength != 14 {
+		t.Errorf("Content-Length = %q (%d), expected 14", resp.Header.Get("Content-Length"), resp.ContentLength)
+	}
+	if header.Get("Content-Encoding") != "gzip" {
+		t.Error("DecompressBody modified the original header")
+	}
+
+	resp = &http.Response{
+		Header: http.Header{"Content-Encoding": {"zstd"}},
+		Body:   ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	err = DecompressBody(resp)
+	if err == nil {
+		t.Fatal("DecompressBody(zstd): expected error, got nil")
+	}
+	got, err = ioutil.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(got, body) {
+		t.Errorf("DecompressBody(zstd): body = %q, %v, expected it to be left untouched", got, err)
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	RegisterDecompressor("x-test", func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(r), nil
+	})
+
+	got, err := Decompress([]string{"x-test"}, []byte("foo"))
+	if err != nil || string(got) != "foo" {
+		t.Errorf("Decompress(x-test) = %q, %v, expected %q, nil", got, err, "foo")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterDecompressor(gzip): expected panic")
+		}
+	}()
+	RegisterDecompressor("gzip", gzipDecompressor)
+}
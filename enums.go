@@ -0,0 +1,106 @@
+package bacom
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/yazgazan/jaydiff/jpath"
+)
+
+// EnumCollector gathers the string values found at enum-like JSON paths (matched using the same
+// suffix semantics as the ignore rules) in sample JSON values (as decoded by encoding/json).
+// Array indexes are replaced by [] in the collected paths.
+type EnumCollector struct {
+	paths  []string
+	values map[string]map[string]bool
+}
+
+// NewEnumCollector returns an empty *EnumCollector for the provided JSON paths
+func NewEnumCollector(paths []string) *EnumCollector {
+	return &EnumCollector{
+		paths:  paths,
+		values: map[string]map[string]bool{},
+	}
+}
+
+// Add collects the enum values in v
+func (c *EnumCollector) Add(v interface{}) {
+	c.walk(v, "", "", func(path, _ string, s string) {
+		if c.values[path] == nil {
+			c.values[path] = map[string]bool{}
+		}
+		c.values[path][s] = true
+	})
+}
+
+// Enums returns the sorted values collected for each path
+func (c *EnumCollector) Enums() map[string][]string {
+	enums := make(map[string][]string, len(c.values))
+
+	for path, values := range c.values {
+		enums[path] = sortedSet(values)
+	}
+
+	return enums
+}
+
+// Unseen returns a difference for each value of v that wasn't collected before.
+// Paths for which no values have been collected are not checked.
+func (c *EnumCollector) Unseen(v interface{}) []Difference {
+	var diffs []Difference
+
+	c.walk(v, "", "", func(path, fullPath string, s string) {
+		values, ok := c.values[path]
+		if !ok || values[s] {
+			return
+		}
+		diffs = append(diffs, Difference{
+			Kind:     UnknownEnumDifference,
+			Path:     fullPath,
+			Expected: sortedSet(values),
+			Actual:   s,
+		})
+	})
+
+	return diffs
+}
+
+// walk calls fn for the strings matching the collector's paths. path has array indexes
+// replaced by [], while fullPath retains them.
+func (c *EnumCollector) walk(v interface{}, path, fullPath string, fn func(path, fullPath, s string)) {
+	switch v := v.(type) {
+	case string:
+		if pathMatches(c.paths, path) {
+			fn(path, fullPath, v)
+		}
+	case []interface{}:
+		for i, item := range v {
+			c.walk(item, path+"[]", fullPath+"["+strconv.Itoa(i)+"]", fn)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedMapKeys(v) {
+			key := "." + jpath.EscapeKey(k)
+			c.walk(v[k], path+key, fullPath+key, fn)
+		}
+	}
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	return values
+}
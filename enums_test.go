@@ -0,0 +1,49 @@
+package bacom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnumCollector(t *testing.T) {
+	c := NewEnumCollector([]string{".status", ".Items[].kind"})
+
+	c.Add(map[string]interface{}{
+		"status": "active",
+		"name":   "foo",
+		"Items": []interface{}{
+			map[string]interface{}{"kind": "a"},
+			map[string]interface{}{"kind": "b"},
+		},
+	})
+	c.Add(map[string]interface{}{
+		"status": "inactive",
+		"nested": map[string]interface{}{"status": "ok"},
+	})
+
+	expected := map[string][]string{
+		".status":        {"active", "inactive"},
+		".nested.status": {"ok"},
+		".Items[].kind":  {"a", "b"},
+	}
+	if !reflect.DeepEqual(c.Enums(), expected) {
+		t.Errorf("Enums() = %v, expected %v", c.Enums(), expected)
+	}
+
+	diffs := c.Unseen(map[string]interface{}{
+		"status": "deleted",
+		"name":   "bar",
+		"other":  map[string]interface{}{"status": "new"},
+		"Items": []interface{}{
+			map[string]interface{}{"kind": "a"},
+			map[string]interface{}{"kind": "c"},
+		},
+	})
+	expectedDiffs := []Difference{
+		{Kind: UnknownEnumDifference, Path: ".Items[1].kind", Expected: []string{"a", "b"}, Actual: "c"},
+		{Kind: UnknownEnumDifference, Path: ".status", Expected: []string{"active", "inactive"}, Actual: "deleted"},
+	}
+	if !reflect.DeepEqual(diffs, expectedDiffs) {
+		t.Errorf("Unseen(...) = %+v, expected %+v", diffs, expectedDiffs)
+	}
+}
@@ -62,6 +62,15 @@ func IsRequestFilename(fname string) bool {
 
 // GetResponseFilename transform a _req[0-9]*.txt filename into a _resp[0-9]*.txt
 func GetResponseFilename(reqFname string) (string, error) {
+	return companionFilename(reqFname, "_resp", ".txt")
+}
+
+// GetSessionFilename transform a _req[0-9]*.txt filename into the _ws[0-9]*.json file holding its WebSocket session
+func GetSessionFilename(reqFname string) (string, error) {
+	return companionFilename(reqFname, "_ws", ".json")
+}
+
+func companionFilename(reqFname, suffix, ext string) (string, error) {
 	idx := strings.LastIndex(reqFname, "_req")
 	if idx == -1 {
 		return "", ErrReqInvalidName
@@ -70,14 +79,14 @@ func GetResponseFilename(reqFname string) (string, error) {
 		return "", ErrReqInvalidName
 	}
 	if idx+len("_req.txt") == len(reqFname) {
-		return reqFname[0:idx] + "_resp.txt", nil
+		return reqFname[0:idx] + suffix + ext, nil
 	}
 	n, err := strconv.Atoi(reqFname[idx+len("_req") : len(reqFname)-len(".txt")])
 	if err != nil {
 		return "", ErrReqInvalidName
 	}
 
-	return reqFname[0:idx] + "_resp" + strconv.Itoa(n) + ".txt", nil
+	return reqFname[0:idx] + suffix + strconv.Itoa(n) + ext, nil
 }
 
 // NameFromReqFileName extracts the request name from the filename (removing the _req[0-9]*.txt suffix)
@@ -111,6 +111,26 @@ func TestGetResponseFilename(t *testing.T) {
 	}
 }
 
+func TestGetSessionFilename(t *testing.T) {
+	for _, test := range []struct {
+		In       string
+		Expected string
+		Err      error
+	}{
+		{"foo_req.txt", "foo_ws.json", nil},
+		{"foo_req3.txt", "foo_ws3.json", nil},
+		{"foo_req.go", "", ErrReqInvalidName},
+	} {
+		v, err := GetSessionFilename(test.In)
+		if v != test.Expected {
+			t.Errorf("GetSessionFilename(%q) = %q, expected %q", test.In, v, test.Expected)
+		}
+		if err != test.Err {
+			t.Errorf("GetSessionFilename(%q): error = %v, expected %v", test.In, err, test.Err)
+		}
+	}
+}
+
 func TestGetRequestsFiles(t *testing.T) {
 	testDir := filepath.Join(os.TempDir(), "TestGetRequestsFiles")
 	testFiles := []string{
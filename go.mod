@@ -1,18 +1,24 @@
 module github.com/yazgazan/bacom
 
-go 1.13
+go 1.17
 
 require (
 	github.com/BurntSushi/toml v0.3.0
 	github.com/Masterminds/semver v1.4.2
+	github.com/andybalholm/brotli v1.1.1
 	github.com/fatih/color v1.7.0
 	github.com/imdario/mergo v0.3.5
-	github.com/mattn/go-colorable v0.0.9 // indirect
-	github.com/mattn/go-isatty v0.0.3 // indirect
-	github.com/mb0/diff v0.0.0-20131118162322-d8d9a906c24d // indirect
 	github.com/pkg/errors v0.8.0
 	github.com/yazgazan/jaydiff v0.1.5
-	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4
-	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/net v0.17.0
+	golang.org/x/sync v0.1.0
 	gopkg.in/yaml.v2 v2.2.8
 )
+
+require (
+	github.com/mattn/go-colorable v0.0.9 // indirect
+	github.com/mattn/go-isatty v0.0.3 // indirect
+	github.com/mb0/diff v0.0.0-20131118162322-d8d9a906c24d // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
@@ -0,0 +1,182 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Break is a schema change breaking an operation
+type Break struct {
+	// Path is the path of the field in the response data (i.e `.user.email`)
+	Path    string
+	Message string
+}
+
+// Breaks returns the changes in schema breaking op: the fields and arguments it uses must exist, and the required
+// arguments must be set. If base is not nil, the types of the selected fields are compared with the types they had
+// in base, reporting fields becoming nullable or changing type.
+// Only the fields selected by op (including through fragments) are checked.
+func Breaks(doc *Document, op *Operation, schema, base *Schema) []Break {
+	c := &checker{
+		doc:     doc,
+		schema:  schema,
+		base:    base,
+		seen:    map[Break]bool{},
+		visited: map[string]bool{},
+	}
+
+	root := schema.Roots[op.Type]
+	if _, ok := schema.Types[root]; !ok {
+		c.report("", "schema has no %s type", op.Type)
+		return c.breaks
+	}
+	var baseRoot string
+	if base != nil {
+		baseRoot = base.Roots[op.Type]
+	}
+	c.selections("", root, baseRoot, op.Selections)
+
+	return c.breaks
+}
+
+type checker struct {
+	doc          *Document
+	schema, base *Schema
+	breaks       []Break
+	seen         map[Break]bool
+	// visited holds the fragments being checked, to avoid infinite recursions
+	visited map[string]bool
+}
+
+func (c *checker) report(path, format string, args ...interface{}) {
+	b := Break{Path: path, Message: fmt.Sprintf(format, args...)}
+	if c.seen[b] {
+		return
+	}
+	c.seen[b] = true
+	c.breaks = append(c.breaks, b)
+}
+
+// selections checks selections on the type parent (and baseParent, its counterpart in the base schema)
+func (c *checker) selections(path, parent, baseParent string, selections []Selection) {
+	for _, s := range selections {
+		switch {
+		case s.IsField():
+			c.field(path, parent, baseParent, s)
+		case s.Fragment != "":
+			f, ok := c.doc.Fragments[s.Fragment]
+			if !ok || c.visited[f.Name] {
+				continue
+			}
+			c.visited[f.Name] = true
+			c.fragment(path, parent, baseParent, f.TypeCondition, f.Selections)
+			delete(c.visited, f.Name)
+		default:
+			c.fragment(path, parent, baseParent, s.TypeCondition, s.Selections)
+		}
+	}
+}
+
+func (c *checker) fragment(path, parent, baseParent, typeCondition string, selections []Selection) {
+	if typeCondition == "" {
+		c.selections(path, parent, baseParent, selections)
+		return
+	}
+	if _, ok := c.schema.Types[typeCondition]; !ok {
+		c.report(path, "type %s was removed", typeCondition)
+		return
+	}
+	c.selections(path, typeCondition, typeCondition, selections)
+}
+
+func (c *checker) field(path, parent, baseParent string, s Selection) {
+	path += "." + s.Key()
+	if s.Name == "__typename" {
+		return
+	}
+	t, ok := c.schema.Types[parent]
+	if !ok || t.Kind == UnionKind {
+		return
+	}
+	f, ok := t.Fields[s.Name]
+	if !ok {
+		c.report(path, "field %s.%s was removed", parent, s.Name)
+		return
+	}
+
+	used := map[string]bool{}
+	for _, name := range s.Arguments {
+		used[name] = true
+		if _, ok := f.Args[name]; !ok {
+			c.report(path, "argument %s.%s(%s:) was removed", parent, s.Name, name)
+		}
+	}
+	for _, name := range argNames(f.Args) {
+		if arg := f.Args[name]; arg.Type.NonNull && !arg.HasDefault && !used[name] {
+			c.report(path, "argument %s.%s(%s:) is required", parent, s.Name, name)
+		}
+	}
+
+	baseField := c.baseField(baseParent, s.Name)
+	var baseType string
+	if baseField != nil {
+		baseType = baseField.Type.NamedType()
+		switch {
+		case !sameShape(baseField.Type, f.Type):
+			c.report(path, "field %s.%s changed type from %s to %s", parent, s.Name, baseField.Type, f.Type)
+		case becameNullable(baseField.Type, f.Type):
+			c.report(path, "field %s.%s became nullable (%s to %s)", parent, s.Name, baseField.Type, f.Type)
+		}
+	}
+
+	if len(s.Selections) != 0 {
+		c.selections(path, f.Type.NamedType(), baseType, s.Selections)
+	}
+}
+
+func (c *checker) baseField(parent, name string) *Field {
+	if c.base == nil {
+		return nil
+	}
+	t, ok := c.base.Types[parent]
+	if !ok {
+		return nil
+	}
+
+	return t.Fields[name]
+}
+
+// argNames returns the names of the arguments in order, so that breaks are reported in a stable order
+func argNames(args map[string]*Argument) []string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// sameShape returns true if a and b are the same type, ignoring non-null modifiers
+func sameShape(a, b TypeRef) bool {
+	if (a.Elem == nil) != (b.Elem == nil) {
+		return false
+	}
+	if a.Elem != nil {
+		return sameShape(*a.Elem, *b.Elem)
+	}
+
+	return a.Name == b.Name
+}
+
+// becameNullable returns true if a non-null modifier of a is missing in b. a and b must have the same shape.
+func becameNullable(a, b TypeRef) bool {
+	if a.NonNull && !b.NonNull {
+		return true
+	}
+	if a.Elem != nil {
+		return becameNullable(*a.Elem, *b.Elem)
+	}
+
+	return false
+}
@@ -0,0 +1,261 @@
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+const baseSchema = `
+"""
+The root query
+"""
+type Query {
+  user(id: ID!): User
+  "all the users"
+  users(first: Int = 10): [User!]!
+  search(text: String!): [SearchResult]
+}
+
+interface Node @key(fields: "id") {
+  id: ID!
+}
+
+type User implements Node & Entity {
+  id: ID!
+  name: String!
+  email: String
+  tags: [String!]!
+  age: Int
+}
+
+type Group {
+  name: String!
+}
+
+union SearchResult = | User | Group
+
+enum Role { ADMIN USER @deprecated }
+
+input UserFilter {
+  role: Role = USER
+}
+
+scalar Time
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+`
+
+const targetSchema = `
+schema {
+  query: Root
+}
+
+type Root {
+  user(id: ID!, tenant: String!): User
+  users(first: Int = 10, after: String): [User!]!
+  search(text: String!): [SearchResult]
+}
+
+type User {
+  id: ID!
+  name: String
+  tags: [String]!
+  age: Float
+}
+
+extend type User {
+  nickname: String
+}
+
+union SearchResult = User
+`
+
+func TestParseQuery(t *testing.T) {
+	doc, err := ParseQuery(`
+# comment
+query GetUser($id: ID! = "1", $withTags: Boolean!) @cached {
+  me: user(id: $id, filter: {roles: [ADMIN, USER], name: "a \" b"}) {
+    ...UserFields
+    tags @include(if: $withTags)
+  }
+}
+
+fragment UserFields on User {
+  id
+  ... on User { name }
+  ... @skip(if: false) { email }
+}
+
+{ users { id } }
+`)
+	if err != nil {
+		t.Fatalf("ParseQuery: unexpected error: %s", err)
+	}
+
+	op, err := doc.Operation("GetUser")
+	if err != nil {
+		t.Fatalf("Operation(GetUser): unexpected error: %s", err)
+	}
+	expected := &Operation{
+		Type: Query,
+		Name: "GetUser",
+		Selections: []Selection{
+			{
+				Alias:     "me",
+				Name:      "user",
+				Arguments: []string{"id", "filter"},
+				Selections: []Selection{
+					{Fragment: "UserFields"},
+					{Name: "tags"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(op, expected) {
+		t.Errorf("Operation(GetUser) = %+v, expected %+v", op, expected)
+	}
+
+	expectedFragment := &Fragment{
+		Name:          "UserFields",
+		TypeCondition: "User",
+		Selections: []Selection{
+			{Name: "id"},
+			{TypeCondition: "User", Selections: []Selection{{Name: "name"}}},
+			{Selections: []Selection{{Name: "email"}}},
+		},
+	}
+	if !reflect.DeepEqual(doc.Fragments["UserFields"], expectedFragment) {
+		t.Errorf("Fragments[UserFields] = %+v, expected %+v", doc.Fragments["UserFields"], expectedFragment)
+	}
+
+	if _, err = doc.Operation(""); err == nil {
+		t.Error("Operation(\"\"): expected error with multiple operations, got nil")
+	}
+
+	for _, src := range []string{
+		`{ user(id: 1 }`,
+		`query { user`,
+		`mutation { create(name: "unterminated) }`,
+		`subscription %`,
+	} {
+		if _, err = ParseQuery(src); err == nil {
+			t.Errorf("ParseQuery(%q): expected error, got nil", src)
+		}
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(baseSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: unexpected error: %s", err)
+	}
+
+	if got := s.Types["Query"].Fields["users"].Type.String(); got != "[User!]!" {
+		t.Errorf("Query.users type = %s, expected [User!]!", got)
+	}
+	if !s.Types["Query"].Fields["users"].Args["first"].HasDefault {
+		t.Error("Query.users(first:) should have a default value")
+	}
+	if got := s.Types["SearchResult"].Members; !reflect.DeepEqual(got, []string{"User", "Group"}) {
+		t.Errorf("SearchResult members = %v, expected [User Group]", got)
+	}
+	for name, kind := range map[string]string{
+		"Node":       InterfaceKind,
+		"Role":       EnumKind,
+		"UserFilter": InputKind,
+		"Time":       ScalarKind,
+	} {
+		if s.Types[name] == nil || s.Types[name].Kind != kind {
+			t.Errorf("Types[%s] = %+v, expected a %s", name, s.Types[name], kind)
+		}
+	}
+
+	s, err = ParseSchema(targetSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema: unexpected error: %s", err)
+	}
+	if s.Roots[Query] != "Root" {
+		t.Errorf("Roots[query] = %q, expected Root", s.Roots[Query])
+	}
+	if s.Types["User"].Fields["nickname"] == nil {
+		t.Error("User.nickname from the type extension is missing")
+	}
+
+	if _, err = ParseSchema(`type Query { user: }`); err == nil {
+		t.Error("ParseSchema(invalid): expected error, got nil")
+	}
+}
+
+func TestBreaks(t *testing.T) {
+	base, err := ParseSchema(baseSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema(base): unexpected error: %s", err)
+	}
+	target, err := ParseSchema(targetSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema(target): unexpected error: %s", err)
+	}
+
+	for _, test := range []struct {
+		Name     string
+		Query    string
+		Base     *Schema
+		Expected []Break
+	}{
+		{
+			Name:  "compatible",
+			Query: `{ users(first: 2) { id ...on User { __typename } } }`,
+			Base:  base,
+		},
+		{
+			Name: "removed fields and arguments",
+			Query: `query Q { me: user(id: "1") { id email ...F } users(filter: {}) { id } }
+fragment F on User { email ...F }`,
+			Expected: []Break{
+				{Path: ".me", Message: "argument Root.user(tenant:) is required"},
+				{Path: ".me.email", Message: "field User.email was removed"},
+				{Path: ".users", Message: "argument Root.users(filter:) was removed"},
+			},
+		},
+		{
+			Name:  "removed types",
+			Query: `{ search(text: "a") { ... on Group { name } ... on User { id } } }`,
+			Expected: []Break{
+				{Path: ".search", Message: "type Group was removed"},
+			},
+		},
+		{
+			Name:  "nullability and type changes",
+			Query: `{ users { name tags age } }`,
+			Base:  base,
+			Expected: []Break{
+				{Path: ".users.name", Message: "field User.name became nullable (String! to String)"},
+				{Path: ".users.tags", Message: "field User.tags became nullable ([String!]! to [String]!)"},
+				{Path: ".users.age", Message: "field User.age changed type from Int to Float"},
+			},
+		},
+		{
+			Name:  "missing root type",
+			Query: `mutation { deleteUser(id: 1) }`,
+			Expected: []Break{
+				{Message: "schema has no mutation type"},
+			},
+		},
+	} {
+		doc, err := ParseQuery(test.Query)
+		if err != nil {
+			t.Errorf("%s: ParseQuery: unexpected error: %s", test.Name, err)
+			continue
+		}
+		op, err := doc.Operation("")
+		if err != nil {
+			t.Errorf("%s: Operation: unexpected error: %s", test.Name, err)
+			continue
+		}
+
+		breaks := Breaks(doc, op, target, test.Base)
+		if !reflect.DeepEqual(breaks, test.Expected) {
+			t.Errorf("%s: Breaks(...) = %+v, expected %+v", test.Name, breaks, test.Expected)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package graphql
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokNumber
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lexer splits GraphQL documents into tokens. Ignored tokens (white space, commas and comments)
+// are skipped. String values are not unescaped, as they are never interpreted.
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, value: "...", pos: start}, nil
+	case strings.IndexByte("!$&():=@[]{|}", c) != -1:
+		l.pos++
+		return token{kind: tokPunct, value: string(c), pos: start}, nil
+	case isNameStart(c):
+		for l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || strings.IndexByte(".eE+-", l.src[l.pos]) != -1) {
+			l.pos++
+		}
+		return token{kind: tokNumber, value: l.src[start:l.pos], pos: start}, nil
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString()
+	case c == '"':
+		return l.string()
+	}
+
+	return token{}, l.errorf(start, "unexpected character %q", c)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ', c == '\t', c == '\n', c == '\r', c == ',':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case '\n', '\r':
+			return token{}, l.errorf(start, "unterminated string")
+		case '"':
+			l.pos++
+			return token{kind: tokString, value: l.src[start:l.pos], pos: start}, nil
+		}
+	}
+
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	for l.pos += 3; l.pos < len(l.src); l.pos++ {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			l.pos += 3
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: tokString, value: l.src[start:l.pos], pos: start}, nil
+		}
+	}
+
+	return token{}, l.errorf(start, "unterminated block string")
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	line := strings.Count(l.src[:pos], "\n") + 1
+
+	return errors.Wrapf(errors.Errorf(format, args...), "line %d", line)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser holds the state shared by ParseQuery and ParseSchema
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: lexer{src: src}}
+
+	return p, p.advance()
+}
+
+func (p *parser) advance() (err error) {
+	p.tok, err = p.lex.next()
+
+	return err
+}
+
+// at returns true if the current token is the punctuator or keyword s
+func (p *parser) at(s string) bool {
+	return (p.tok.kind == tokPunct || p.tok.kind == tokName) && p.tok.value == s
+}
+
+// skip consumes the current token if it is the punctuator or keyword s
+func (p *parser) skip(s string) (bool, error) {
+	if !p.at(s) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(s string) error {
+	if !p.at(s) {
+		return p.unexpected(s)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected("a name")
+	}
+	name := p.tok.value
+
+	return name, p.advance()
+}
+
+func (p *parser) unexpected(expected string) error {
+	if p.tok.kind == tokEOF {
+		return p.lex.errorf(p.tok.pos, "unexpected end of document, expected %s", expected)
+	}
+
+	return p.lex.errorf(p.tok.pos, "unexpected %q, expected %s", p.tok.value, expected)
+}
+
+// skipDescription skips the (optional) description preceding a definition
+func (p *parser) skipDescription() error {
+	if p.tok.kind != tokString {
+		return nil
+	}
+
+	return p.advance()
+}
+
+// skipValue skips a value (or a variable) without interpreting it
+func (p *parser) skipValue() error {
+	switch {
+	case p.at("$"):
+		err := p.advance()
+		if err != nil {
+			return err
+		}
+		_, err = p.name()
+		return err
+	case p.at("["):
+		err := p.advance()
+		for err == nil && !p.at("]") {
+			err = p.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+		return p.advance()
+	case p.at("{"):
+		err := p.advance()
+		for err == nil && !p.at("}") {
+			if _, err = p.name(); err != nil {
+				return err
+			}
+			if err = p.expect(":"); err != nil {
+				return err
+			}
+			err = p.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+		return p.advance()
+	case p.tok.kind == tokName, p.tok.kind == tokNumber, p.tok.kind == tokString:
+		return p.advance()
+	}
+
+	return p.unexpected("a value")
+}
+
+// arguments parses an (optional) list of arguments, returning their names
+func (p *parser) arguments() ([]string, error) {
+	ok, err := p.skip("(")
+	if !ok || err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for !p.at(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if err = p.skipValue(); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, p.advance()
+}
+
+// skipDirectives skips the directives applied to a definition or selection
+func (p *parser) skipDirectives() error {
+	for p.at("@") {
+		err := p.advance()
+		if err != nil {
+			return err
+		}
+		if _, err = p.name(); err != nil {
+			return err
+		}
+		if _, err = p.arguments(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package graphql parses GraphQL queries and schemas (SDL), to find the schema changes breaking
+// stored operations.
+// Only the parts of the documents needed to resolve the selected fields are kept: values, variables and
+// directives are skipped.
+package graphql
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Operation types
+const (
+	Query        = "query"
+	Mutation     = "mutation"
+	Subscription = "subscription"
+)
+
+// Document is a parsed GraphQL query document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation or subscription
+type Operation struct {
+	Type       string
+	Name       string
+	Selections []Selection
+}
+
+// Fragment is a named fragment definition
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+// Selection is either a field, a fragment spread or an inline fragment
+type Selection struct {
+	// Alias, Name and Arguments are set for fields
+	Alias     string
+	Name      string
+	Arguments []string
+	// Fragment is the name of the fragment spread
+	Fragment string
+	// TypeCondition is the type condition of inline fragments (empty if omitted)
+	TypeCondition string
+	Selections    []Selection
+}
+
+// IsField returns true if the selection is a field (and not a fragment)
+func (s Selection) IsField() bool {
+	return s.Name != ""
+}
+
+// Key returns the name of the field in the response (its alias, if any)
+func (s Selection) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+
+	return s.Name
+}
+
+// ParseQuery parses an executable GraphQL document
+func ParseQuery(src string) (*Document, error) {
+	doc := &Document{Fragments: map[string]*Fragment{}}
+
+	p, err := newParser(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing GraphQL query")
+	}
+
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.at("{"):
+			var op *Operation
+			op, err = p.shorthandQuery()
+			doc.Operations = append(doc.Operations, op)
+		case p.at(Query), p.at(Mutation), p.at(Subscription):
+			var op *Operation
+			op, err = p.operation()
+			doc.Operations = append(doc.Operations, op)
+		case p.at("fragment"):
+			var f *Fragment
+			f, err = p.fragment()
+			if err == nil {
+				doc.Fragments[f.Name] = f
+			}
+		default:
+			err = p.unexpected("an operation or a fragment")
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing GraphQL query")
+		}
+	}
+
+	return doc, nil
+}
+
+// Operation returns the operation named name. If name is empty, the document must contain a single operation.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, errors.Errorf("missing operation name (found %d operations)", len(d.Operations))
+		}
+		return d.Operations[0], nil
+	}
+
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+
+	return nil, errors.Errorf("unknown operation %q", name)
+}
+
+func (p *parser) shorthandQuery() (*Operation, error) {
+	selections, err := p.selectionSet()
+
+	return &Operation{Type: Query, Selections: selections}, err
+}
+
+func (p *parser) operation() (op *Operation, err error) {
+	op = &Operation{Type: p.tok.value}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokName {
+		op.Name = p.tok.value
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.skipVariableDefinitions(); err != nil {
+		return nil, err
+	}
+	if err = p.skipDirectives(); err != nil {
+		return nil, err
+	}
+	op.Selections, err = p.selectionSet()
+
+	return op, err
+}
+
+func (p *parser) skipVariableDefinitions() error {
+	ok, err := p.skip("(")
+	if !ok || err != nil {
+		return err
+	}
+
+	for !p.at(")") {
+		if err = p.skipValue(); err != nil {
+			return err
+		}
+		if err = p.expect(":"); err != nil {
+			return err
+		}
+		if _, err = p.typeRef(); err != nil {
+			return err
+		}
+		if ok, err = p.skip("="); ok && err == nil {
+			err = p.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+		if err = p.skipDirectives(); err != nil {
+			return err
+		}
+	}
+
+	return p.advance()
+}
+
+func (p *parser) fragment() (f *Fragment, err error) {
+	f = &Fragment{}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	if f.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err = p.expect("on"); err != nil {
+		return nil, err
+	}
+	if f.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err = p.skipDirectives(); err != nil {
+		return nil, err
+	}
+	f.Selections, err = p.selectionSet()
+
+	return f, err
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []Selection
+	for !p.at("}") {
+		var s Selection
+		if p.at("...") {
+			s, err = p.fragmentSelection()
+		} else {
+			s, err = p.field()
+		}
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+
+	return selections, p.advance()
+}
+
+func (p *parser) fragmentSelection() (s Selection, err error) {
+	if err = p.advance(); err != nil {
+		return s, err
+	}
+
+	switch {
+	case p.at("on"):
+		if err = p.advance(); err != nil {
+			return s, err
+		}
+		if s.TypeCondition, err = p.name(); err != nil {
+			return s, err
+		}
+	case p.tok.kind == tokName:
+		s.Fragment = p.tok.value
+		if err = p.advance(); err != nil {
+			return s, err
+		}
+		return s, p.skipDirectives()
+	}
+
+	if err = p.skipDirectives(); err != nil {
+		return s, err
+	}
+	s.Selections, err = p.selectionSet()
+
+	return s, err
+}
+
+func (p *parser) field() (s Selection, err error) {
+	if s.Name, err = p.name(); err != nil {
+		return s, err
+	}
+	ok, err := p.skip(":")
+	if ok && err == nil {
+		s.Alias = s.Name
+		s.Name, err = p.name()
+	}
+	if err != nil {
+		return s, err
+	}
+	if s.Arguments, err = p.arguments(); err != nil {
+		return s, err
+	}
+	if err = p.skipDirectives(); err != nil {
+		return s, err
+	}
+	if p.at("{") {
+		s.Selections, err = p.selectionSet()
+	}
+
+	return s, err
+}
@@ -0,0 +1,371 @@
+package graphql
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Kinds of the types defined in a schema
+const (
+	ObjectKind    = "type"
+	InterfaceKind = "interface"
+	InputKind     = "input"
+	EnumKind      = "enum"
+	UnionKind     = "union"
+	ScalarKind    = "scalar"
+)
+
+// Schema is a parsed GraphQL schema (SDL)
+type Schema struct {
+	Types map[string]*Type
+	// Roots holds the names of the root types, by operation type
+	Roots map[string]string
+}
+
+// Type is a named type of a schema
+type Type struct {
+	Name string
+	Kind string
+	// Fields holds the fields of objects and interfaces, and the input fields of input objects
+	Fields map[string]*Field
+	// Members holds the types of unions
+	Members []string
+}
+
+// Field is a field of an object, interface or input type
+type Field struct {
+	Name string
+	Type TypeRef
+	Args map[string]*Argument
+}
+
+// Argument is an argument of a field
+type Argument struct {
+	Name       string
+	Type       TypeRef
+	HasDefault bool
+}
+
+// TypeRef is a reference to a type, wrapped in lists and non-null modifiers
+type TypeRef struct {
+	Name string
+	// Elem is the type of the elements of list types
+	Elem    *TypeRef
+	NonNull bool
+}
+
+// String returns the type in GraphQL notation (i.e `[String!]!`)
+func (t TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+// NamedType returns the name of the type, without lists and non-null modifiers
+func (t TypeRef) NamedType() string {
+	if t.Elem != nil {
+		return t.Elem.NamedType()
+	}
+
+	return t.Name
+}
+
+// ParseSchema parses a GraphQL schema definition. Type extensions are merged with their definitions.
+// The root types default to Query, Mutation and Subscription unless a schema definition is present.
+func ParseSchema(src string) (*Schema, error) {
+	s := &Schema{
+		Types: map[string]*Type{},
+		Roots: map[string]string{
+			Query:        "Query",
+			Mutation:     "Mutation",
+			Subscription: "Subscription",
+		},
+	}
+
+	p, err := newParser(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing GraphQL schema")
+	}
+
+	for p.tok.kind != tokEOF {
+		err = p.skipDescription()
+		if err == nil {
+			_, err = p.skip("extend")
+		}
+		if err == nil {
+			err = p.definition(s)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing GraphQL schema")
+		}
+	}
+
+	return s, nil
+}
+
+func (p *parser) definition(s *Schema) error {
+	if p.tok.kind != tokName {
+		return p.unexpected("a definition")
+	}
+	kind := p.tok.value
+	err := p.advance()
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "schema":
+		return p.schemaDefinition(s)
+	case "directive":
+		return p.skipDirectiveDefinition()
+	case ObjectKind, InterfaceKind, InputKind, EnumKind, UnionKind, ScalarKind:
+	default:
+		return p.lex.errorf(p.tok.pos, "unknown definition %q", kind)
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	t, ok := s.Types[name]
+	if !ok {
+		t = &Type{Name: name, Kind: kind, Fields: map[string]*Field{}}
+		s.Types[name] = t
+	}
+
+	if ok, err = p.skip("implements"); ok && err == nil {
+		err = p.skipNames("&")
+	}
+	if err != nil {
+		return err
+	}
+	if err = p.skipDirectives(); err != nil {
+		return err
+	}
+
+	switch kind {
+	case ObjectKind, InterfaceKind, InputKind:
+		return p.fieldsDefinition(t)
+	case EnumKind:
+		return p.skipEnumValues()
+	case UnionKind:
+		return p.unionMembers(t)
+	}
+
+	return nil
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	err := p.skipDirectives()
+	if err != nil {
+		return err
+	}
+	ok, err := p.skip("{")
+	if !ok || err != nil {
+		return err
+	}
+
+	for !p.at("}") {
+		op, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err = p.expect(":"); err != nil {
+			return err
+		}
+		if s.Roots[op], err = p.name(); err != nil {
+			return err
+		}
+	}
+
+	return p.advance()
+}
+
+func (p *parser) skipDirectiveDefinition() error {
+	err := p.expect("@")
+	if err != nil {
+		return err
+	}
+	if _, err = p.name(); err != nil {
+		return err
+	}
+	if p.at("(") {
+		if _, err = p.argumentsDefinition(); err != nil {
+			return err
+		}
+	}
+	if _, err = p.skip("repeatable"); err != nil {
+		return err
+	}
+	if err = p.expect("on"); err != nil {
+		return err
+	}
+
+	return p.skipNames("|")
+}
+
+// skipNames skips a list of names separated by sep (with an optional leading separator)
+func (p *parser) skipNames(sep string) error {
+	_, err := p.names(sep)
+
+	return err
+}
+
+func (p *parser) names(sep string) ([]string, error) {
+	_, err := p.skip(sep)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		ok, err := p.skip(sep)
+		if !ok || err != nil {
+			return names, err
+		}
+	}
+}
+
+func (p *parser) unionMembers(t *Type) error {
+	ok, err := p.skip("=")
+	if !ok || err != nil {
+		return err
+	}
+	members, err := p.names("|")
+	t.Members = append(t.Members, members...)
+
+	return err
+}
+
+func (p *parser) skipEnumValues() error {
+	ok, err := p.skip("{")
+	if !ok || err != nil {
+		return err
+	}
+
+	for !p.at("}") {
+		if err = p.skipDescription(); err != nil {
+			return err
+		}
+		if _, err = p.name(); err != nil {
+			return err
+		}
+		if err = p.skipDirectives(); err != nil {
+			return err
+		}
+	}
+
+	return p.advance()
+}
+
+func (p *parser) fieldsDefinition(t *Type) error {
+	ok, err := p.skip("{")
+	if !ok || err != nil {
+		return err
+	}
+
+	for !p.at("}") {
+		f := &Field{}
+		if err = p.skipDescription(); err != nil {
+			return err
+		}
+		if f.Name, err = p.name(); err != nil {
+			return err
+		}
+		if p.at("(") {
+			if f.Args, err = p.argumentsDefinition(); err != nil {
+				return err
+			}
+		}
+		if err = p.expect(":"); err != nil {
+			return err
+		}
+		if f.Type, err = p.typeRef(); err != nil {
+			return err
+		}
+		// default values of input fields
+		if ok, err = p.skip("="); ok && err == nil {
+			err = p.skipValue()
+		}
+		if err != nil {
+			return err
+		}
+		if err = p.skipDirectives(); err != nil {
+			return err
+		}
+		t.Fields[f.Name] = f
+	}
+
+	return p.advance()
+}
+
+func (p *parser) argumentsDefinition() (map[string]*Argument, error) {
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+
+	args := map[string]*Argument{}
+	for !p.at(")") {
+		arg := &Argument{}
+		if err = p.skipDescription(); err != nil {
+			return nil, err
+		}
+		if arg.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if arg.HasDefault, err = p.skip("="); arg.HasDefault && err == nil {
+			err = p.skipValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.skipDirectives(); err != nil {
+			return nil, err
+		}
+		args[arg.Name] = arg
+	}
+
+	return args, p.advance()
+}
+
+func (p *parser) typeRef() (t TypeRef, err error) {
+	ok, err := p.skip("[")
+	switch {
+	case err != nil:
+		return t, err
+	case ok:
+		var elem TypeRef
+		if elem, err = p.typeRef(); err != nil {
+			return t, err
+		}
+		t.Elem = &elem
+		if err = p.expect("]"); err != nil {
+			return t, err
+		}
+	default:
+		if t.Name, err = p.name(); err != nil {
+			return t, err
+		}
+	}
+	t.NonNull, err = p.skip("!")
+
+	return t, err
+}
@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"fmt"
+)
+
+// Breaks compares the services, messages and enums of base with their definition in target, returning the
+// backward-incompatible changes: removed services, methods, messages, fields and enum values, changed method
+// signatures and changed field types. Fields are matched by number, so renamed fields (which break JSON clients)
+// are reported too.
+func Breaks(base, target *Descriptors) []string {
+	var breaks []string
+	report := func(format string, args ...interface{}) {
+		breaks = append(breaks, fmt.Sprintf(format, args...))
+	}
+
+	for _, name := range sortedKeys(base.Services) {
+		svc, ok := target.Services[name]
+		if !ok {
+			report("service %s was removed", name)
+			continue
+		}
+		for _, methodName := range sortedKeys(base.Services[name].Methods) {
+			before := base.Services[name].Methods[methodName]
+			after, ok := svc.Methods[methodName]
+			fullName := "/" + name + "/" + methodName
+			switch {
+			case !ok:
+				report("method %s was removed", fullName)
+			case before.Input != after.Input:
+				report("method %s input changed from %s to %s", fullName, before.Input, after.Input)
+			case before.Output != after.Output:
+				report("method %s output changed from %s to %s", fullName, before.Output, after.Output)
+			case before.ClientStreaming != after.ClientStreaming || before.ServerStreaming != after.ServerStreaming:
+				report("method %s streaming changed", fullName)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(base.Messages) {
+		msg, ok := target.Messages[name]
+		if !ok {
+			report("message %s was removed", name)
+			continue
+		}
+		for _, before := range base.Messages[name].Fields {
+			after := msg.field(before.Number)
+			switch {
+			case after == nil:
+				report("field %s.%s (%d) was removed", name, before.Name, before.Number)
+			case before.typeString() != after.typeString():
+				report("field %s.%s changed type from %s to %s", name, before.Name, before.typeString(), after.typeString())
+			case before.Key() != after.Key():
+				report("field %s.%s was renamed to %s", name, before.Name, after.Name)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(base.Enums) {
+		enum, ok := target.Enums[name]
+		if !ok {
+			report("enum %s was removed", name)
+			continue
+		}
+		for _, value := range sortedKeys(base.Enums[name].Values) {
+			n := base.Enums[name].Values[value]
+			if after, ok := enum.Values[value]; !ok || after != n {
+				report("enum value %s.%s (%d) was removed", name, value, n)
+			}
+		}
+	}
+
+	return breaks
+}
+
+// typeString returns the type of the field as written in .proto files (i.e `repeated pkg.User`)
+func (f Field) typeString() string {
+	t := f.Type
+	if f.TypeName != "" {
+		t = f.TypeName
+	}
+	if f.Repeated {
+		t = "repeated " + t
+	}
+
+	return t
+}
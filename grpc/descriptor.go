@@ -0,0 +1,420 @@
+// Package grpc transcodes unary gRPC calls to and from JSON using the descriptors of their services, without
+// generated code, and compares descriptors to find backward-incompatible changes.
+package grpc
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Descriptors holds the services, messages and enums of a set of .proto files, indexed by their fully-qualified
+// name (without the leading dot). Unlike descriptor sets, they are stored as JSON.
+type Descriptors struct {
+	Services map[string]*Service `json:"services,omitempty"`
+	Messages map[string]*Message `json:"messages,omitempty"`
+	Enums    map[string]*Enum    `json:"enums,omitempty"`
+}
+
+// Service is a gRPC service
+type Service struct {
+	Methods map[string]*Method `json:"methods"`
+}
+
+// Method is a method of a gRPC service
+type Method struct {
+	Input           string `json:"input"`
+	Output          string `json:"output"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+}
+
+// Unary returns true if the method is neither client nor server streaming
+func (m Method) Unary() bool {
+	return !m.ClientStreaming && !m.ServerStreaming
+}
+
+// Message is a protobuf message type
+type Message struct {
+	Fields []*Field `json:"fields"`
+	// MapEntry is set for the messages generated for map fields
+	MapEntry bool `json:"map_entry,omitempty"`
+}
+
+// Field is a field of a message
+type Field struct {
+	Name     string `json:"name"`
+	JSONName string `json:"json_name,omitempty"`
+	Number   int32  `json:"number"`
+	// Type is the name of the field's type, as used in .proto files ("string", "int64", ...).
+	// Message and enum fields use "message" and "enum", with the name of the type in TypeName.
+	Type     string `json:"type"`
+	TypeName string `json:"type_name,omitempty"`
+	Repeated bool   `json:"repeated,omitempty"`
+}
+
+// Key returns the name of the field in JSON documents
+func (f Field) Key() string {
+	if f.JSONName != "" {
+		return f.JSONName
+	}
+
+	return jsonName(f.Name)
+}
+
+// Enum is a protobuf enum type
+type Enum struct {
+	Values map[string]int32 `json:"values"`
+}
+
+// field returns the field numbered num, or nil
+func (m Message) field(num int32) *Field {
+	for _, f := range m.Fields {
+		if f.Number == num {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// name returns the name of the value v, if it is defined
+func (e Enum) name(v int32) (string, bool) {
+	for name, value := range e.Values {
+		if value == v {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// fieldTypes are the names of the field types, indexed by their number in descriptor.proto
+var fieldTypes = [...]string{
+	1: "double", 2: "float", 3: "int64", 4: "uint64", 5: "int32", 6: "fixed64", 7: "fixed32", 8: "bool",
+	9: "string", 10: "group", 11: "message", 12: "bytes", 13: "uint32", 14: "enum", 15: "sfixed32",
+	16: "sfixed64", 17: "sint32", 18: "sint64",
+}
+
+// ParseDescriptorSet reads a FileDescriptorSet, as produced by `protoc --descriptor_set_out`.
+// The files imported by the services should be included (using `--include_imports`).
+func ParseDescriptorSet(b []byte) (*Descriptors, error) {
+	d := &Descriptors{
+		Services: map[string]*Service{},
+		Messages: map[string]*Message{},
+		Enums:    map[string]*Enum{},
+	}
+
+	files, err := readFields(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing descriptor set")
+	}
+	for _, file := range files {
+		if file.num != 1 || file.typ != wireBytes {
+			continue
+		}
+		err = d.addFile(file.data)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing descriptor set")
+		}
+	}
+
+	return d, nil
+}
+
+func (d *Descriptors) addFile(b []byte) error {
+	fields, err := readFields(b)
+	if err != nil {
+		return err
+	}
+
+	var pkg string
+	for _, f := range fields {
+		if f.num == 2 && f.typ == wireBytes {
+			pkg = string(f.data)
+		}
+	}
+
+	for _, f := range fields {
+		switch {
+		case f.typ != wireBytes:
+		case f.num == 4:
+			err = d.addMessage(pkg, f.data)
+		case f.num == 5:
+			err = d.addEnum(pkg, f.data)
+		case f.num == 6:
+			err = d.addService(pkg, f.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+
+	return scope + "." + name
+}
+
+func (d *Descriptors) addMessage(scope string, b []byte) error {
+	fields, err := readFields(b)
+	if err != nil {
+		return err
+	}
+
+	msg := &Message{}
+	name := qualify(scope, stringField(fields, 1))
+	for _, f := range fields {
+		switch {
+		case f.typ != wireBytes:
+		case f.num == 2:
+			var field *Field
+			field, err = parseField(f.data)
+			msg.Fields = append(msg.Fields, field)
+		case f.num == 3:
+			err = d.addMessage(name, f.data)
+		case f.num == 4:
+			err = d.addEnum(name, f.data)
+		case f.num == 7:
+			var options []wireField
+			options, err = readFields(f.data)
+			msg.MapEntry = boolField(options, 7)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	d.Messages[name] = msg
+
+	return nil
+}
+
+func parseField(b []byte) (*Field, error) {
+	fields, err := readFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Field{
+		Name:     stringField(fields, 1),
+		JSONName: stringField(fields, 10),
+		Number:   int32(varintField(fields, 3)),
+		TypeName: strings.TrimPrefix(stringField(fields, 6), "."),
+		Repeated: varintField(fields, 4) == 3,
+	}
+	if t := varintField(fields, 5); t < uint64(len(fieldTypes)) {
+		f.Type = fieldTypes[t]
+	}
+	if f.Type == "" {
+		return nil, errors.Errorf("field %q: unknown type %d", f.Name, varintField(fields, 5))
+	}
+
+	return f, nil
+}
+
+func (d *Descriptors) addEnum(scope string, b []byte) error {
+	fields, err := readFields(b)
+	if err != nil {
+		return err
+	}
+
+	enum := &Enum{Values: map[string]int32{}}
+	for _, f := range fields {
+		if f.num != 2 || f.typ != wireBytes {
+			continue
+		}
+		value, err := readFields(f.data)
+		if err != nil {
+			return err
+		}
+		enum.Values[stringField(value, 1)] = int32(varintField(value, 2))
+	}
+	d.Enums[qualify(scope, stringField(fields, 1))] = enum
+
+	return nil
+}
+
+func (d *Descriptors) addService(pkg string, b []byte) error {
+	fields, err := readFields(b)
+	if err != nil {
+		return err
+	}
+
+	svc := &Service{Methods: map[string]*Method{}}
+	for _, f := range fields {
+		if f.num != 2 || f.typ != wireBytes {
+			continue
+		}
+		method, err := readFields(f.data)
+		if err != nil {
+			return err
+		}
+		svc.Methods[stringField(method, 1)] = &Method{
+			Input:           strings.TrimPrefix(stringField(method, 2), "."),
+			Output:          strings.TrimPrefix(stringField(method, 3), "."),
+			ClientStreaming: boolField(method, 5),
+			ServerStreaming: boolField(method, 6),
+		}
+	}
+	d.Services[qualify(pkg, stringField(fields, 1))] = svc
+
+	return nil
+}
+
+func stringField(fields []wireField, num int32) string {
+	var s string
+
+	for _, f := range fields {
+		if f.num == num && f.typ == wireBytes {
+			s = string(f.data)
+		}
+	}
+
+	return s
+}
+
+func varintField(fields []wireField, num int32) uint64 {
+	var v uint64
+
+	for _, f := range fields {
+		if f.num == num && f.typ == wireVarint {
+			v = f.v
+		}
+	}
+
+	return v
+}
+
+func boolField(fields []wireField, num int32) bool {
+	return varintField(fields, num) != 0
+}
+
+// Method returns the method called using the HTTP/2 path fullMethod (`/package.Service/Method`)
+func (d *Descriptors) Method(fullMethod string) (*Method, error) {
+	parts := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(parts) != 2 {
+		return nil, errors.Errorf("invalid gRPC method %q", fullMethod)
+	}
+
+	svc, ok := d.Services[parts[0]]
+	if !ok {
+		return nil, errors.Errorf("unknown gRPC service %q", parts[0])
+	}
+	m, ok := svc.Methods[parts[1]]
+	if !ok {
+		return nil, errors.Errorf("unknown gRPC method %q", fullMethod)
+	}
+
+	return m, nil
+}
+
+// Subset returns the descriptors needed to call fullMethod: its service (with this method only) and the messages
+// and enums used by its input and output, recursively.
+func (d *Descriptors) Subset(fullMethod string) (*Descriptors, error) {
+	m, err := d.Method(fullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	sub := &Descriptors{
+		Services: map[string]*Service{svc[0]: {Methods: map[string]*Method{svc[1]: m}}},
+		Messages: map[string]*Message{},
+		Enums:    map[string]*Enum{},
+	}
+	err = d.addMessageTo(sub, m.Input)
+	if err == nil {
+		err = d.addMessageTo(sub, m.Output)
+	}
+
+	return sub, err
+}
+
+func (d *Descriptors) addMessageTo(sub *Descriptors, name string) error {
+	if _, ok := sub.Messages[name]; ok {
+		return nil
+	}
+	msg, ok := d.Messages[name]
+	if !ok {
+		return errors.Errorf("unknown message %q", name)
+	}
+	sub.Messages[name] = msg
+
+	for _, f := range msg.Fields {
+		switch f.Type {
+		case "message":
+			if err := d.addMessageTo(sub, f.TypeName); err != nil {
+				return err
+			}
+		case "enum":
+			enum, ok := d.Enums[f.TypeName]
+			if !ok {
+				return errors.Errorf("unknown enum %q", f.TypeName)
+			}
+			sub.Enums[f.TypeName] = enum
+		}
+	}
+
+	return nil
+}
+
+// jsonName converts a field name to lowerCamelCase, as protoc does when json_name isn't set
+func jsonName(name string) string {
+	var b strings.Builder
+
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch m := m.(type) {
+	case map[string]*Service:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*Method:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*Message:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*Enum:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]int32:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Content types of gRPC calls
+const (
+	// ContentType is the content-type of the messages sent over the wire
+	ContentType = "application/grpc"
+	// JSONContentType is used for the JSON-transcoded responses stored in response files
+	JSONContentType = "application/grpc+json"
+	// CallContentType is used for the unary calls stored in request files, see Call
+	CallContentType = "application/vnd.bacom.grpc-call+json"
+)
+
+// Call is the content of the request files of unary gRPC calls: the JSON-transcoded request message and the
+// descriptors of the method (see Descriptors.Subset). The method is the path of the request.
+type Call struct {
+	Message     interface{}  `json:"message"`
+	Descriptors *Descriptors `json:"descriptors"`
+}
+
+// ReadMessages reads the length-prefixed messages of a gRPC request or response body. Compressed messages are
+// decompressed if encoding (the grpc-encoding header) is gzip.
+func ReadMessages(r io.Reader, encoding string) ([][]byte, error) {
+	var messages [][]byte
+
+	for {
+		var prefix [5]byte
+		_, err := io.ReadFull(r, prefix[:])
+		if err == io.EOF {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading gRPC message")
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+		_, err = io.ReadFull(r, msg)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading gRPC message")
+		}
+		if prefix[0] == 1 {
+			msg, err = decompress(encoding, msg)
+			if err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func decompress(encoding string, msg []byte) ([]byte, error) {
+	if encoding != "gzip" {
+		return nil, errors.Errorf("unsupported gRPC message encoding %q", encoding)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(msg))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompressing gRPC message")
+	}
+	msg, err = ioutil.ReadAll(r)
+
+	return msg, errors.Wrap(err, "decompressing gRPC message")
+}
+
+// WriteMessage writes an uncompressed, length-prefixed message
+func WriteMessage(w io.Writer, msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+
+	_, err := w.Write(append(prefix[:], msg...))
+
+	return err
+}
@@ -0,0 +1,254 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+
+	return b
+}
+
+func str(num int32, s string) []byte {
+	return appendBytes(nil, num, []byte(s))
+}
+
+func varint(num int32, v uint64) []byte {
+	return appendVarint(appendTag(nil, num, wireVarint), v)
+}
+
+func sub(num int32, parts ...[]byte) []byte {
+	return appendBytes(nil, num, concat(parts...))
+}
+
+// field encodes a FieldDescriptorProto
+func field(name string, num int32, typ uint64, repeated bool, typeName string) []byte {
+	label := uint64(1)
+	if repeated {
+		label = 3
+	}
+	b := concat(str(1, name), varint(3, uint64(num)), varint(4, label), varint(5, typ), str(10, jsonName(name)))
+	if typeName != "" {
+		b = append(b, str(6, typeName)...)
+	}
+
+	return sub(2, b)
+}
+
+// usersDescriptorSet returns a FileDescriptorSet equivalent to:
+//
+//	package users;
+//	service Users { rpc GetUser(GetUserRequest) returns (User); }
+//	message GetUserRequest { int64 id = 1; string user_name = 2; }
+//	message User {
+//	  string name = 1; int64 id = 2; repeated int32 scores = 3; Role role = 4; map<string, int32> counts = 5;
+//	  bytes avatar = 6; sint32 delta = 7; double ratio = 8; Address address = 9;
+//	}
+//	message Address { string city = 1; }
+//	enum Role { UNKNOWN = 0; ADMIN = 1; }
+func usersDescriptorSet() []byte {
+	user := sub(4,
+		str(1, "User"),
+		field("name", 1, 9, false, ""),
+		field("id", 2, 3, false, ""),
+		field("scores", 3, 5, true, ""),
+		field("role", 4, 14, false, ".users.Role"),
+		field("counts", 5, 11, true, ".users.User.CountsEntry"),
+		field("avatar", 6, 12, false, ""),
+		field("delta", 7, 17, false, ""),
+		field("ratio", 8, 1, false, ""),
+		field("address", 9, 11, false, ".users.Address"),
+		sub(3,
+			str(1, "CountsEntry"),
+			field("key", 1, 9, false, ""),
+			field("value", 2, 5, false, ""),
+			sub(7, varint(7, 1)),
+		),
+	)
+	file := sub(1,
+		str(1, "users.proto"),
+		str(2, "users"),
+		sub(4, str(1, "GetUserRequest"), field("id", 1, 3, false, ""), field("user_name", 2, 9, false, "")),
+		user,
+		sub(4, str(1, "Address"), field("city", 1, 9, false, "")),
+		sub(5, str(1, "Role"), sub(2, str(1, "UNKNOWN"), varint(2, 0)), sub(2, str(1, "ADMIN"), varint(2, 1))),
+		sub(6, str(1, "Users"), sub(2,
+			str(1, "GetUser"), str(2, ".users.GetUserRequest"), str(3, ".users.User"),
+		)),
+	)
+
+	return file
+}
+
+func TestParseDescriptorSet(t *testing.T) {
+	d, err := ParseDescriptorSet(usersDescriptorSet())
+	if err != nil {
+		t.Fatalf("ParseDescriptorSet: unexpected error: %s", err)
+	}
+
+	m, err := d.Method("/users.Users/GetUser")
+	if err != nil {
+		t.Fatalf("Method: unexpected error: %s", err)
+	}
+	if expected := (Method{Input: "users.GetUserRequest", Output: "users.User"}); *m != expected {
+		t.Errorf("Method(...) = %+v, expected %+v", *m, expected)
+	}
+	if !d.Messages["users.User.CountsEntry"].MapEntry {
+		t.Error("users.User.CountsEntry should be a map entry")
+	}
+	if f := d.Messages["users.GetUserRequest"].field(2); f == nil || f.Key() != "userName" {
+		t.Errorf("users.GetUserRequest field 2 = %+v, expected userName", f)
+	}
+
+	if _, err = d.Method("/users.Users/DeleteUser"); err == nil {
+		t.Error("Method(DeleteUser): expected error, got nil")
+	}
+	if _, err = ParseDescriptorSet([]byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Error("ParseDescriptorSet(truncated): expected error, got nil")
+	}
+
+	sub, err := d.Subset("/users.Users/GetUser")
+	if err != nil {
+		t.Fatalf("Subset: unexpected error: %s", err)
+	}
+	if len(sub.Messages) != 4 || len(sub.Enums) != 1 || len(sub.Services) != 1 {
+		t.Errorf("Subset(...) = %+v, expected 4 messages, 1 enum and 1 service", sub)
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	d, err := ParseDescriptorSet(usersDescriptorSet())
+	if err != nil {
+		t.Fatalf("ParseDescriptorSet: unexpected error: %s", err)
+	}
+
+	user := map[string]interface{}{
+		"name":    "foo",
+		"id":      "9007199254740993",
+		"scores":  []interface{}{1.0, -2.0},
+		"role":    "ADMIN",
+		"counts":  map[string]interface{}{"a": 1.0, "b": 0.0},
+		"avatar":  "AAE=",
+		"delta":   -3.0,
+		"ratio":   0.5,
+		"address": map[string]interface{}{"city": "Paris"},
+	}
+
+	b, err := d.Encode("users.User", user)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %s", err)
+	}
+	got, err := d.Decode("users.User", b)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, user) {
+		t.Errorf("Decode(Encode(%+v)) = %+v", user, got)
+	}
+
+	// packed scores, an unknown field and an unknown enum value
+	b = concat(appendBytes(nil, 3, []byte{1, 2}), varint(42, 1), varint(4, 7))
+	got, err = d.Decode("users.User", b)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{"scores": []interface{}{1.0, 2.0}, "role": 7.0}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Decode(packed) = %+v, expected %+v", got, expected)
+	}
+
+	b, err = d.Encode("users.GetUserRequest", map[string]interface{}{"id": 1.0, "user_name": "foo"})
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %s", err)
+	}
+	if expected := concat(varint(1, 1), str(2, "foo")); !bytes.Equal(b, expected) {
+		t.Errorf("Encode(GetUserRequest) = %v, expected %v", b, expected)
+	}
+
+	for _, v := range []map[string]interface{}{
+		{"name": 1.0},
+		{"role": "OWNER"},
+		{"scores": 1.0},
+		{"id": "one"},
+	} {
+		if _, err = d.Encode("users.User", v); err == nil {
+			t.Errorf("Encode(%+v): expected error, got nil", v)
+		}
+	}
+	if _, err = d.Decode("users.User", str(2, "foo")); err == nil {
+		t.Error("Decode(wrong wire type): expected error, got nil")
+	}
+}
+
+func TestMessages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, msg := range [][]byte{[]byte("foo"), {}} {
+		if err := WriteMessage(buf, msg); err != nil {
+			t.Fatalf("WriteMessage: unexpected error: %s", err)
+		}
+	}
+	compressed := &bytes.Buffer{}
+	w := gzip.NewWriter(compressed)
+	_, _ = w.Write([]byte("bar"))
+	_ = w.Close()
+	buf.Write([]byte{1, 0, 0, 0, byte(compressed.Len())})
+	buf.Write(compressed.Bytes())
+
+	messages, err := ReadMessages(bytes.NewReader(buf.Bytes()), "gzip")
+	if err != nil {
+		t.Fatalf("ReadMessages: unexpected error: %s", err)
+	}
+	expected := [][]byte{[]byte("foo"), {}, []byte("bar")}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("ReadMessages(...) = %q, expected %q", messages, expected)
+	}
+
+	if _, err = ReadMessages(bytes.NewReader(buf.Bytes()), "snappy"); err == nil {
+		t.Error("ReadMessages(snappy): expected error, got nil")
+	}
+	if _, err = ReadMessages(bytes.NewReader([]byte{0, 0, 0, 0, 4, 1}), ""); err == nil {
+		t.Error("ReadMessages(truncated): expected error, got nil")
+	}
+}
+
+func TestBreaks(t *testing.T) {
+	base, err := ParseDescriptorSet(usersDescriptorSet())
+	if err != nil {
+		t.Fatalf("ParseDescriptorSet: unexpected error: %s", err)
+	}
+	target, err := ParseDescriptorSet(usersDescriptorSet())
+	if err != nil {
+		t.Fatalf("ParseDescriptorSet: unexpected error: %s", err)
+	}
+
+	if breaks := Breaks(base, target); len(breaks) != 0 {
+		t.Errorf("Breaks(identical) = %q, expected none", breaks)
+	}
+
+	user := target.Messages["users.User"]
+	user.Fields = user.Fields[1:]
+	user.Fields[0] = &Field{Name: "id", Number: 2, Type: "string"}
+	user.Fields[1] = &Field{Name: "points", Number: 3, Type: "int32", Repeated: true}
+	delete(target.Enums["users.Role"].Values, "ADMIN")
+	delete(target.Messages, "users.Address")
+	target.Services["users.Users"].Methods["GetUser"] = &Method{Input: "users.GetUserRequest", Output: "users.Address"}
+
+	expected := []string{
+		"method /users.Users/GetUser output changed from users.User to users.Address",
+		"message users.Address was removed",
+		"field users.User.name (1) was removed",
+		"field users.User.id changed type from int64 to string",
+		"field users.User.scores was renamed to points",
+		"enum value users.Role.ADMIN (1) was removed",
+	}
+	if breaks := Breaks(base, target); !reflect.DeepEqual(breaks, expected) {
+		t.Errorf("Breaks(...) = %q, expected %q", breaks, expected)
+	}
+}
@@ -0,0 +1,453 @@
+package grpc
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Decode transcodes an encoded message of type name to JSON (as decoded by encoding/json), following the proto3
+// JSON mapping: fields are named using their JSON name, 64 bits integers are encoded as strings, enums using
+// the name of their values and bytes in base64. Unknown fields are ignored, and fields set to their default value
+// are omitted (as they are absent from the encoded message).
+func (d *Descriptors) Decode(name string, b []byte) (map[string]interface{}, error) {
+	msg, ok := d.Messages[name]
+	if !ok {
+		return nil, errors.Errorf("unknown message %q", name)
+	}
+	fields, err := readFields(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", name)
+	}
+
+	out := map[string]interface{}{}
+	for _, wf := range fields {
+		f := msg.field(wf.num)
+		if f == nil {
+			continue
+		}
+		err = d.decodeField(out, f, wf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s.%s", name, f.Name)
+		}
+	}
+
+	return out, nil
+}
+
+func (d *Descriptors) decodeField(out map[string]interface{}, f *Field, wf wireField) error {
+	key := f.Key()
+
+	if f.Repeated && wf.typ == wireBytes && packable(f.Type) {
+		values, err := d.decodePacked(f, wf.data)
+		if err != nil {
+			return err
+		}
+		existing, _ := out[key].([]interface{})
+		out[key] = append(existing, values...)
+		return nil
+	}
+
+	v, err := d.decodeValue(f, wf)
+	if err != nil {
+		return err
+	}
+
+	if entry, ok := d.Messages[f.TypeName]; ok && entry.MapEntry && f.Type == "message" {
+		m, _ := out[key].(map[string]interface{})
+		if m == nil {
+			m = map[string]interface{}{}
+			out[key] = m
+		}
+		kv := v.(map[string]interface{})
+		var k, value interface{} = "", nil
+		for _, ef := range entry.Fields {
+			switch ef.Number {
+			case 1:
+				k = kv[ef.Key()]
+				if k == nil {
+					k = zeroValue(ef)
+				}
+			case 2:
+				value = kv[ef.Key()]
+				if value == nil {
+					value = zeroValue(ef)
+				}
+			}
+		}
+		m[fmt.Sprint(k)] = value
+		return nil
+	}
+
+	switch {
+	case f.Repeated:
+		existing, _ := out[key].([]interface{})
+		out[key] = append(existing, v)
+	case f.Type == "message" && out[key] != nil:
+		// repeated occurrences of a message field are merged
+		for k, fv := range v.(map[string]interface{}) {
+			out[key].(map[string]interface{})[k] = fv
+		}
+	default:
+		out[key] = v
+	}
+
+	return nil
+}
+
+// packable returns true for the scalar types that can be encoded as packed repeated fields
+func packable(t string) bool {
+	switch t {
+	case "string", "bytes", "message", "group":
+		return false
+	}
+
+	return true
+}
+
+// wireType returns the wire type of the values of type t
+func wireType(t string) int {
+	switch t {
+	case "double", "fixed64", "sfixed64":
+		return wireFixed64
+	case "float", "fixed32", "sfixed32":
+		return wireFixed32
+	case "string", "bytes", "message":
+		return wireBytes
+	case "group":
+		return wireStartGroup
+	}
+
+	return wireVarint
+}
+
+func (d *Descriptors) decodePacked(f *Field, b []byte) ([]interface{}, error) {
+	var values []interface{}
+
+	for len(b) != 0 {
+		wf := wireField{num: f.Number, typ: wireType(f.Type)}
+		switch wf.typ {
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			wf.v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			wf.v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			v, n, err := readVarint(b)
+			if err != nil {
+				return nil, err
+			}
+			wf.v, b = v, b[n:]
+		}
+
+		v, err := d.decodeValue(f, wf)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func (d *Descriptors) decodeValue(f *Field, wf wireField) (interface{}, error) {
+	if expected := wireType(f.Type); wf.typ != expected {
+		return nil, errors.Errorf("unexpected wire type %d for a %s field", wf.typ, f.Type)
+	}
+
+	switch f.Type {
+	case "double":
+		return jsonFloat(math.Float64frombits(wf.v)), nil
+	case "float":
+		return jsonFloat(float64(math.Float32frombits(uint32(wf.v)))), nil
+	case "int64", "sfixed64":
+		return strconv.FormatInt(int64(wf.v), 10), nil
+	case "sint64":
+		return strconv.FormatInt(int64(wf.v>>1)^-int64(wf.v&1), 10), nil
+	case "uint64", "fixed64":
+		return strconv.FormatUint(wf.v, 10), nil
+	case "int32", "sfixed32":
+		return float64(int32(wf.v)), nil
+	case "sint32":
+		return float64(int32(uint32(wf.v)>>1) ^ -int32(wf.v&1)), nil
+	case "uint32", "fixed32":
+		return float64(uint32(wf.v)), nil
+	case "bool":
+		return wf.v != 0, nil
+	case "string":
+		return string(wf.data), nil
+	case "bytes":
+		return base64.StdEncoding.EncodeToString(wf.data), nil
+	case "enum":
+		if enum, ok := d.Enums[f.TypeName]; ok {
+			if name, ok := enum.name(int32(wf.v)); ok {
+				return name, nil
+			}
+		}
+		return float64(int32(wf.v)), nil
+	case "message":
+		return d.Decode(f.TypeName, wf.data)
+	}
+
+	return nil, errors.Errorf("unsupported field type %s", f.Type)
+}
+
+func jsonFloat(v float64) interface{} {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+
+	return v
+}
+
+// zeroValue returns the JSON representation of the default value of the field's type
+func zeroValue(f *Field) interface{} {
+	switch f.Type {
+	case "string", "bytes":
+		return ""
+	case "bool":
+		return false
+	case "int64", "sfixed64", "sint64", "uint64", "fixed64":
+		return "0"
+	case "message":
+		return map[string]interface{}{}
+	}
+
+	return 0.0
+}
+
+// Encode transcodes v, the JSON representation of a message of type name (see Decode), to the protobuf
+// encoding. Fields can be named using either their JSON or their original name. Unknown fields are ignored.
+func (d *Descriptors) Encode(name string, v interface{}) ([]byte, error) {
+	msg, ok := d.Messages[name]
+	if !ok {
+		return nil, errors.Errorf("unknown message %q", name)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("encoding %s: expected an object, got %T", name, v)
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	for _, f := range msg.Fields {
+		fv, ok := m[f.Key()]
+		if !ok {
+			fv = m[f.Name]
+		}
+		if fv == nil {
+			continue
+		}
+
+		b, err = d.encodeField(b, f, fv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding %s.%s", name, f.Name)
+		}
+	}
+
+	return b, nil
+}
+
+func (d *Descriptors) encodeField(b []byte, f *Field, v interface{}) ([]byte, error) {
+	if entry, ok := d.Messages[f.TypeName]; ok && entry.MapEntry && f.Type == "message" {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected an object, got %T", v)
+		}
+		for _, k := range sortedKeys(m) {
+			var fields []byte
+			for _, ef := range entry.Fields {
+				var ev interface{} = k
+				switch {
+				case ef.Number == 2:
+					ev = m[k]
+				case ef.Type == "bool":
+					ev = k == "true"
+				}
+				var err error
+				fields, err = d.encodeValue(fields, ef, ev)
+				if err != nil {
+					return nil, err
+				}
+			}
+			b = appendBytes(b, f.Number, fields)
+		}
+		return b, nil
+	}
+
+	if !f.Repeated {
+		return d.encodeValue(b, f, v)
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("expected an array, got %T", v)
+	}
+	for _, value := range values {
+		var err error
+		b, err = d.encodeValue(b, f, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (d *Descriptors) encodeValue(b []byte, f *Field, v interface{}) ([]byte, error) {
+	switch f.Type {
+	case "double":
+		n, err := toFloat(v)
+		return appendFixed64(appendTag(b, f.Number, wireFixed64), math.Float64bits(n)), err
+	case "float":
+		n, err := toFloat(v)
+		return appendFixed32(appendTag(b, f.Number, wireFixed32), math.Float32bits(float32(n))), err
+	case "int64", "int32", "uint64", "uint32", "sint64", "sint32", "bool":
+		n, err := toVarint(f.Type, v)
+		return appendVarint(appendTag(b, f.Number, wireVarint), n), err
+	case "fixed64", "sfixed64":
+		n, err := toVarint(f.Type, v)
+		return appendFixed64(appendTag(b, f.Number, wireFixed64), n), err
+	case "fixed32", "sfixed32":
+		n, err := toVarint(f.Type, v)
+		return appendFixed32(appendTag(b, f.Number, wireFixed32), uint32(n)), err
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("expected a string, got %T", v)
+		}
+		return appendBytes(b, f.Number, []byte(s)), nil
+	case "bytes":
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("expected a base64 string, got %T", v)
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			data, err = base64.URLEncoding.DecodeString(s)
+		}
+		return appendBytes(b, f.Number, data), err
+	case "enum":
+		n, err := d.enumValue(f.TypeName, v)
+		return appendVarint(appendTag(b, f.Number, wireVarint), uint64(int64(n))), err
+	case "message":
+		data, err := d.Encode(f.TypeName, v)
+		return appendBytes(b, f.Number, data), err
+	}
+
+	return nil, errors.Errorf("unsupported field type %s", f.Type)
+}
+
+func (d *Descriptors) enumValue(name string, v interface{}) (int32, error) {
+	if s, ok := v.(string); ok {
+		enum, ok := d.Enums[name]
+		if !ok {
+			return 0, errors.Errorf("unknown enum %q", name)
+		}
+		n, ok := enum.Values[s]
+		if !ok {
+			return 0, errors.Errorf("unknown %s value %q", name, s)
+		}
+		return n, nil
+	}
+	n, err := toFloat(v)
+
+	return int32(n), err
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		switch v {
+		case "NaN":
+			return math.NaN(), nil
+		case "Infinity":
+			return math.Inf(1), nil
+		case "-Infinity":
+			return math.Inf(-1), nil
+		}
+		return strconv.ParseFloat(v, 64)
+	}
+
+	return 0, errors.Errorf("expected a number, got %T", v)
+}
+
+// toVarint returns the wire representation of an integer or boolean value of type t
+func toVarint(t string, v interface{}) (uint64, error) {
+	if t == "bool" {
+		b, ok := v.(bool)
+		if !ok {
+			return 0, errors.Errorf("expected a boolean, got %T", v)
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	var n int64
+	switch v := v.(type) {
+	case float64:
+		n = int64(v)
+		if t == "uint64" || t == "fixed64" {
+			return uint64(v), nil
+		}
+	case string:
+		if t == "uint64" || t == "fixed64" {
+			return strconv.ParseUint(v, 10, 64)
+		}
+		var err error
+		n, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, errors.Errorf("expected a number, got %T", v)
+	}
+
+	switch t {
+	case "sint64":
+		return uint64(n<<1) ^ uint64(n>>63), nil
+	case "sint32":
+		return uint64(uint32(int32(n)<<1) ^ uint32(int32(n)>>31)), nil
+	case "uint32", "fixed32", "sfixed32":
+		return uint64(uint32(n)), nil
+	}
+
+	return uint64(n), nil
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+
+	return append(b, buf[:]...)
+}
+
+func appendFixed32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,160 @@
+package grpc
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// wireField is a single field read from an encoded message. v holds the value of varint and fixed
+// fields, data the content of length-delimited fields.
+type wireField struct {
+	num  int32
+	typ  int
+	v    uint64
+	data []byte
+}
+
+func readVarint(b []byte) (uint64, int, error) {
+	var v uint64
+
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1, nil
+		}
+	}
+
+	return 0, 0, errTruncated
+}
+
+// readFields splits an encoded message into its fields. Groups (deprecated) are skipped.
+func readFields(b []byte) ([]wireField, error) {
+	var fields []wireField
+
+	for len(b) != 0 {
+		tag, n, err := readVarint(b)
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+
+		f := wireField{num: int32(tag >> 3), typ: int(tag & 7)}
+		switch f.typ {
+		case wireVarint:
+			f.v, n, err = readVarint(b)
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			f.v, n = binary.LittleEndian.Uint64(b), 8
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			f.v, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		case wireBytes:
+			var l uint64
+			l, n, err = readVarint(b)
+			if err == nil && uint64(len(b)-n) < l {
+				err = errTruncated
+			}
+			if err == nil {
+				f.data = b[n : n+int(l)]
+				n += int(l)
+			}
+		case wireStartGroup:
+			n, err = skipGroup(b, f.num)
+		default:
+			err = errors.Errorf("unexpected wire type %d", f.typ)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b = b[n:]
+
+		if f.typ != wireStartGroup {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields, nil
+}
+
+// skipGroup returns the length of the group num, up to (and including) its end tag
+func skipGroup(b []byte, num int32) (int, error) {
+	for i := 0; i < len(b); {
+		tag, n, err := readVarint(b[i:])
+		if err != nil {
+			return 0, err
+		}
+		if int(tag&7) == wireEndGroup && int32(tag>>3) == num {
+			return i + n, nil
+		}
+		end, err := fieldLength(b[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += end
+	}
+
+	return 0, errTruncated
+}
+
+// fieldLength returns the length of the first field of b, including its tag
+func fieldLength(b []byte) (int, error) {
+	tag, n, err := readVarint(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch int(tag & 7) {
+	case wireVarint:
+		_, m, err := readVarint(b[n:])
+		return n + m, err
+	case wireFixed64:
+		return n + 8, nil
+	case wireFixed32:
+		return n + 4, nil
+	case wireBytes:
+		l, m, err := readVarint(b[n:])
+		return n + m + int(l), err
+	case wireStartGroup:
+		m, err := skipGroup(b[n:], int32(tag>>3))
+		return n + m, err
+	}
+
+	return 0, errors.Errorf("unexpected wire type %d", tag&7)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+
+	return append(b, byte(v))
+}
+
+func appendTag(b []byte, num int32, typ int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(typ))
+}
+
+func appendBytes(b []byte, num int32, data []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+
+	return append(b, data...)
+}
@@ -1,14 +1,52 @@
 package har
 
+// Version is the version of the HAR format produced by this package
+const Version = "1.2"
+
 // HAR represent a collection of requests
 type HAR struct {
-	Log struct {
-		Entries []Entry
-	} `json:"log"`
+	Log Log `json:"log"`
+}
+
+// Log is the root of a HAR document
+type Log struct {
+	Version string  `json:"version,omitempty"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator describes the application that created the HAR document
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
 }
 
 // Entry represents a HAR request/response pair
 type Entry struct {
-	Request  Request  `json:"request"`
-	Response Response `json:"response"`
+	StartedDateTime string   `json:"startedDateTime,omitempty"`
+	Time            float64  `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+}
+
+// Timings details the time spent in the different phases of a request, in milliseconds.
+// -1 is used for the phases that don't apply or are unknown.
+type Timings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// UnknownTimings is used when the timings of a request haven't been recorded
+var UnknownTimings = Timings{
+	Blocked: -1,
+	DNS:     -1,
+	Connect: -1,
+	SSL:     -1,
 }
@@ -0,0 +1,95 @@
+package har
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestRoundTrip(t *testing.T) {
+	body := []byte(`{"foo": "bar"}`)
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8080/api?b=2&a=1", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("http.NewRequest: unexpected error: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	harReq := FromHTTPRequest(req, body)
+	expectedQuery := []KV{{"a", "1"}, {"b", "2"}}
+	if !reflect.DeepEqual(harReq.QueryString, expectedQuery) {
+		t.Errorf("FromHTTPRequest(...).QueryString = %v, expected %v", harReq.QueryString, expectedQuery)
+	}
+	expectedCookies := []Cookie{{Name: "session", Value: "abc"}}
+	if !reflect.DeepEqual(harReq.Cookies, expectedCookies) {
+		t.Errorf("FromHTTPRequest(...).Cookies = %v, expected %v", harReq.Cookies, expectedCookies)
+	}
+
+	got, err := harReq.ToHTTPRequest("", false)
+	if err != nil {
+		t.Fatalf("ToHTTPRequest: unexpected error: %s", err)
+	}
+	if got.Method != req.Method || got.URL.String() != req.URL.String() {
+		t.Errorf("ToHTTPRequest() = %s %s, expected %s %s", got.Method, got.URL, req.Method, req.URL)
+	}
+	b, err := ioutil.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("reading body: unexpected error: %s", err)
+	}
+	if !bytes.Equal(b, body) {
+		t.Errorf("ToHTTPRequest().Body = %q, expected %q", b, body)
+	}
+}
+
+func TestResponseBinaryBody(t *testing.T) {
+	body := []byte{0xff, 0xd8, 0xff, 0x00}
+	resp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": []string{"image/jpeg"}},
+	}
+
+	harResp := FromHTTPResponse(resp, body)
+	if harResp.StatusText != "OK" {
+		t.Errorf("FromHTTPResponse(...).StatusText = %q, expected %q", harResp.StatusText, "OK")
+	}
+	if harResp.Content.Encoding != "base64" {
+		t.Errorf("FromHTTPResponse(...).Content.Encoding = %q, expected %q", harResp.Content.Encoding, "base64")
+	}
+
+	got, err := harResp.ToHTTPResponse(nil)
+	if err != nil {
+		t.Fatalf("ToHTTPResponse: unexpected error: %s", err)
+	}
+	b, err := ioutil.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("reading body: unexpected error: %s", err)
+	}
+	if !bytes.Equal(b, body) {
+		t.Errorf("ToHTTPResponse().Body = %v, expected %v", b, body)
+	}
+}
+
+func TestRequestMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Request{Method: http.MethodGet, URL: "http://localhost/"})
+	if err != nil {
+		t.Fatalf("json.Marshal: unexpected error: %s", err)
+	}
+	if strings.Contains(string(b), "postData") {
+		t.Errorf("json.Marshal(Request) = %s, expected no postData", b)
+	}
+
+	var req Request
+	err = json.Unmarshal([]byte(`{"method": "POST", "postData": {"mimeType": "text/plain", "text": "foo"}}`), &req)
+	if err != nil {
+		t.Fatalf("json.Unmarshal: unexpected error: %s", err)
+	}
+	if req.PostData.Text != "foo" {
+		t.Errorf("json.Unmarshal(...).PostData.Text = %q, expected %q", req.PostData.Text, "foo")
+	}
+}
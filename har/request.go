@@ -2,45 +2,72 @@ package har
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"unicode/utf8"
 )
 
 // Request is the HAR representation of an http request
 type Request struct {
-	Method      string
-	URL         string
-	HTTPVersion string
-	HeaderSize  int64
-	BodySize    int64
-	Headers     []KV
-	QueryString []KV
-	Cookies     []Cookie
-	PostData    Content
-	Content     Content `json:"content"`
+	Method      string   `json:"method"`
+	URL         string   `json:"url"`
+	HTTPVersion string   `json:"httpVersion"`
+	HeaderSize  int64    `json:"headersSize"`
+	BodySize    int64    `json:"bodySize"`
+	Headers     []KV     `json:"headers"`
+	QueryString []KV     `json:"queryString"`
+	Cookies     []Cookie `json:"cookies"`
+	PostData    Content  `json:"postData"`
+	Content     Content  `json:"content"`
+}
+
+// MarshalJSON omits the postData (and the non-standard content) when the request has no body
+func (r Request) MarshalJSON() ([]byte, error) {
+	type plain Request
+	out := struct {
+		plain
+		PostData *Content `json:"postData,omitempty"`
+		Content  *Content `json:"content,omitempty"`
+	}{plain: plain(r)}
+
+	if r.PostData != (Content{}) {
+		out.PostData = &r.PostData
+	}
+	if r.Content != (Content{}) {
+		out.Content = &r.Content
+	}
+
+	return json.Marshal(out)
 }
 
 // Content represents a request's or response's body
 type Content struct {
-	Size        int64
-	MimeType    string
-	Text        string
-	Compression int
+	Size        int64  `json:"size"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text"`
+	Encoding    string `json:"encoding,omitempty"`
+	Compression int    `json:"compression,omitempty"`
 }
 
 // KV is used to store key-value pairs for headers and queries
 type KV struct {
-	Name  string
-	Value string
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // Cookie represents a request's cookie
 type Cookie struct {
-	Name     string
-	Value    string
-	HTTPOnly bool
-	Secure   bool
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly"`
+	Secure   bool   `json:"secure"`
 }
 
 type readCloserWrapper struct {
@@ -55,7 +82,94 @@ func (r *Request) getBody() (io.ReadCloser, error) {
 	if len(r.PostData.Text) == 0 {
 		return nil, nil
 	}
-	return &readCloserWrapper{bytes.NewBuffer([]byte(r.PostData.Text))}, nil
+	b, err := r.PostData.bytes()
+
+	return &readCloserWrapper{bytes.NewBuffer(b)}, err
+}
+
+func (c Content) bytes() ([]byte, error) {
+	if c.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+
+	return []byte(c.Text), nil
+}
+
+func (c Content) length() int64 {
+	b, err := c.bytes()
+	if err != nil {
+		return int64(len(c.Text))
+	}
+
+	return int64(len(b))
+}
+
+func newContent(mimeType string, body []byte) Content {
+	c := Content{
+		Size:     int64(len(body)),
+		MimeType: mimeType,
+		Text:     string(body),
+	}
+	if !utf8.Valid(body) {
+		c.Text = base64.StdEncoding.EncodeToString(body)
+		c.Encoding = "base64"
+	}
+
+	return c
+}
+
+// FromHTTPRequest generates a HAR request from an *http.Request and its body
+func FromHTTPRequest(req *http.Request, body []byte) Request {
+	r := Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		HeaderSize:  -1,
+		BodySize:    int64(len(body)),
+		Headers:     fromHeader(req.Header),
+		QueryString: []KV{},
+		Cookies:     []Cookie{},
+	}
+	if req.Host != "" {
+		r.Headers = append([]KV{{Name: "Host", Value: req.Host}}, r.Headers...)
+	}
+
+	query := req.URL.Query()
+	for _, name := range sortedKeys(query) {
+		for _, v := range query[name] {
+			r.QueryString = append(r.QueryString, KV{Name: name, Value: v})
+		}
+	}
+	for _, c := range req.Cookies() {
+		r.Cookies = append(r.Cookies, Cookie{Name: c.Name, Value: c.Value})
+	}
+	if len(body) != 0 {
+		r.PostData = newContent(req.Header.Get("Content-Type"), body)
+	}
+
+	return r
+}
+
+func fromHeader(header http.Header) []KV {
+	kvs := []KV{}
+
+	for _, name := range sortedKeys(header) {
+		for _, v := range header[name] {
+			kvs = append(kvs, KV{Name: name, Value: v})
+		}
+	}
+
+	return kvs
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
 
 // ToHTTPRequest generate an *http.Request from a HAR request
@@ -91,6 +205,6 @@ func (r *Request) ToHTTPRequest(host string, ForceHTTP bool) (*http.Request, err
 		Header:        headers,
 		Body:          body,
 		GetBody:       r.getBody,
-		ContentLength: int64(len(r.PostData.Text)),
+		ContentLength: r.PostData.length(),
 	}, err
 }
@@ -4,27 +4,64 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Response is the HAR representation of an http response
 type Response struct {
-	Status       int
-	StatusText   string
-	HTTPVersion  string
-	RedirectURL  string
-	HeadersSize  int64
-	BodySize     int64
-	TransferSize int64 `json:"_transferSize"`
-	Headers      []KV
-	Cookies      []Cookie
-	Content      Content `json:"content"`
+	Status       int      `json:"status"`
+	StatusText   string   `json:"statusText"`
+	HTTPVersion  string   `json:"httpVersion"`
+	RedirectURL  string   `json:"redirectURL"`
+	HeadersSize  int64    `json:"headersSize"`
+	BodySize     int64    `json:"bodySize"`
+	TransferSize int64    `json:"_transferSize,omitempty"`
+	Headers      []KV     `json:"headers"`
+	Cookies      []Cookie `json:"cookies"`
+	Content      Content  `json:"content"`
 }
 
 func (r *Response) getBody() (io.ReadCloser, error) {
 	if len(r.Content.Text) == 0 {
 		return nil, nil
 	}
-	return &readCloserWrapper{bytes.NewBuffer([]byte(r.Content.Text))}, nil
+	b, err := r.Content.bytes()
+
+	return &readCloserWrapper{bytes.NewBuffer(b)}, err
+}
+
+// FromHTTPResponse generates a HAR response from an *http.Response and its body
+func FromHTTPResponse(resp *http.Response, body []byte) Response {
+	r := Response{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+		Headers:     fromHeader(resp.Header),
+		Cookies:     []Cookie{},
+		Content:     newContent(resp.Header.Get("Content-Type"), body),
+	}
+
+	for _, c := range resp.Cookies() {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.Format(time.RFC3339)
+		}
+		r.Cookies = append(r.Cookies, cookie)
+	}
+
+	return r
 }
 
 // ToHTTPResponse generate an *http.Response from a HAR response
@@ -37,6 +74,9 @@ func (r *Response) ToHTTPResponse(req *http.Request) (*http.Response, error) {
 				continue
 			case http.CanonicalHeaderKey("Accept-Encoding"):
 				continue
+			case http.CanonicalHeaderKey("Content-Encoding"):
+				// the content of HAR responses is decoded
+				continue
 			}
 
 			headers.Set(hName, header.Value)
@@ -52,7 +92,7 @@ func (r *Response) ToHTTPResponse(req *http.Request) (*http.Response, error) {
 		ProtoMinor:    1,
 		Header:        headers,
 		Body:          body,
-		ContentLength: int64(len(r.Content.Text)),
+		ContentLength: r.Content.length(),
 		Request:       req,
 	}
 
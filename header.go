@@ -3,47 +3,88 @@ package bacom
 import (
 	"net/http"
 	"path"
-
-	"github.com/fatih/color"
+	"sort"
 )
 
 // CompareHeaders returns a list of differences between two http.Header.
 // ignore and ignoreContent are expected to be normalized http headers names.
+// The differences are rendered as colorized text (see HeaderDifferences for the structured version).
 func CompareHeaders(ignore, ignoreContent []string, lhs, rhs http.Header) ([]string, error) {
 	var results []string
 
-	for k := range lhs {
+	diffs, err := HeaderDifferences(ignore, ignoreContent, lhs, rhs)
+	for _, d := range diffs {
+		results = append(results, d.Lines(true)...)
+	}
+
+	return results, err
+}
+
+// HeaderDifferences returns the list of differences between two http.Header.
+// The parameters are the same as for CompareHeaders.
+func HeaderDifferences(ignore, ignoreContent []string, lhs, rhs http.Header) ([]Difference, error) {
+	var diffs []Difference
+
+	for _, k := range sortedKeys(lhs) {
 		if ok, err := containsPattern(ignore, k); err != nil {
-			return results, err
+			return diffs, err
 		} else if ok {
 			continue
 		}
 		if _, ok := rhs[k]; !ok {
-			results = append(results, missingHeader(k, lhs.Get(k)))
+			diffs = append(diffs, Difference{
+				Kind:     MissingHeaderDifference,
+				Header:   k,
+				Expected: lhs.Get(k),
+			})
 			continue
 		}
 		if ok, err := containsPattern(ignoreContent, k); err != nil {
-			return results, err
+			return diffs, err
 		} else if ok {
 			continue
 		}
 		if lhs.Get(k) != rhs.Get(k) {
-			results = append(results,
-				missingHeader(k, lhs.Get(k)),
-				excessHeader(k, rhs.Get(k)),
-			)
+			diffs = append(diffs, Difference{
+				Kind:     HeaderContentDifference,
+				Header:   k,
+				Expected: lhs.Get(k),
+				Actual:   rhs.Get(k),
+			})
 		}
 	}
 
-	return results, nil
+	return diffs, nil
 }
 
-func missingHeader(name, value string) string {
-	return "- (Header) " + name + ": " + color.RedString("%s", value)
+// TrailerDifferences returns the list of differences between two sets of trailers (as found in
+// http.Response.Trailer), using the same rules as HeaderDifferences.
+func TrailerDifferences(ignore, ignoreContent []string, lhs, rhs http.Header) ([]Difference, error) {
+	diffs, err := HeaderDifferences(ignore, ignoreContent, lhs, rhs)
+
+	for i, d := range diffs {
+		switch d.Kind {
+		case MissingHeaderDifference:
+			d.Kind = MissingTrailerDifference
+		case HeaderContentDifference:
+			d.Kind = TrailerContentDifference
+		}
+		d.Trailer, d.Header = d.Header, ""
+		diffs[i] = d
+	}
+
+	return diffs, err
 }
 
-func excessHeader(name, value string) string {
-	return "+ (Header) " + name + ": " + color.GreenString("%s", value)
+func sortedKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
 }
 
 func containsPattern(patterns []string, needle string) (bool, error) {
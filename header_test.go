@@ -2,6 +2,7 @@ package bacom
 
 import (
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -68,3 +69,38 @@ func TestCompareHeaders(t *testing.T) {
 		t.Errorf("CompareHeaders(%q, nil, headerA, headerC): expected error, got nil", []string{"[-]"})
 	}
 }
+
+func TestTrailerDifferences(t *testing.T) {
+	lhs := http.Header{
+		"X-Checksum": {"abc"},
+		"X-Status":   {"ok"},
+		"X-Time":     {"12ms"},
+	}
+	rhs := http.Header{
+		"X-Checksum": {"def"},
+		"X-Time":     {"15ms"},
+	}
+
+	diffs, err := TrailerDifferences(nil, []string{"X-Time"}, lhs, rhs)
+	if err != nil {
+		t.Fatalf("TrailerDifferences: unexpected error: %s", err)
+	}
+	expected := []Difference{
+		{Kind: TrailerContentDifference, Trailer: "X-Checksum", Expected: "abc", Actual: "def"},
+		{Kind: MissingTrailerDifference, Trailer: "X-Status", Expected: "ok"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("TrailerDifferences(...) = %+v, expected %+v", diffs, expected)
+	}
+	if diffs[1].String() != "missing_trailer: trailer X-Status missing (expected ok)" {
+		t.Errorf("String() = %q", diffs[1].String())
+	}
+
+	diffs, err = TrailerDifferences(nil, nil, nil, rhs)
+	if err != nil {
+		t.Fatalf("TrailerDifferences: unexpected error: %s", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("TrailerDifferences(nil, nil, nil, rhs) = %+v, expected no differences", diffs)
+	}
+}
@@ -10,13 +10,30 @@ import (
 //
 // - Excess keys in the right hand side
 // - Excess and Missing values in slices
-// - Values of the same type with different content (excluding slices and maps)
+// - Values of the same type with different content (excluding slices, maps and RawBody values)
 // - Type difference where null is involved
 func Prune(d diff.Differ, ignoreNull bool) diff.Differ {
-	d, err := diff.Walk(d, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
+	return prune(d, ignoreNull, nil)
+}
+
+// prune implements Prune, keeping the content differences on scalars matching the content rules
+func prune(d diff.Differ, ignoreNull bool, rules ContentRules) diff.Differ {
+	return pruneAt(d, "", ignoreNull, rules, false)
+}
+
+// pruneAt implements prune for the diff tree found at path. With keepContent, the content differences on scalars
+// are only ignored when a content rule considers the values equal.
+func pruneAt(d diff.Differ, path string, ignoreNull bool, rules ContentRules, keepContent bool) diff.Differ {
+	d, err := walkAt(d, path, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
 		switch {
 		case diff.IsScalar(d) && d.Diff() == diff.ContentDiffer:
-			fallthrough
+			if isRawBody(d) || rules.breaking(d, path) {
+				return nil, nil
+			}
+			if _, ok := rules.Match(path); keepContent && !ok {
+				return nil, nil
+			}
+			return diff.Ignore()
 		case ignoreNull && isNil(d):
 			fallthrough
 		case diff.IsExcess(d):
@@ -34,6 +51,13 @@ func Prune(d diff.Differ, ignoreNull bool) diff.Differ {
 	return d
 }
 
+// walkAt is diff.Walk for the diff tree found at path
+func walkAt(d diff.Differ, path string, fn diff.WalkFn) (diff.Differ, error) {
+	return diff.Walk(d, func(parent diff.Differ, d diff.Differ, p string) (diff.Differ, error) {
+		return fn(parent, d, path+p)
+	})
+}
+
 func isNil(d diff.Differ) bool {
 	lhs, err := diff.LHS(d)
 	if err != nil {
@@ -59,10 +83,15 @@ type IgnorePrunner []string
 
 // Prune Removes ignored diff branches from the diff tree
 func (p IgnorePrunner) Prune(d diff.Differ) diff.Differ {
+	return p.pruneAt(d, "")
+}
+
+// pruneAt implements Prune for the diff tree found at path
+func (p IgnorePrunner) pruneAt(d diff.Differ, path string) diff.Differ {
 	if len(p) == 0 {
 		return d
 	}
-	d, err := diff.Walk(d, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
+	d, err := walkAt(d, path, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
 		if pathMatches(p, path) {
 			return diff.Ignore()
 		}
@@ -80,12 +109,17 @@ type IgnoreMissingPrunner []string
 
 // Prune Removes ignored diff branches from the diff tree
 func (p IgnoreMissingPrunner) Prune(d diff.Differ) diff.Differ {
+	return p.pruneAt(d, "")
+}
+
+// pruneAt implements Prune for the diff tree found at path
+func (p IgnoreMissingPrunner) pruneAt(d diff.Differ, path string) diff.Differ {
 	if len(p) == 0 {
 		return d
 	}
 
-	d, err := diff.Walk(d, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
-		if !diff.IsMissing(d) {
+	d, err := walkAt(d, path, func(parent diff.Differ, d diff.Differ, path string) (diff.Differ, error) {
+		if !isMissing(d) {
 			return nil, nil
 		}
 
@@ -7,8 +7,13 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
+// saveMu serializes the file name allocation done by SaveRequest, so that concurrent
+// savers writing to the same directory don't pick the same file name.
+var saveMu sync.Mutex
+
 // Saver is used to handle saving or requests and responses to a new version
 type Saver struct {
 	dir, fname, reqName string
@@ -27,7 +32,11 @@ func NewSaver(dir, fname string) *Saver {
 // SaveRequest moves the request file to the new folder (as specified in NewSaver).
 // The file name is adjusted if file with the same name already exists at that location
 // and if the files are not identical.
+// SaveRequest is safe to call from multiple goroutines.
 func (s *Saver) SaveRequest() error {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
 	reqName, err := nameFromReqFileName(filepath.Base(s.fname))
 	if err != nil {
 		return err
@@ -52,6 +61,12 @@ func (s *Saver) SaveRequest() error {
 	return copyFile(s.fname, dst)
 }
 
+// RequestName returns the name of the request file in the output directory, which can differ from the
+// original name once SaveRequest has been called
+func (s *Saver) RequestName() string {
+	return s.reqName
+}
+
 // SaveResponse saves the response to the appropriate file in the output
 // directory. The name generated by SaveRequest is used to ensure the response
 // has a matching file name.
@@ -67,7 +82,17 @@ func (s *Saver) SaveResponse(resp *http.Response) error {
 	}
 	defer handleClose(&err, f)
 
-	return resp.Write(f)
+	return WriteResponse(f, resp)
+}
+
+// SaveSession saves the WebSocket session of the request saved by SaveRequest
+func (s *Saver) SaveSession(session WebSocketSession) error {
+	sessionName, err := GetSessionFilename(s.reqName)
+	if err != nil {
+		return err
+	}
+
+	return WriteSession(filepath.Join(s.dir, sessionName), session)
 }
 
 func fileExists(fname string) bool {
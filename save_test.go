@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -97,6 +99,63 @@ func TestSaveRequest(t *testing.T) {
 	if !fileExists(testDst1) {
 		t.Errorf("expected %q to exists", testDst1)
 	}
+	if saver.RequestName() != "foo_req1.txt" {
+		t.Errorf("saver.RequestName() = %q, expected %q", saver.RequestName(), "foo_req1.txt")
+	}
+}
+
+func TestSaveRequestConcurrent(t *testing.T) {
+	testDirSrc := filepath.Join(os.TempDir(), "testDirConcurrentSrc")
+	removeTestDirSrc, err := createTestFolder(t, testDirSrc)
+	if err != nil {
+		t.Fatalf("failed to create test dir %q: %s", testDirSrc, err)
+	}
+	defer removeTestDirSrc()
+
+	testDirDst := filepath.Join(os.TempDir(), "testDirConcurrentDst")
+	removeTestDirDst, err := createTestFolder(t, testDirDst)
+	if err != nil {
+		t.Fatalf("failed to create test dir %q: %s", testDirDst, err)
+	}
+	defer removeTestDirDst()
+
+	n := 10
+	savers := make([]*Saver, n)
+	for i := range savers {
+		dir := filepath.Join(testDirSrc, strconv.Itoa(i))
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			t.Fatalf("failed to create test dir %q: %s", dir, err)
+		}
+		testSrc := filepath.Join(dir, "foo_req.txt")
+		err = createTestRequest(testSrc, []byte(strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		savers[i] = NewSaver(testDirDst, testSrc)
+	}
+
+	wg := &sync.WaitGroup{}
+	errs := make([]error, n)
+	for i, saver := range savers {
+		wg.Add(1)
+		go func(i int, saver *Saver) {
+			defer wg.Done()
+			errs[i] = saver.SaveRequest()
+		}(i, saver)
+	}
+	wg.Wait()
+
+	names := map[string]bool{}
+	for i, saver := range savers {
+		if errs[i] != nil {
+			t.Errorf("saver %d: unexpected error: %s", i, errs[i])
+		}
+		if names[saver.reqName] {
+			t.Errorf("saver %d: request name %q already used", i, saver.reqName)
+		}
+		names[saver.reqName] = true
+	}
 }
 
 func TestSaveRequestFail(t *testing.T) {
@@ -0,0 +1,154 @@
+package bacom
+
+import (
+	"math"
+	"sort"
+
+	"github.com/yazgazan/jaydiff/jpath"
+)
+
+// SchemaDraft is the JSON Schema version produced by SchemaBuilder
+const SchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is a subset of JSON Schema, as inferred by SchemaBuilder
+type Schema struct {
+	Schema     string             `json:"$schema,omitempty"`
+	Title      string             `json:"title,omitempty"`
+	Type       interface{}        `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// SchemaBuilder infers a JSON Schema from sample JSON values (as decoded by encoding/json).
+// The ignore and ignoreMissing JSON paths follow the same rules as in Compare:
+// ignored paths are left out of the schema, and paths in ignoreMissing are never required.
+type SchemaBuilder struct {
+	ignore        []string
+	ignoreMissing []string
+	root          *schemaNode
+}
+
+type schemaNode struct {
+	types   map[string]bool
+	objects int
+	props   map[string]*schemaNode
+	seen    map[string]int
+	items   *schemaNode
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{
+		types: map[string]bool{},
+		props: map[string]*schemaNode{},
+		seen:  map[string]int{},
+	}
+}
+
+// NewSchemaBuilder returns an empty *SchemaBuilder
+func NewSchemaBuilder(ignore, ignoreMissing []string) *SchemaBuilder {
+	return &SchemaBuilder{
+		ignore:        ignore,
+		ignoreMissing: ignoreMissing,
+		root:          newSchemaNode(),
+	}
+}
+
+// Add merges the sample v into the inferred schema
+func (b *SchemaBuilder) Add(v interface{}) {
+	b.add(b.root, "", v)
+}
+
+func (b *SchemaBuilder) add(node *schemaNode, path string, v interface{}) {
+	switch v := v.(type) {
+	default:
+		node.types["string"] = true
+	case nil:
+		node.types["null"] = true
+	case bool:
+		node.types["boolean"] = true
+	case float64:
+		if v == math.Trunc(v) {
+			node.types["integer"] = true
+		} else {
+			node.types["number"] = true
+		}
+	case int, int64:
+		node.types["integer"] = true
+	case []interface{}:
+		node.types["array"] = true
+		if node.items == nil {
+			node.items = newSchemaNode()
+		}
+		for _, item := range v {
+			b.add(node.items, path+"[]", item)
+		}
+	case map[string]interface{}:
+		node.types["object"] = true
+		node.objects++
+		for k, value := range v {
+			propPath := path + "." + jpath.EscapeKey(k)
+			if pathMatches(b.ignore, propPath) {
+				continue
+			}
+			prop, ok := node.props[k]
+			if !ok {
+				prop = newSchemaNode()
+				node.props[k] = prop
+			}
+			node.seen[k]++
+			b.add(prop, propPath, value)
+		}
+	}
+}
+
+// Schema returns the schema inferred from the samples added so far
+func (b *SchemaBuilder) Schema() *Schema {
+	return b.schema(b.root, "")
+}
+
+func (b *SchemaBuilder) schema(node *schemaNode, path string) *Schema {
+	s := &Schema{
+		Type: schemaType(node.types),
+	}
+
+	if node.items != nil && len(node.items.types) != 0 {
+		s.Items = b.schema(node.items, path+"[]")
+	}
+	if len(node.props) == 0 {
+		return s
+	}
+
+	s.Properties = make(map[string]*Schema, len(node.props))
+	for k, prop := range node.props {
+		propPath := path + "." + jpath.EscapeKey(k)
+		s.Properties[k] = b.schema(prop, propPath)
+		if node.seen[k] == node.objects && !pathMatches(b.ignoreMissing, propPath) {
+			s.Required = append(s.Required, k)
+		}
+	}
+	sort.Strings(s.Required)
+
+	return s
+}
+
+func schemaType(types map[string]bool) interface{} {
+	var names []string
+
+	for name := range types {
+		if name == "integer" && types["number"] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch len(names) {
+	case 0:
+		return nil
+	case 1:
+		return names[0]
+	}
+
+	return names
+}
@@ -0,0 +1,67 @@
+package bacom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaBuilder(t *testing.T) {
+	b := NewSchemaBuilder([]string{".secret"}, []string{".Results[].Foo"})
+
+	b.Add(map[string]interface{}{
+		"secret": "foo",
+		"count":  2.0,
+		"Results": []interface{}{
+			map[string]interface{}{"Foo": "bar", "Bar": 42.0, "Buzz": nil},
+			map[string]interface{}{"Foo": "fizz", "Bar": 11.0},
+		},
+	})
+	b.Add(map[string]interface{}{
+		"count":   2.5,
+		"Results": []interface{}{},
+		"next":    "/page/2",
+	})
+
+	expected := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"count": {Type: "number"},
+			"next":  {Type: "string"},
+			"Results": {
+				Type: "array",
+				Items: &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"Foo":  {Type: "string"},
+						"Bar":  {Type: "integer"},
+						"Buzz": {Type: "null"},
+					},
+					Required: []string{"Bar"},
+				},
+			},
+		},
+		Required: []string{"Results", "count"},
+	}
+
+	got := b.Schema()
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("SchemaBuilder.Schema() = %+v, expected %+v", got, expected)
+	}
+}
+
+func TestSchemaType(t *testing.T) {
+	for _, test := range []struct {
+		types    map[string]bool
+		expected interface{}
+	}{
+		{map[string]bool{}, nil},
+		{map[string]bool{"string": true}, "string"},
+		{map[string]bool{"string": true, "null": true}, []string{"null", "string"}},
+		{map[string]bool{"integer": true, "number": true}, "number"},
+	} {
+		got := schemaType(test.types)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("schemaType(%v) = %v, expected %v", test.types, got, test.expected)
+		}
+	}
+}
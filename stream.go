@@ -0,0 +1,244 @@
+package bacom
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yazgazan/jaydiff/jpath"
+)
+
+// DefaultEventName is the name of the events without an explicit name
+const DefaultEventName = "message"
+
+// Event is a single event of a Server-Sent Events or NDJSON stream
+type Event struct {
+	Name string `json:"event"`
+	ID   string `json:"id,omitempty"`
+	// Data is the decoded JSON data of the event, or a string if it isn't valid JSON
+	Data interface{} `json:"data"`
+}
+
+// EventStream is a decoded stream of events, see DecodeSSE and DecodeNDJSON
+type EventStream []Event
+
+// DecodeSSE decodes a text/event-stream body. Comments and retry fields are ignored.
+// Unlike browsers, a last event not followed by an empty line is kept.
+func DecodeSSE(r io.Reader) (EventStream, error) {
+	var (
+		stream EventStream
+		name   string
+		id     string
+		data   []string
+	)
+
+	dispatch := func() {
+		if len(data) != 0 {
+			if name == "" {
+				name = DefaultEventName
+			}
+			stream = append(stream, Event{
+				Name: name,
+				ID:   id,
+				Data: decodeEventData(strings.Join(data, "\n")),
+			})
+		}
+		name, data = "", nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			dispatch()
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i != -1 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			// the last event id is kept for the following events
+			id = value
+		}
+	}
+	dispatch()
+
+	return stream, scanner.Err()
+}
+
+func decodeEventData(s string) interface{} {
+	var v interface{}
+
+	err := json.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return s
+	}
+
+	return v
+}
+
+// DecodeNDJSON decodes a stream of JSON values. The names of the events are read from the
+// typeField key of the values (if set, and if the value is a string).
+func DecodeNDJSON(r io.Reader, typeField string) (EventStream, error) {
+	var stream EventStream
+
+	dec := json.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return stream, nil
+		}
+		if err != nil {
+			return stream, err
+		}
+
+		name := DefaultEventName
+		if m, ok := v.(map[string]interface{}); ok && typeField != "" {
+			if s, ok := m[typeField].(string); ok && s != "" {
+				name = s
+			}
+		}
+		stream = append(stream, Event{Name: name, Data: v})
+	}
+}
+
+// Tree returns the data of the events grouped by name (`{"name": [data, ...]}`), in order
+func (s EventStream) Tree() map[string]interface{} {
+	tree := map[string]interface{}{}
+
+	for _, e := range s {
+		events, _ := tree[e.Name].([]interface{})
+		tree[e.Name] = append(events, e.Data)
+	}
+
+	return tree
+}
+
+// Names returns the names of the events, in order of first appearance
+func (s EventStream) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+
+	for _, e := range s {
+		if seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		names = append(names, e.Name)
+	}
+
+	return names
+}
+
+// EventCounts describes how the number of events of each type are compared
+type EventCounts string
+
+// Rules supported for StreamOptions.Counts
+const (
+	// EventCountsAny ignores the number of events (the default)
+	EventCountsAny EventCounts = "any"
+	// EventCountsAtLeast reports event types with less events than in the left hand side
+	EventCountsAtLeast EventCounts = "at_least"
+	// EventCountsExact reports event types with a different number of events
+	EventCountsExact EventCounts = "exact"
+)
+
+// StreamOptions holds the rules used when comparing two event streams
+type StreamOptions struct {
+	// Ordered reports event types appearing (for the first time) in a different order
+	Ordered bool
+	Counts  EventCounts
+}
+
+// Validate checks the counts rule
+func (o StreamOptions) Validate() error {
+	switch o.Counts {
+	default:
+		return errors.Errorf("unknown event counts rule %q", o.Counts)
+	case "", EventCountsAny, EventCountsAtLeast, EventCountsExact:
+	}
+
+	return nil
+}
+
+// Differences compares two event streams. The data of the events are compared by event name using
+// opts, on the trees returned by EventStream.Tree (for example, `.name[].field` matches the field of
+// all the "name" events). Missing event types are reported as missing keys.
+func (o StreamOptions) Differences(opts BodyOptions, lhs, rhs EventStream) ([]Difference, error) {
+	var diffs []Difference
+
+	if o.Ordered {
+		diffs = append(diffs, eventOrderDifferences(lhs, rhs)...)
+	}
+
+	lhsTree, rhsTree := lhs.Tree(), rhs.Tree()
+	for _, name := range lhs.Names() {
+		lhsEvents, _ := lhsTree[name].([]interface{})
+		rhsEvents, ok := rhsTree[name].([]interface{})
+		if !ok {
+			continue
+		}
+
+		switch {
+		case o.Counts == EventCountsExact && len(lhsEvents) != len(rhsEvents),
+			o.Counts == EventCountsAtLeast && len(lhsEvents) > len(rhsEvents):
+			diffs = append(diffs, Difference{
+				Kind:     EventCountDifference,
+				Path:     "." + jpath.EscapeKey(name),
+				Expected: len(lhsEvents),
+				Actual:   len(rhsEvents),
+			})
+		}
+	}
+
+	bodyDiffs, err := opts.Differences(lhsTree, rhsTree)
+
+	return append(diffs, bodyDiffs...), err
+}
+
+// eventOrderDifferences compares the order of the event types present in both streams
+func eventOrderDifferences(lhs, rhs EventStream) []Difference {
+	lhsNames, rhsNames := lhs.Names(), rhs.Names()
+	lhsOrder := commonNames(lhsNames, rhsNames)
+	rhsOrder := commonNames(rhsNames, lhsNames)
+
+	if strings.Join(lhsOrder, "\n") == strings.Join(rhsOrder, "\n") {
+		return nil
+	}
+
+	return []Difference{{
+		Kind:     EventOrderDifference,
+		Expected: strings.Join(lhsOrder, ", "),
+		Actual:   strings.Join(rhsOrder, ", "),
+	}}
+}
+
+// commonNames returns the names of a that are also in b
+func commonNames(a, b []string) []string {
+	var names []string
+
+	for _, name := range a {
+		for _, other := range b {
+			if name == other {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	return names
+}
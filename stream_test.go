@@ -0,0 +1,169 @@
+package bacom
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeSSE(t *testing.T) {
+	body := ": keep-alive\r\n" +
+		"event: start\r\n" +
+		"id: 1\r\n" +
+		"data: {\"id\": 1}\r\n" +
+		"\r\n" +
+		"retry: 1000\n" +
+		"data: first line\n" +
+		"data:second line\n" +
+		"\n" +
+		"event: empty\n" +
+		"\n" +
+		"event: end\n" +
+		"data: 42"
+
+	expected := EventStream{
+		{Name: "start", ID: "1", Data: map[string]interface{}{"id": 1.0}},
+		{Name: DefaultEventName, ID: "1", Data: "first line\nsecond line"},
+		{Name: "end", ID: "1", Data: 42.0},
+	}
+
+	got, err := DecodeSSE(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeSSE: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DecodeSSE(...) = %+v, expected %+v", got, expected)
+	}
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	body := `{"type": "start"}
+{"type": "data", "value": 1}
+
+{"value": 2}
+`
+
+	expected := EventStream{
+		{Name: "start", Data: map[string]interface{}{"type": "start"}},
+		{Name: "data", Data: map[string]interface{}{"type": "data", "value": 1.0}},
+		{Name: DefaultEventName, Data: map[string]interface{}{"value": 2.0}},
+	}
+
+	got, err := DecodeNDJSON(strings.NewReader(body), "type")
+	if err != nil {
+		t.Fatalf("DecodeNDJSON: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DecodeNDJSON(...) = %+v, expected %+v", got, expected)
+	}
+
+	_, err = DecodeNDJSON(strings.NewReader(`{"type": `), "type")
+	if err == nil {
+		t.Error("DecodeNDJSON(invalid): expected error, got nil")
+	}
+}
+
+func TestStreamOptionsDifferences(t *testing.T) {
+	event := func(name string, data interface{}) Event {
+		return Event{Name: name, Data: data}
+	}
+	base := EventStream{
+		event("start", map[string]interface{}{"id": 1.0}),
+		event("data", map[string]interface{}{"value": 1.0}),
+		event("data", map[string]interface{}{"value": 2.0}),
+		event("end", map[string]interface{}{}),
+	}
+
+	for _, test := range []struct {
+		Name     string
+		Options  StreamOptions
+		Target   EventStream
+		Expected []Difference
+	}{
+		{
+			Name:   "identical",
+			Target: base,
+		},
+		{
+			Name:    "reordered, less events",
+			Options: StreamOptions{},
+			Target: EventStream{
+				event("data", map[string]interface{}{"value": 3.0}),
+				event("start", map[string]interface{}{"id": 2.0}),
+				event("end", map[string]interface{}{}),
+			},
+		},
+		{
+			Name:    "ordered",
+			Options: StreamOptions{Ordered: true},
+			Target: EventStream{
+				event("data", map[string]interface{}{"value": 3.0}),
+				event("start", map[string]interface{}{"id": 2.0}),
+				event("end", map[string]interface{}{}),
+				event("progress", map[string]interface{}{}),
+			},
+			Expected: []Difference{
+				{Kind: EventOrderDifference, Expected: "start, data, end", Actual: "data, start, end"},
+			},
+		},
+		{
+			Name:    "at least",
+			Options: StreamOptions{Counts: EventCountsAtLeast},
+			Target: EventStream{
+				event("start", map[string]interface{}{"id": 1.0}),
+				event("start", map[string]interface{}{"id": 2.0}),
+				event("data", map[string]interface{}{"value": 1.0}),
+			},
+			Expected: []Difference{
+				{Kind: EventCountDifference, Path: ".data", Expected: 2, Actual: 1},
+				{Kind: MissingKeyDifference, Path: ".end", Expected: []interface{}{map[string]interface{}{}}},
+			},
+		},
+		{
+			Name:    "exact",
+			Options: StreamOptions{Counts: EventCountsExact},
+			Target: EventStream{
+				event("start", map[string]interface{}{"id": 1.0}),
+				event("start", map[string]interface{}{"id": 2.0}),
+				event("data", map[string]interface{}{"value": "1"}),
+				event("data", map[string]interface{}{"value": 2.0}),
+				event("end", map[string]interface{}{}),
+			},
+			Expected: []Difference{
+				{Kind: EventCountDifference, Path: ".start", Expected: 1, Actual: 2},
+				{Kind: TypeChangeDifference, Path: ".data[0].value", Expected: 1.0, Actual: "1"},
+			},
+		},
+	} {
+		diffs, err := test.Options.Differences(BodyOptions{}, base, test.Target)
+		if err != nil {
+			t.Errorf("%s: Differences: unexpected error: %s", test.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.Expected) {
+			t.Errorf("%s: Differences(...) = %+v, expected %+v", test.Name, diffs, test.Expected)
+		}
+	}
+
+	err := StreamOptions{Counts: "most"}.Validate()
+	if err == nil {
+		t.Error("StreamOptions{Counts: most}.Validate(): expected error, got nil")
+	}
+}
+
+func TestStreamOptionsDifferencesEscaping(t *testing.T) {
+	base := EventStream{
+		{Name: "user.created", Data: map[string]interface{}{"id": 1.0}},
+		{Name: "user.created", Data: map[string]interface{}{"id": 2.0}},
+	}
+	target := EventStream{{Name: "user.created", Data: map[string]interface{}{"id": 1.0}}}
+
+	diffs, err := StreamOptions{Counts: EventCountsExact}.Differences(BodyOptions{}, base, target)
+	if err != nil {
+		t.Fatalf("Differences: unexpected error: %s", err)
+	}
+	expected := []Difference{{Kind: EventCountDifference, Path: `."user.created"`, Expected: 2, Actual: 1}}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Differences(...) = %+v, expected %+v", diffs, expected)
+	}
+}
@@ -0,0 +1,124 @@
+package bacom
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Validator implements custom backward-compatibility rules. base and target are the decoded
+// JSON bodies of the responses (nil when empty). base is nil when there is no base response.
+type Validator interface {
+	Validate(method, path, version string, base, target interface{}) ([]Difference, error)
+}
+
+// ValidatorFunc is a function implementing Validator
+type ValidatorFunc func(method, path, version string, base, target interface{}) ([]Difference, error)
+
+// Validate calls f
+func (f ValidatorFunc) Validate(method, path, version string, base, target interface{}) ([]Difference, error) {
+	return f(method, path, version, base, target)
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]Validator{}
+)
+
+// RegisterValidator makes a validator available under the provided name.
+// It panics if a validator is already registered with the same name, or if v is nil.
+func RegisterValidator(name string, v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	if v == nil {
+		panic("bacom: RegisterValidator validator is nil")
+	}
+	if _, dup := validators[name]; dup {
+		panic("bacom: RegisterValidator called twice for validator " + name)
+	}
+	validators[name] = v
+}
+
+// GetValidator returns the validator registered under name
+func GetValidator(name string) (Validator, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	v, ok := validators[name]
+
+	return v, ok
+}
+
+// Validators returns the sorted names of the registered validators
+func Validators() []string {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CommandValidator runs a command (using /bin/sh) to validate responses.
+// The command receives a JSON document on stdin, holding the method, path, version, base and target
+// fields. It is expected to write a JSON array of differences on stdout (an empty output meaning
+// no differences). Differences without a kind are reported as ValidationDifference.
+// The command is killed after Timeout (unless 0), see RunCommand.
+type CommandValidator struct {
+	Name    string
+	Command string
+	Timeout time.Duration
+}
+
+type commandValidatorInput struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Version string      `json:"version"`
+	Base    interface{} `json:"base"`
+	Target  interface{} `json:"target"`
+}
+
+// Validate runs the command
+func (v CommandValidator) Validate(method, path, version string, base, target interface{}) ([]Difference, error) {
+	var diffs []Difference
+
+	in, err := json.Marshal(commandValidatorInput{
+		Method:  method,
+		Path:    path,
+		Version: version,
+		Base:    base,
+		Target:  target,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "validator %q", v.Name)
+	}
+
+	out, err := RunCommand(v.Command, v.Timeout, in)
+	if err != nil {
+		return nil, errors.Wrapf(err, "validator %q", v.Name)
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	err = json.Unmarshal(out, &diffs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "validator %q: decoding output", v.Name)
+	}
+	for i := range diffs {
+		if diffs[i].Kind == "" {
+			diffs[i].Kind = ValidationDifference
+		}
+	}
+
+	return diffs, nil
+}
@@ -0,0 +1,91 @@
+package bacom
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegisterValidator(t *testing.T) {
+	v := ValidatorFunc(func(method, path, version string, base, target interface{}) ([]Difference, error) {
+		return []Difference{{Kind: ValidationDifference, Path: path, Message: method + " " + version}}, nil
+	})
+
+	RegisterValidator("test-register", v)
+
+	got, ok := GetValidator("test-register")
+	if !ok {
+		t.Fatalf("GetValidator(%q): not found", "test-register")
+	}
+	diffs, err := got.Validate("GET", "/api", "v1.0.0", nil, nil)
+	if err != nil {
+		t.Errorf("Validate: unexpected error: %s", err)
+	}
+	expected := []Difference{{Kind: ValidationDifference, Path: "/api", Message: "GET v1.0.0"}}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Validate(...) = %+v, expected %+v", diffs, expected)
+	}
+
+	found := false
+	for _, name := range Validators() {
+		found = found || name == "test-register"
+	}
+	if !found {
+		t.Errorf("Validators() = %q, expected it to contain %q", Validators(), "test-register")
+	}
+
+	_, ok = GetValidator("test-unknown")
+	if ok {
+		t.Errorf("GetValidator(%q): expected not found", "test-unknown")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterValidator(%q) twice: expected panic", "test-register")
+		}
+	}()
+	RegisterValidator("test-register", v)
+}
+
+func TestCommandValidator(t *testing.T) {
+	for _, test := range []struct {
+		Command  string
+		Expected []Difference
+		Err      bool
+	}{
+		{
+			Command: `cat > /dev/null`,
+		},
+		{
+			Command: `grep -q '"target":{"id":"42"}' && echo '[{"path": ".id", "message": "id format changed"}]'`,
+			Expected: []Difference{
+				{Kind: ValidationDifference, Path: ".id", Message: "id format changed"},
+			},
+		},
+		{
+			Command: `grep -q '"version":"v1.0.0"' && echo '[{"kind": "content", "path": ".id", "expected": "a", "actual": "b"}]'`,
+			Expected: []Difference{
+				{Kind: ContentDifference, Path: ".id", Expected: "a", Actual: "b"},
+			},
+		},
+		{Command: `echo "oops" >&2; exit 3`, Err: true},
+		{Command: `echo "not json"`, Err: true},
+		{Command: `sleep 5`, Err: true},
+	} {
+		v := CommandValidator{Name: "test", Command: test.Command, Timeout: 500 * time.Millisecond}
+		diffs, err := v.Validate("GET", "/api", "v1.0.0", map[string]interface{}{"id": 42.0}, map[string]interface{}{"id": "42"})
+		if test.Err {
+			if err == nil {
+				t.Errorf("CommandValidator{%q}.Validate: expected error, got nil", test.Command)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CommandValidator{%q}.Validate: unexpected error: %s", test.Command, err)
+			continue
+		}
+		if !reflect.DeepEqual(diffs, test.Expected) {
+			t.Errorf("CommandValidator{%q}.Validate = %+v, expected %+v", test.Command, diffs, test.Expected)
+		}
+	}
+}
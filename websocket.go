@@ -0,0 +1,117 @@
+package bacom
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// WebSocketSessionContentType is the content-type used for the bodies holding WebSocket sessions,
+// see DecodeWebSocketSession
+const WebSocketSessionContentType = "application/vnd.bacom.websocket-session+json"
+
+// Senders of WebSocket messages
+const (
+	FromClient = "client"
+	FromServer = "server"
+)
+
+// Types of WebSocket messages
+const (
+	TextMessage   = "text"
+	BinaryMessage = "binary"
+	CloseMessage  = "close"
+)
+
+// WebSocketMessage is a message of a recorded WebSocket session
+type WebSocketMessage struct {
+	From string `json:"from"`
+	// Time is the number of milliseconds between the end of the handshake and the message
+	Time int64  `json:"time_ms"`
+	Type string `json:"type"`
+	// Data is the content of text messages, the base64-encoded content of binary messages or the reason of close
+	// messages
+	Data string `json:"data,omitempty"`
+	// Code is the status code of close messages
+	Code int `json:"code,omitempty"`
+}
+
+// WebSocketSession is a recorded WebSocket session, stored in the _ws[0-9]*.json file of the handshake request
+// (see GetSessionFilename)
+type WebSocketSession struct {
+	Messages []WebSocketMessage `json:"messages"`
+}
+
+// ReadSession reads the session stored alongside the request file reqFname
+func ReadSession(reqFname string) (session WebSocketSession, err error) {
+	fname, err := GetSessionFilename(reqFname)
+	if err != nil {
+		return session, err
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return session, err
+	}
+	defer handleClose(&err, f)
+
+	err = json.NewDecoder(f).Decode(&session)
+
+	return session, errors.Wrapf(err, "reading session %q", fname)
+}
+
+// WriteSession writes session to fname
+func WriteSession(fname string, session WebSocketSession) (err error) {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer handleClose(&err, f)
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(session)
+}
+
+// DecodeWebSocketSession decodes a session (in JSON, as stored in session files) into the stream of the messages
+// sent by the server. Text messages are decoded as JSON if possible, and named after their typeField key like
+// NDJSON values (see DecodeNDJSON). Close messages are named "close".
+func DecodeWebSocketSession(r io.Reader, typeField string) (EventStream, error) {
+	var (
+		session WebSocketSession
+		stream  EventStream
+	)
+
+	err := json.NewDecoder(r).Decode(&session)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding websocket session")
+	}
+
+	for _, msg := range session.Messages {
+		if msg.From != FromServer {
+			continue
+		}
+
+		e := Event{Name: DefaultEventName, Data: msg.Data}
+		switch msg.Type {
+		case TextMessage:
+			e.Data = decodeEventData(msg.Data)
+			if m, ok := e.Data.(map[string]interface{}); ok && typeField != "" {
+				if s, ok := m[typeField].(string); ok && s != "" {
+					e.Name = s
+				}
+			}
+		case CloseMessage:
+			e.Name = CloseMessage
+			e.Data = map[string]interface{}{"code": float64(msg.Code), "reason": msg.Data}
+		}
+		stream = append(stream, e)
+	}
+
+	return stream, nil
+}
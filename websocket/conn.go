@@ -0,0 +1,175 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// acceptGUID is appended to the client's key when computing the Sec-WebSocket-Accept header
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// IsUpgrade returns true if the headers of a request (or response) ask for an upgrade to the WebSocket protocol
+func IsUpgrade(header http.Header) bool {
+	if !strings.EqualFold(header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept header expected for the Sec-WebSocket-Key key
+func AcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Conn is the client side of a WebSocket connection
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	asm  Assembler
+
+	// writes can come from ReadMessage (answering pings) and WriteMessage
+	writeMu sync.Mutex
+}
+
+// Dial sends the opening handshake req (an http or https URL) and returns the connection if the server
+// accepted it (status 101). The response is returned in all cases, so that refused handshakes can be inspected.
+// The Sec-WebSocket-Key of req is used if set, and extensions are never negotiated.
+func Dial(req *http.Request) (*Conn, *http.Response, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		if req.URL.Scheme == "https" {
+			addr = net.JoinHostPort(req.URL.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(req.URL.Hostname(), "80")
+		}
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if req.URL.Scheme == "https" {
+		conn, err = tls.Dial("tcp", addr, &tls.Config{ServerName: req.URL.Hostname()})
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "websocket handshake")
+	}
+
+	c, resp, err := handshake(conn, req)
+	if c == nil {
+		_ = conn.Close()
+	}
+
+	return c, resp, err
+}
+
+func handshake(conn net.Conn, req *http.Request) (*Conn, *http.Response, error) {
+	req.Header = req.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		b := make([]byte, 16)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "websocket handshake")
+		}
+		key = base64.StdEncoding.EncodeToString(b)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Del("Sec-WebSocket-Extensions")
+
+	err := req.Write(conn)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "websocket handshake")
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "websocket handshake")
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// the connection is closed by Dial, reading the body first
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+		return nil, resp, errors.Wrap(err, "websocket handshake")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		return nil, resp, errors.New("websocket handshake: invalid Sec-WebSocket-Accept header")
+	}
+
+	return &Conn{conn: conn, r: r}, resp, nil
+}
+
+// ReadFrame reads the next frame sent by the server
+func (c *Conn) ReadFrame() (Frame, error) {
+	return ReadFrame(c.r)
+}
+
+// WriteFrame sends a (masked) frame to the server
+func (c *Conn) WriteFrame(f Frame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return WriteFrame(c.conn, f, true)
+}
+
+// ReadMessage returns the next data or close message sent by the server. Pings are answered.
+func (c *Conn) ReadMessage() (Message, error) {
+	for {
+		f, err := c.ReadFrame()
+		if err != nil {
+			return Message{}, err
+		}
+		if f.Opcode == OpPing {
+			err = c.WriteFrame(Frame{Fin: true, Opcode: OpPong, Payload: f.Payload})
+			if err != nil {
+				return Message{}, err
+			}
+			continue
+		}
+		if msg, ok := c.asm.Add(f); ok {
+			return msg, nil
+		}
+	}
+}
+
+// WriteMessage sends a message in a single frame
+func (c *Conn) WriteMessage(msg Message) error {
+	return c.WriteFrame(Frame{Fin: true, Opcode: msg.Opcode, Payload: msg.Data})
+}
+
+// Close closes the underlying connection, without sending a close frame
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,188 @@
+// Package websocket implements the parts of the WebSocket protocol (RFC 6455) needed to record and replay
+// sessions: reading and writing frames, reassembling fragmented messages and the client side of the opening
+// handshake. Extensions (such as permessage-deflate) are not supported.
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Opcodes of the frames
+const (
+	OpContinuation byte = 0x0
+	OpText         byte = 0x1
+	OpBinary       byte = 0x2
+	OpClose        byte = 0x8
+	OpPing         byte = 0x9
+	OpPong         byte = 0xa
+)
+
+// CloseNormal is the status code of the close frames sent when a session ends normally
+const CloseNormal = 1000
+
+// maxPayload limits the size of the frames read, as the length is read before the payload
+const maxPayload = 64 << 20
+
+// Frame is a single, unmasked, WebSocket frame
+type Frame struct {
+	Fin     bool
+	Opcode  byte
+	Payload []byte
+}
+
+// IsControl returns true for close, ping and pong frames
+func (f Frame) IsControl() bool {
+	return f.Opcode&0x8 != 0
+}
+
+// ReadFrame reads a frame from r, unmasking its payload
+func ReadFrame(r io.Reader) (f Frame, err error) {
+	var header [2]byte
+	_, err = io.ReadFull(r, header[:])
+	if err != nil {
+		return f, err
+	}
+	if header[0]&0x70 != 0 {
+		return f, errors.New("reading websocket frame: unsupported extension")
+	}
+	f.Fin = header[0]&0x80 != 0
+	f.Opcode = header[0] & 0x0f
+
+	n := uint64(header[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		_, err = io.ReadFull(r, b[:])
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err = io.ReadFull(r, b[:])
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if err != nil {
+		return f, errors.Wrap(err, "reading websocket frame")
+	}
+	if n > maxPayload {
+		return f, errors.Errorf("reading websocket frame: payload too large (%d bytes)", n)
+	}
+
+	var key [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		_, err = io.ReadFull(r, key[:])
+		if err != nil {
+			return f, errors.Wrap(err, "reading websocket frame")
+		}
+	}
+	f.Payload = make([]byte, n)
+	_, err = io.ReadFull(r, f.Payload)
+	if err != nil {
+		return f, errors.Wrap(err, "reading websocket frame")
+	}
+	if masked {
+		maskBytes(key, f.Payload)
+	}
+
+	return f, nil
+}
+
+// WriteFrame writes f to w. Frames sent by clients must be masked.
+func WriteFrame(w io.Writer, f Frame, mask bool) error {
+	b := make([]byte, 2, 14+len(f.Payload))
+	b[0] = f.Opcode
+	if f.Fin {
+		b[0] |= 0x80
+	}
+
+	n := len(f.Payload)
+	switch {
+	case n < 126:
+		b[1] = byte(n)
+	case n <= 0xffff:
+		b[1] = 126
+		b = append(b, byte(n>>8), byte(n))
+	default:
+		b[1] = 127
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		b = append(b, size[:]...)
+	}
+
+	if !mask {
+		_, err := w.Write(append(b, f.Payload...))
+		return err
+	}
+
+	b[1] |= 0x80
+	var key [4]byte
+	_, err := rand.Read(key[:])
+	if err != nil {
+		return err
+	}
+	b = append(b, key[:]...)
+	start := len(b)
+	b = append(b, f.Payload...)
+	maskBytes(key, b[start:])
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+// Message is a complete data message (text or binary) or a close message
+type Message struct {
+	Opcode byte
+	Data   []byte
+}
+
+// Assembler reassembles the messages fragmented over multiple frames
+type Assembler struct {
+	opcode byte
+	data   []byte
+}
+
+// Add adds a frame to the message being assembled, returning the message if it is complete.
+// Ping and pong frames are ignored.
+func (a *Assembler) Add(f Frame) (Message, bool) {
+	switch {
+	case f.Opcode == OpClose:
+		return Message{Opcode: OpClose, Data: f.Payload}, true
+	case f.IsControl():
+		return Message{}, false
+	case f.Opcode != OpContinuation:
+		a.opcode, a.data = f.Opcode, nil
+	}
+	a.data = append(a.data, f.Payload...)
+	if !f.Fin {
+		return Message{}, false
+	}
+
+	return Message{Opcode: a.opcode, Data: a.data}, true
+}
+
+// CloseData returns the payload of a close frame
+func CloseData(code int, reason string) []byte {
+	b := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(b, uint16(code))
+
+	return append(b, reason...)
+}
+
+// ParseClose reads the status code and reason of a close frame's payload. The code is 0 if the payload is empty.
+func ParseClose(b []byte) (code int, reason string) {
+	if len(b) < 2 {
+		return 0, ""
+	}
+
+	return int(binary.BigEndian.Uint16(b)), string(b[2:])
+}
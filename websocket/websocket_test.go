@@ -0,0 +1,203 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	long := bytes.Repeat([]byte("a"), 70000)
+
+	for _, test := range []struct {
+		frame Frame
+		mask  bool
+	}{
+		{Frame{Fin: true, Opcode: OpText, Payload: []byte("hello")}, false},
+		{Frame{Fin: true, Opcode: OpText, Payload: []byte("hello")}, true},
+		{Frame{Fin: false, Opcode: OpBinary, Payload: bytes.Repeat([]byte{1}, 300)}, true},
+		{Frame{Fin: true, Opcode: OpBinary, Payload: long}, false},
+		{Frame{Fin: true, Opcode: OpClose, Payload: CloseData(CloseNormal, "bye")}, true},
+	} {
+		buf := &bytes.Buffer{}
+		err := WriteFrame(buf, test.frame, test.mask)
+		if err != nil {
+			t.Fatalf("WriteFrame: unexpected error: %s", err)
+		}
+		if masked := buf.Bytes()[1]&0x80 != 0; masked != test.mask {
+			t.Errorf("WriteFrame(mask=%v): masked = %v", test.mask, masked)
+		}
+
+		got, err := ReadFrame(buf)
+		if err != nil {
+			t.Fatalf("ReadFrame: unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, test.frame) {
+			t.Errorf("ReadFrame(WriteFrame(%d bytes, %v)) = %v, expected %v",
+				len(test.frame.Payload), test.mask, got.Opcode, test.frame.Opcode)
+		}
+	}
+
+	if _, err := ReadFrame(bytes.NewReader([]byte{0x81, 0x05, 'h'})); err == nil {
+		t.Error("ReadFrame(truncated): expected error, got nil")
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{0xc1, 0x00})); err == nil {
+		t.Error("ReadFrame(compressed): expected error, got nil")
+	}
+}
+
+func TestAssembler(t *testing.T) {
+	var (
+		asm      Assembler
+		messages []Message
+	)
+
+	for _, f := range []Frame{
+		{Fin: false, Opcode: OpText, Payload: []byte("hel")},
+		{Fin: true, Opcode: OpPing, Payload: []byte("ping")},
+		{Fin: true, Opcode: OpContinuation, Payload: []byte("lo")},
+		{Fin: true, Opcode: OpBinary, Payload: []byte{1}},
+		{Fin: true, Opcode: OpClose, Payload: CloseData(1001, "going away")},
+	} {
+		if msg, ok := asm.Add(f); ok {
+			messages = append(messages, msg)
+		}
+	}
+
+	expected := []Message{
+		{Opcode: OpText, Data: []byte("hello")},
+		{Opcode: OpBinary, Data: []byte{1}},
+		{Opcode: OpClose, Data: CloseData(1001, "going away")},
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Add(...) = %q, expected %q", messages, expected)
+	}
+	if code, reason := ParseClose(messages[2].Data); code != 1001 || reason != "going away" {
+		t.Errorf("ParseClose(...) = %d, %q, expected 1001, \"going away\"", code, reason)
+	}
+}
+
+// echoServer answers the handshake and echoes the messages it receives, in upper case
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsUpgrade(r.Header) {
+			http.Error(w, "expected a websocket handshake", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + AcceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		_ = rw.Flush()
+		_ = WriteFrame(conn, Frame{Fin: true, Opcode: OpPing}, false)
+
+		for {
+			f, err := ReadFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch f.Opcode {
+			case OpPong:
+			case OpClose:
+				_ = WriteFrame(conn, f, false)
+				return
+			default:
+				f.Payload = bytes.ToUpper(f.Payload)
+				_ = WriteFrame(conn, f, false)
+			}
+		}
+	}))
+}
+
+func TestDial(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, resp, err := Dial(req)
+	if err != nil {
+		t.Fatalf("Dial: unexpected error: %s", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Dial: status = %d, expected 101", resp.StatusCode)
+	}
+
+	for _, msg := range []Message{
+		{Opcode: OpText, Data: []byte("hello")},
+		{Opcode: OpClose, Data: CloseData(CloseNormal, "")},
+	} {
+		err = conn.WriteMessage(msg)
+		if err != nil {
+			t.Fatalf("WriteMessage: unexpected error: %s", err)
+		}
+		got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage: unexpected error: %s", err)
+		}
+		if msg.Opcode == OpText {
+			msg.Data = bytes.ToUpper(msg.Data)
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("ReadMessage() = %q, expected %q", got, msg)
+		}
+	}
+}
+
+func TestDialRefused(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, resp, err := Dial(req)
+	if err != nil || conn != nil {
+		t.Fatalf("Dial(refused) = %v, %v, expected no connection and no error", conn, err)
+	}
+	buf := &bytes.Buffer{}
+	_, _ = buf.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusUnauthorized || strings.TrimSpace(buf.String()) != "unauthorized" {
+		t.Errorf("Dial(refused): response = %d %q", resp.StatusCode, buf.String())
+	}
+
+	srv.Close()
+	if _, _, err = Dial(req); err == nil {
+		t.Error("Dial(closed server): expected error, got nil")
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	for _, test := range []struct {
+		header   http.Header
+		expected bool
+	}{
+		{http.Header{"Upgrade": {"websocket"}, "Connection": {"Upgrade"}}, true},
+		{http.Header{"Upgrade": {"WebSocket"}, "Connection": {"keep-alive, Upgrade"}}, true},
+		{http.Header{"Upgrade": {"websocket"}}, false},
+		{http.Header{"Upgrade": {"h2c"}, "Connection": {"Upgrade"}}, false},
+	} {
+		if got := IsUpgrade(test.header); got != test.expected {
+			t.Errorf("IsUpgrade(%v) = %v, expected %v", test.header, got, test.expected)
+		}
+	}
+
+	// example from RFC 6455
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("AcceptKey(...) = %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+package bacom
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWebSocketSession(t *testing.T) {
+	body := `{"messages": [
+	{"from": "client", "time_ms": 0, "type": "text", "data": "{\"type\": \"subscribe\"}"},
+	{"from": "server", "time_ms": 3, "type": "text", "data": "{\"type\": \"subscribed\", \"id\": 1}"},
+	{"from": "server", "time_ms": 10, "type": "text", "data": "hello"},
+	{"from": "server", "time_ms": 12, "type": "binary", "data": "AAE="},
+	{"from": "server", "time_ms": 15, "type": "close", "code": 1000, "data": "bye"}
+]}`
+
+	expected := EventStream{
+		{Name: "subscribed", Data: map[string]interface{}{"type": "subscribed", "id": 1.0}},
+		{Name: DefaultEventName, Data: "hello"},
+		{Name: DefaultEventName, Data: "AAE="},
+		{Name: CloseMessage, Data: map[string]interface{}{"code": 1000.0, "reason": "bye"}},
+	}
+
+	got, err := DecodeWebSocketSession(strings.NewReader(body), "type")
+	if err != nil {
+		t.Fatalf("DecodeWebSocketSession: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DecodeWebSocketSession(...) = %+v, expected %+v", got, expected)
+	}
+
+	_, err = DecodeWebSocketSession(strings.NewReader(`{"messages": {}}`), "type")
+	if err == nil {
+		t.Error("DecodeWebSocketSession(invalid): expected error, got nil")
+	}
+}
+
+func TestReadSession(t *testing.T) {
+	dir := filepath.Join(os.TempDir(), "TestReadSession")
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	session := WebSocketSession{Messages: []WebSocketMessage{
+		{From: FromClient, Type: TextMessage, Data: "ping"},
+		{From: FromServer, Time: 42, Type: CloseMessage, Code: 1001},
+	}}
+	err = WriteSession(filepath.Join(dir, "foo_ws2.json"), session)
+	if err != nil {
+		t.Fatalf("WriteSession: unexpected error: %s", err)
+	}
+
+	got, err := ReadSession(filepath.Join(dir, "foo_req2.txt"))
+	if err != nil {
+		t.Fatalf("ReadSession: unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, session) {
+		t.Errorf("ReadSession(...) = %+v, expected %+v", got, session)
+	}
+
+	_, err = ReadSession(filepath.Join(dir, "foo_req.txt"))
+	if !os.IsNotExist(err) {
+		t.Errorf("ReadSession(missing): expected a not-exist error, got %v", err)
+	}
+}